@@ -0,0 +1,488 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+	"github.com/yuvrajchettri/chip-8-emulator/wasmjson"
+)
+
+// ------------------------------------------------
+// JavaScript control API.
+// The WASM build exports a global chip8 object so other pages can embed the
+// emulator and scripts or tests can drive it without restarting the Go runtime:
+//
+//	chip8.load(bytes)            load a ROM or an Octo-style cartridge GIF from a Uint8Array and run it
+//	chip8.reset()                restart the loaded ROM
+//	chip8.pause() / resume()     stop and restart the CPU and timers
+//	chip8.step(n)                execute n instructions (1 if omitted), meant for a paused emulator
+//	chip8.setSpeed(hz)           instructions per second
+//	chip8.setQuirks(obj)         {shift, jump}, see chip8.Quirks
+//	chip8.getState()             {pc, i, v, stack, delayTimer, soundTimer, speed, quirks, paused, frame}
+//	chip8.onFrame(cb)            calls cb(state) after every frame, null removes it
+//	chip8.pressKey(k)            press CHIP-8 key 0x0-0xF
+//	chip8.releaseKey(k)
+//	chip8.saveState()            the machine state as a Uint8Array
+//	chip8.loadState(bytes)       restore a state saved by saveState
+//	chip8.setGamepadMapping(obj) bind gamepad buttons and axes to keys, see parseGamepadMapping
+//	chip8.romInfo()              what the ROM database or cartridge knows about the loaded ROM, see romInfo, or null
+//	chip8.setROMDatabase(arr)    lay overrides, parsed from programs.json, over the ROM database, see romdb.Program
+//	chip8.listROMs()             the built-in ROMs the page can fetch from roms/, see romCatalogue
+//
+// Methods that can fail return an error message, or null on success.
+// ------------------------------------------------
+
+// ------------------------------------------------
+// A session owns the running emulator and is the host.Input its runner
+// polls. Loading a ROM replaces the emulator while the renderer, display
+// options and animation loop carry on. The speed, quirks and gamepad
+// mapping the ROM database recommends are applied on load.
+// ------------------------------------------------
+type session struct {
+	runner   *host.Runner
+	renderer *canvasRenderer
+	db       *romdb.Database
+	rom      romdb.Entry // Zero for ROMs the database doesn't know
+
+	speedHz     int
+	quirks      chip8.Quirks
+	presentMode chip8.PresentMode
+	fadeFrames  int
+
+	gamepad    gamepadMapping
+	gamepadROM gamepadMapping // The loaded ROM's mapping, setGamepadMapping lays bindings over it
+	frames     int
+	onFrame    js.Value
+}
+
+// The session has no emulator until the first load
+func newSession(renderer *canvasRenderer, opts options, speedHz int) *session {
+	s := &session{
+		renderer:    renderer,
+		speedHz:     speedHz,
+		presentMode: opts.presentMode,
+		fadeFrames:  opts.fadeFrames,
+		db:          romdb.Default(),
+		gamepad:     defaultGamepadMapping(),
+		gamepadROM:  defaultGamepadMapping(),
+		onFrame:     js.Null(),
+	}
+	s.runner = host.NewRunner(nil, renderer, s, nil)
+	return s
+}
+
+func (s *session) emulator() *chip8.Chip8 {
+	return s.runner.Machine()
+}
+
+// Starts a fresh emulator with the ROM loaded at 0x200
+func (s *session) load(rom []byte) error {
+	emulator, entry, err := s.db.NewMachine(rom, chip8.Options{
+		Speed:       s.speedHz,
+		Quirks:      s.quirks,
+		PresentMode: s.presentMode,
+		FadeFrames:  s.fadeFrames,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.runner.SetMachine(emulator)
+	s.rom = entry
+
+	// What the database or the cartridge recommends sticks for the next ROM
+	s.speedHz = emulator.Speed()
+	s.quirks = emulator.Quirks()
+	s.gamepadROM = romGamepadMapping(s.romKeys())
+	s.gamepad = s.gamepadROM
+	s.frames = 0
+	s.renderer.dirty = true
+	return nil
+}
+
+// Restarts the loaded ROM
+func (s *session) reset() {
+	s.emulator().Reset()
+	s.frames = 0
+	s.renderer.dirty = true
+}
+
+func (s *session) loadState(state []byte) error {
+	if err := s.emulator().UnmarshalBinary(state); err != nil {
+		return err
+	}
+	s.renderer.dirty = true
+	return nil
+}
+
+func (s *session) setPresentMode(mode chip8.PresentMode, fadeFrames int) {
+	s.presentMode = mode
+	s.fadeFrames = fadeFrames
+	s.emulator().SetPresentMode(mode, fadeFrames)
+}
+
+func (s *session) setSpeed(speedHz int) {
+	s.speedHz = speedHz
+	s.emulator().SetSpeed(speedHz)
+}
+
+func (s *session) setQuirks(quirks chip8.Quirks) {
+	s.quirks = quirks
+	s.emulator().SetQuirks(quirks)
+}
+
+// Keys held on the keyboard, the on-screen keypad or a gamepad
+func (s *session) keys() (keys [16]bool) {
+	for chip8Key := uint8(0); chip8Key < 16; chip8Key++ {
+		keys[chip8Key] = keyStates[chip8Key] || gamepadKeys[chip8Key]
+	}
+	return keys
+}
+
+// Poll reads the gamepads once per frame, see host.Input
+func (s *session) Poll() ([16]bool, error) {
+	gamepadKeys = pollGamepads(s.gamepad, connectedGamepads())
+	return s.keys(), nil
+}
+
+func (s *session) step(n int) {
+	emulator := s.emulator()
+	for key, held := range s.keys() {
+		emulator.UpdateKeyboardState(chip8.Key(key), held)
+	}
+	for i := 0; i < n; i++ {
+		emulator.Step()
+	}
+}
+
+// ------------------------------------------------
+// Runs one animation frame: the CPU and timers unless paused, then the
+// canvas if anything changed, then the onFrame callback.
+// ------------------------------------------------
+func (s *session) frame() {
+	// Rendering loop runs at 60 FPS because of requestAnimationFrame, so the
+	// runner executes Speed/60 instructions per frame, e.g. 700/60 ≈ 12.
+	// A ROM that ran off the end of memory just stops changing.
+	if err := s.runner.Frame(); err != nil && !errors.Is(err, host.ErrHalted) {
+		js.Global().Get("console").Call("error", err.Error())
+	}
+
+	s.frames++
+	if s.onFrame.Type() == js.TypeFunction {
+		s.callOnFrame()
+	}
+}
+
+// An exception thrown by the callback is logged rather than left to stop the emulator
+func (s *session) callOnFrame() {
+	defer func() {
+		if err := recover(); err != nil {
+			js.Global().Get("console").Call("error", fmt.Sprint("chip8.onFrame: ", err))
+		}
+	}()
+	s.onFrame.Invoke(s.state())
+}
+
+func (s *session) state() map[string]interface{} {
+	emulator := s.emulator()
+
+	registers := emulator.Registers()
+	v := make([]interface{}, len(registers))
+	for i, val := range registers {
+		v[i] = val
+	}
+
+	stack := emulator.Stack()
+	jsStack := make([]interface{}, len(stack))
+	for i, addr := range stack {
+		jsStack[i] = addr
+	}
+
+	quirks := emulator.Quirks()
+	return map[string]interface{}{
+		"pc":         emulator.ProgramCounter(),
+		"i":          emulator.I,
+		"v":          v,
+		"stack":      jsStack,
+		"delayTimer": emulator.DelayTimer(),
+		"soundTimer": emulator.SoundTimer(),
+		"speed":      emulator.Speed(),
+		"quirks":     map[string]interface{}{"shift": quirks.Shift, "jump": quirks.Jump},
+		"paused":     s.runner.Paused(),
+		"frame":      s.frames,
+	}
+}
+
+// ------------------------------------------------
+// What the ROM database and the cartridge the ROM came in know about the
+// loaded ROM, the database first, null if neither knows it:
+//
+//	{title, authors, release, platform, speed, quirks: {shift, jump},
+//	 keys: {role: key}, palette: "BACKGROUND,FOREGROUND" or "", format}
+//
+// format is the cartridge format, "" for a plain ROM.
+// ------------------------------------------------
+func (s *session) romInfo() interface{} {
+	entry := s.rom
+	cartridge, isCartridge := s.emulator().Cartridge()
+	if entry.Hash == "" && !isCartridge {
+		return nil
+	}
+
+	authors := make([]interface{}, len(entry.Authors))
+	for i, author := range entry.Authors {
+		authors[i] = author
+	}
+	keys := make(map[string]interface{})
+	for role, key := range s.romKeys() {
+		keys[role] = int(key)
+	}
+	settings := entry.Settings(cartridge)
+	quirks := entry.Quirks()
+	if settings.Quirks != nil {
+		quirks = *settings.Quirks
+	}
+	return map[string]interface{}{
+		"title":    settings.Title,
+		"authors":  authors,
+		"release":  entry.Release,
+		"platform": entry.Platform.ID,
+		"speed":    settings.Speed,
+		"quirks":   map[string]interface{}{"shift": quirks.Shift, "jump": quirks.Jump},
+		"keys":     keys,
+		"palette":  settings.Palette,
+		"format":   cartridge.Format,
+	}
+}
+
+// The ROM catalogue as an array of {name, file, size, hash, title,
+// description, speed, quirks, palette}, see roms.ROM. file is the path
+// under roms/ to fetch, the ROM's bundle if it has one.
+func romCatalogue() interface{} {
+	list := roms.List()
+	catalogue := make([]interface{}, len(list))
+	for i, rom := range list {
+		catalogue[i] = map[string]interface{}{
+			"name":        rom.Name,
+			"file":        rom.File,
+			"size":        rom.Size,
+			"hash":        rom.Hash,
+			"title":       rom.Title,
+			"description": rom.Description,
+			"speed":       rom.Speed,
+			"quirks":      map[string]interface{}{"shift": rom.Quirks.Shift, "jump": rom.Quirks.Jump},
+			"palette":     rom.Palette,
+		}
+	}
+	return catalogue
+}
+
+// The keys the loaded ROM uses by their role, from the ROM database or else its cartridge
+func (s *session) romKeys() map[string]chip8.Key {
+	cartridge, _ := s.emulator().Cartridge()
+	return s.rom.Settings(cartridge).Keys
+}
+
+// Lays overrides over the embedded ROM database, they apply from the next load
+func (s *session) setROMDatabase(jsPrograms js.Value) error {
+	var programs []romdb.Program
+	if err := wasmjson.Decode(jsPrograms, &programs); err != nil {
+		return fmt.Errorf("programs: %w", err)
+	}
+	s.db = romdb.Default().WithPrograms(programs)
+	return nil
+}
+
+// Parses a CHIP-8 key argument, 0x0-0xF
+func jsKey(args []js.Value) (uint8, error) {
+	if len(args) < 1 || args[0].Type() != js.TypeNumber {
+		return 0, fmt.Errorf("expected a key number")
+	}
+	key := args[0].Int()
+	if key < 0 || key > 0xF {
+		return 0, fmt.Errorf("key %d out of range 0x0-0xF", key)
+	}
+	return uint8(key), nil
+}
+
+// Copies a Uint8Array into Go
+func jsBytes(v js.Value) ([]byte, error) {
+	if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, fmt.Errorf("expected a Uint8Array")
+	}
+	data := make([]byte, v.Length())
+	js.CopyBytesToGo(data, v)
+	return data, nil
+}
+
+func bytesToJS(data []byte) js.Value {
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+	return array
+}
+
+// Returns the i-th argument, undefined if it wasn't passed
+func jsArg(args []js.Value, i int) js.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return js.Undefined()
+}
+
+func exportAPI(s *session) {
+	api := js.Global().Get("Object").New()
+	method := func(name string, fn func(args []js.Value) interface{}) {
+		api.Set(name, js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			return fn(args)
+		}))
+	}
+
+	method("load", func(args []js.Value) interface{} {
+		rom, err := jsBytes(jsArg(args, 0))
+		if err != nil {
+			return err.Error()
+		}
+		if err := s.load(rom); err != nil {
+			return err.Error()
+		}
+		return nil
+	})
+
+	method("reset", func(args []js.Value) interface{} {
+		s.reset()
+		return nil
+	})
+
+	method("pause", func(args []js.Value) interface{} {
+		s.runner.SetPaused(true)
+		return nil
+	})
+
+	method("resume", func(args []js.Value) interface{} {
+		s.runner.SetPaused(false)
+		return nil
+	})
+
+	method("step", func(args []js.Value) interface{} {
+		n := 1
+		if len(args) > 0 && args[0].Type() == js.TypeNumber {
+			n = args[0].Int()
+		}
+		if n < 0 {
+			return "step count must not be negative"
+		}
+		s.step(n)
+		return nil
+	})
+
+	method("setSpeed", func(args []js.Value) interface{} {
+		if len(args) < 1 || args[0].Type() != js.TypeNumber || args[0].Int() < render.FrameRate {
+			return fmt.Sprintf("speed must be at least %d Hz", render.FrameRate)
+		}
+		s.setSpeed(args[0].Int())
+		return nil
+	})
+
+	method("setQuirks", func(args []js.Value) interface{} {
+		if len(args) < 1 || args[0].Type() != js.TypeObject {
+			return "expected a quirks object"
+		}
+		// Quirks missing from the object keep their current value
+		quirks := s.quirks
+		if shift := args[0].Get("shift"); !shift.IsUndefined() {
+			quirks.Shift = shift.Truthy()
+		}
+		if jump := args[0].Get("jump"); !jump.IsUndefined() {
+			quirks.Jump = jump.Truthy()
+		}
+		s.setQuirks(quirks)
+		return nil
+	})
+
+	method("getState", func(args []js.Value) interface{} {
+		return s.state()
+	})
+
+	method("onFrame", func(args []js.Value) interface{} {
+		if len(args) < 1 || args[0].IsNull() || args[0].IsUndefined() {
+			s.onFrame = js.Null()
+			return nil
+		}
+		if args[0].Type() != js.TypeFunction {
+			return "expected a function or null"
+		}
+		s.onFrame = args[0]
+		return nil
+	})
+
+	method("pressKey", func(args []js.Value) interface{} {
+		key, err := jsKey(args)
+		if err != nil {
+			return err.Error()
+		}
+		keyStates[key] = true
+		return nil
+	})
+
+	method("releaseKey", func(args []js.Value) interface{} {
+		key, err := jsKey(args)
+		if err != nil {
+			return err.Error()
+		}
+		keyStates[key] = false
+		return nil
+	})
+
+	method("saveState", func(args []js.Value) interface{} {
+		state, err := s.emulator().MarshalBinary()
+		if err != nil {
+			return nil
+		}
+		return bytesToJS(state)
+	})
+
+	method("loadState", func(args []js.Value) interface{} {
+		state, err := jsBytes(jsArg(args, 0))
+		if err != nil {
+			return err.Error()
+		}
+		if err := s.loadState(state); err != nil {
+			return err.Error()
+		}
+		return nil
+	})
+
+	method("setGamepadMapping", func(args []js.Value) interface{} {
+		mapping, err := parseGamepadMapping(jsArg(args, 0), s.gamepadROM)
+		if err != nil {
+			return err.Error()
+		}
+		s.gamepad = mapping
+		return nil
+	})
+
+	method("romInfo", func(args []js.Value) interface{} {
+		return s.romInfo()
+	})
+
+	method("setROMDatabase", func(args []js.Value) interface{} {
+		if err := s.setROMDatabase(jsArg(args, 0)); err != nil {
+			return err.Error()
+		}
+		return nil
+	})
+
+	method("listROMs", func(args []js.Value) interface{} {
+		return romCatalogue()
+	})
+
+	js.Global().Set("chip8", api)
+}
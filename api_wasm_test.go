@@ -0,0 +1,311 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/cart"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+)
+
+// Counts V0 up from 5 forever:
+//
+//	0x200: 6005  V0 = 5
+//	0x202: 7001  V0 += 1
+//	0x204: 1202  jump to 0x202
+var countROM = []byte{0x60, 0x05, 0x70, 0x01, 0x12, 0x02}
+
+// Sets up a session on a stub canvas and exports it as the global chip8 object
+func newTestAPI(t *testing.T) (*session, js.Value) {
+	canvas, ctx := stubCanvas()
+	opts, err := parseOptions(nil)
+	require.NoError(t, err)
+
+	s := newSession(newCanvasRenderer(canvas, ctx, opts), opts, 700)
+	exportAPI(s)
+	api := js.Global().Get("chip8")
+
+	require.True(t, api.Call("load", bytesToJS(countROM)).IsNull())
+	return s, api
+}
+
+func TestAPI_LoadAndStep(t *testing.T) {
+	_, api := newTestAPI(t)
+
+	state := api.Call("getState")
+	require.Equal(t, 0x200, state.Get("pc").Int())
+	require.Equal(t, 0, state.Get("v").Index(0).Int())
+	require.Equal(t, 0, state.Get("stack").Length())
+
+	require.True(t, api.Call("step").IsNull())
+	state = api.Call("getState")
+	require.Equal(t, 0x202, state.Get("pc").Int())
+	require.Equal(t, 5, state.Get("v").Index(0).Int())
+
+	require.True(t, api.Call("step", 4).IsNull())
+	state = api.Call("getState")
+	require.Equal(t, 0x202, state.Get("pc").Int())
+	require.Equal(t, 7, state.Get("v").Index(0).Int())
+
+	// Reset restarts the same ROM
+	require.True(t, api.Call("reset").IsNull())
+	state = api.Call("getState")
+	require.Equal(t, 0x200, state.Get("pc").Int())
+	require.Equal(t, 0, state.Get("v").Index(0).Int())
+
+	// Anything but bytes is rejected and the running ROM is kept
+	require.Equal(t, "expected a Uint8Array", api.Call("load", "PONG").String())
+	require.Equal(t, "step count must not be negative", api.Call("step", -1).String())
+	require.Equal(t, 0x200, api.Call("getState").Get("pc").Int())
+}
+
+func TestAPI_PauseResume(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	require.True(t, api.Call("pause").IsNull())
+	s.frame()
+	state := api.Call("getState")
+	require.True(t, state.Get("paused").Bool())
+	require.Equal(t, 0x200, state.Get("pc").Int())
+	require.Equal(t, 1, state.Get("frame").Int())
+
+	// A running frame executes speed/60 instructions
+	require.True(t, api.Call("resume").IsNull())
+	require.True(t, api.Call("setSpeed", 600).IsNull())
+	s.frame()
+	state = api.Call("getState")
+	require.False(t, state.Get("paused").Bool())
+	require.Equal(t, 600, state.Get("speed").Int())
+	// 6005 then 7001 and 1202 alternating, five increments in ten instructions
+	require.Equal(t, 10, state.Get("v").Index(0).Int())
+
+	require.Equal(t, "speed must be at least 60 Hz", api.Call("setSpeed", 10).String())
+	require.Equal(t, 600, api.Call("getState").Get("speed").Int())
+}
+
+func TestAPI_Quirks(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	require.True(t, api.Call("setQuirks", map[string]interface{}{"jump": true}).IsNull())
+	quirks := api.Call("getState").Get("quirks")
+	require.False(t, quirks.Get("shift").Bool())
+	require.True(t, quirks.Get("jump").Bool())
+
+	// Quirks and speed survive loading another ROM
+	require.True(t, api.Call("setSpeed", 1200).IsNull())
+	require.True(t, api.Call("reset").IsNull())
+	require.True(t, s.emulator().Quirks().Jump)
+	require.Equal(t, 1200, s.emulator().Speed())
+}
+
+func TestAPI_Keys(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	require.True(t, api.Call("pressKey", 0xA).IsNull())
+	require.True(t, api.Call("step").IsNull())
+	require.True(t, s.emulator().IsKeyPressed(0xA))
+
+	require.True(t, api.Call("releaseKey", 0xA).IsNull())
+	require.True(t, api.Call("step").IsNull())
+	require.False(t, s.emulator().IsKeyPressed(0xA))
+
+	require.Equal(t, "key 16 out of range 0x0-0xF", api.Call("pressKey", 16).String())
+	require.Equal(t, "expected a key number", api.Call("releaseKey").String())
+}
+
+func TestSetDisplayOptions(t *testing.T) {
+	s, _ := newTestAPI(t)
+	screen := s.renderer.screen
+	palette := screen.Options().Palette
+
+	// Missing options keep their current value
+	require.Nil(t, setDisplayOptions(s, js.ValueOf(map[string]interface{}{"grid": true, "present": "fade"})))
+	require.True(t, screen.Effects().Grid)
+	require.Equal(t, palette, screen.Options().Palette)
+	require.Equal(t, chip8.PRESENT_FADE, s.presentMode)
+
+	require.Equal(t, "fadeFrames must be a number, got string", setDisplayOptions(s, js.ValueOf(map[string]interface{}{"fadeFrames": "4"})))
+	require.Equal(t, "expected an options object", setDisplayOptions(s, js.Undefined()))
+	require.Equal(t, chip8.PRESENT_FADE, s.presentMode)
+}
+
+func TestAPI_OnFrame(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	js.Global().Call("eval", `
+		globalThis.framesSeen = [];
+		chip8.onFrame(state => framesSeen.push(state.frame));
+	`)
+	s.frame()
+	s.frame()
+	require.Equal(t, 2, js.Global().Get("framesSeen").Length())
+	require.Equal(t, 2, js.Global().Get("framesSeen").Index(1).Int())
+
+	// A throwing callback doesn't stop the frame loop
+	js.Global().Call("eval", `chip8.onFrame(() => { throw new Error("boom"); })`)
+	require.NotPanics(t, s.frame)
+
+	require.True(t, api.Call("onFrame", nil).IsNull())
+	s.frame()
+	require.Equal(t, 2, js.Global().Get("framesSeen").Length())
+	require.Equal(t, "expected a function or null", api.Call("onFrame", 1).String())
+}
+
+func TestAPI_SaveState(t *testing.T) {
+	_, api := newTestAPI(t)
+
+	require.True(t, api.Call("step", 3).IsNull())
+	state := api.Call("saveState")
+	require.True(t, state.InstanceOf(js.Global().Get("Uint8Array")))
+
+	require.True(t, api.Call("step", 4).IsNull())
+	require.Equal(t, 8, api.Call("getState").Get("v").Index(0).Int())
+
+	require.True(t, api.Call("loadState", state).IsNull())
+	require.Equal(t, 6, api.Call("getState").Get("v").Index(0).Int())
+	require.Equal(t, 0x202, api.Call("getState").Get("pc").Int())
+
+	require.Equal(t, "not a CHIP-8 save state", api.Call("loadState", bytesToJS([]byte("nope"))).String())
+	require.Equal(t, "expected a Uint8Array", api.Call("loadState").String())
+}
+
+func TestAPI_ROMDatabase(t *testing.T) {
+	s, api := newTestAPI(t)
+	require.True(t, api.Call("romInfo").IsNull())
+
+	// Known ROMs get the database's speed and gamepad bindings
+	pong, err := os.ReadFile("roms/PONG")
+	require.NoError(t, err)
+	require.True(t, api.Call("load", bytesToJS(pong)).IsNull())
+	info := api.Call("romInfo")
+	require.Equal(t, "Pong (1 player)", info.Get("title").String())
+	require.Equal(t, 0x1, info.Get("keys").Get("up").Int())
+	require.Equal(t, 720, api.Call("getState").Get("speed").Int())
+	require.Equal(t, uint8(0x1), s.gamepad.buttons[12])
+
+	// Page bindings are laid over the ROM's
+	require.True(t, api.Call("setGamepadMapping", js.Global().Call("eval", `({ buttons: { 0: 0xA } })`)).IsNull())
+	require.Equal(t, uint8(0xA), s.gamepad.buttons[0])
+	require.Equal(t, uint8(0x1), s.gamepad.buttons[12])
+
+	// Overrides apply from the next load
+	hash := romdb.Hash(pong)
+	programs := js.Global().Get("JSON").Call("parse", `[{"roms": {"`+hash+`": {
+		"tickrate": 20,
+		"keys": {"a": 5},
+		"quirkyPlatforms": {"superchip": {"jump": false}},
+		"colors": {"pixels": ["#000", "#fff"]}
+	}}}]`)
+	require.True(t, api.Call("setROMDatabase", programs).IsNull())
+	require.True(t, api.Call("load", bytesToJS(pong)).IsNull())
+	info = api.Call("romInfo")
+	require.Equal(t, 1200, api.Call("getState").Get("speed").Int())
+	require.Equal(t, "Pong (1 player)", info.Get("title").String())
+	require.True(t, info.Get("quirks").Get("jump").Bool())
+	require.Equal(t, 5, info.Get("keys").Get("a").Int())
+	require.Equal(t, "#000,#fff", info.Get("palette").String())
+
+	require.Equal(t, "programs: wasmjson: cannot decode string value into []romdb.Program", api.Call("setROMDatabase", "[]").String())
+	require.Equal(t, "programs: wasmjson: cannot decode number [0] into romdb.Program", api.Call("setROMDatabase", js.Global().Call("eval", "[1]")).String())
+}
+
+// A cartridge GIF with the JSON payload given
+func testCartridge(t *testing.T, payload string) js.Value {
+	label := image.NewPaletted(image.Rect(0, 0, 64, 32), color.Palette{color.RGBA{A: 255}})
+	var buf bytes.Buffer
+	require.NoError(t, cart.WritePayload(&buf, label, []byte(payload)))
+	return bytesToJS(buf.Bytes())
+}
+
+func TestAPI_Cartridge(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	// countROM at 15 instructions per frame
+	data := testCartridge(t, `{"binary": "600570011202", "options": {
+		"tickrate": 15, "shiftQuirks": true, "backgroundColor": "#000000", "fillColor": "#FFFFFF"
+	}}`)
+	require.True(t, api.Call("load", data).IsNull())
+	require.Equal(t, 900, api.Call("getState").Get("speed").Int())
+	require.Equal(t, 900, s.speedHz)
+	require.False(t, s.quirks.Shift)
+
+	info := api.Call("romInfo")
+	require.Equal(t, "octo", info.Get("format").String())
+	require.Equal(t, "#000000,#FFFFFF", info.Get("palette").String())
+	require.Equal(t, "", info.Get("title").String())
+
+	api.Call("step", 3)
+	require.Equal(t, 6, api.Call("getState").Get("v").Index(0).Int())
+
+	// A plain ROM isn't a cartridge
+	require.True(t, api.Call("load", bytesToJS(countROM)).IsNull())
+	require.True(t, api.Call("romInfo").IsNull())
+
+	require.Equal(t, "cart: the cartridge only has Octo source, assemble it with Octo",
+		api.Call("load", testCartridge(t, `{"program": ": main"}`)).String())
+	require.Contains(t, api.Call("load", testCartridge(t, `{"binary": `)).String(), "cart: invalid payload")
+}
+
+func TestAPI_Bundle(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	// The embedded ROMs are bundles with the ROM database's settings
+	tank, err := namedROM("TANK")
+	require.NoError(t, err)
+	require.True(t, api.Call("load", bytesToJS(tank)).IsNull())
+	require.Equal(t, 720, api.Call("getState").Get("speed").Int())
+
+	info := api.Call("romInfo")
+	require.Equal(t, "bundle", info.Get("format").String())
+	require.Equal(t, "Tank", info.Get("title").String())
+	require.Equal(t, 0x5, info.Get("keys").Get("a").Int())
+	require.Equal(t, "", info.Get("palette").String())
+	require.Equal(t, uint8(0x5), s.gamepad.buttons[0])
+
+	raw, err := os.ReadFile("roms/TANK")
+	require.NoError(t, err)
+	require.Equal(t, raw, s.emulator().ROM())
+
+	require.Equal(t, "bundle: the image has no manifest, it isn't a bundle", api.Call("load", bytesToJS(pngWithoutManifest(t))).String())
+}
+
+func pngWithoutManifest(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	return buf.Bytes()
+}
+
+func TestAPI_ListROMs(t *testing.T) {
+	_, api := newTestAPI(t)
+	list := api.Call("listROMs")
+	require.Equal(t, len(roms.List()), list.Length())
+
+	var pong js.Value
+	for i := 0; i < list.Length(); i++ {
+		if list.Index(i).Get("name").String() == "PONG" {
+			pong = list.Index(i)
+		}
+	}
+	require.False(t, pong.IsUndefined())
+	require.Equal(t, "PONG.bundle.png", pong.Get("file").String())
+	require.Equal(t, "Pong (1 player)", pong.Get("title").String())
+	require.Equal(t, 720, pong.Get("speed").Int())
+	require.False(t, pong.Get("quirks").Get("shift").Bool())
+
+	// The file the page fetches loads like the ROM
+	data, err := os.ReadFile("roms/" + pong.Get("file").String())
+	require.NoError(t, err)
+	require.True(t, api.Call("load", bytesToJS(data)).IsNull())
+	require.Equal(t, "Pong (1 player)", api.Call("romInfo").Get("title").String())
+}
@@ -0,0 +1,177 @@
+// Package bundle reads and writes ROM bundles: PNG images of a ROM's cover
+// screenshot that carry the ROM and everything needed to run it in a JSON
+// manifest, so a bundle is both a ROM file and a picture of it.
+//
+// The manifest is the text of an uncompressed iTXt chunk with the keyword
+// "chip8-bundle":
+//
+//	{"title": "Pong", "speed": 720, "quirks": {"shift": false, "jump": false},
+//	 "keys": {"up": 1}, "palette": "#000000,#FFFFFF", "rom": "base64"}
+//
+// Everything but rom is optional. Importing the package registers the format
+// with chip8.RegisterFormat, so chip8.LoadROM runs bundles. Encode is
+// command-line only, the WASM build reads bundles but doesn't write them.
+package bundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+const (
+	Magic   = "\x89PNG\r\n\x1a\n" // PNG signature
+	Keyword = "chip8-bundle"      // iTXt keyword of the manifest
+)
+
+// Bundle is the manifest of a bundle
+type Bundle struct {
+	Title    string         `json:"title,omitempty"`
+	Authors  []string       `json:"authors,omitempty"`
+	Platform string         `json:"platform,omitempty"` // ROM database platform ID, see romdb.Platform
+	Speed    int            `json:"speed,omitempty"`    // Instructions per second
+	Quirks   *Quirks        `json:"quirks,omitempty"`
+	Keys     map[string]int `json:"keys,omitempty"`    // Keys the ROM uses by their role, as in the ROM database
+	Palette  string         `json:"palette,omitempty"` // "BACKGROUND,FOREGROUND" hex colours
+	ROM      []byte         `json:"rom"`
+}
+
+// Quirks are chip8.Quirks with the names the JavaScript API uses
+type Quirks struct {
+	Shift bool `json:"shift"`
+	Jump  bool `json:"jump"`
+}
+
+// Chip8 returns the bundle as chip8.LoadROM applies it
+func (b Bundle) Chip8() (chip8.Cartridge, error) {
+	if len(b.ROM) == 0 {
+		return chip8.Cartridge{}, errors.New("bundle: the bundle has no ROM")
+	}
+
+	cart := chip8.Cartridge{ROM: b.ROM, Speed: b.Speed, Palette: b.Palette, Title: b.Title}
+	if b.Quirks != nil {
+		cart.Quirks = &chip8.Quirks{Shift: b.Quirks.Shift, Jump: b.Quirks.Jump}
+	}
+	for role, key := range b.Keys {
+		if key >= 0 && key <= 0xF {
+			if cart.Keys == nil {
+				cart.Keys = make(map[string]chip8.Key)
+			}
+			cart.Keys[role] = chip8.Key(key)
+		}
+	}
+	return cart, nil
+}
+
+// ------------------------------------------------
+// PNG chunks: a 32-bit big-endian length, a 4 byte type, the data and the
+// CRC-32 of the type and data
+// ------------------------------------------------
+
+// WriteManifest copies the PNG cover to w with manifest added before its
+// final IEND chunk
+func WriteManifest(w io.Writer, cover []byte, manifest []byte) error {
+	var end int
+	err := forEachChunk(cover, func(typ string, data []byte, offset int) bool {
+		if typ == "IEND" {
+			end = offset
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if end == 0 {
+		return errors.New("bundle: the cover has no IEND chunk")
+	}
+
+	// Keyword, compression flag and method, empty language tag and translated keyword
+	text := append([]byte(Keyword), 0, 0, 0, 0, 0)
+	text = append(text, manifest...)
+
+	var buf bytes.Buffer
+	buf.Write(cover[:end])
+	writeChunk(&buf, "iTXt", text)
+	buf.Write(cover[end:])
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeChunk(buf *bytes.Buffer, typ string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	buf.WriteString(typ)
+	buf.Write(data)
+	buf.Write(crc.Sum(nil))
+}
+
+// ReadManifest returns the manifest of a bundle
+func ReadManifest(data []byte) ([]byte, error) {
+	var manifest []byte
+	prefix := append([]byte(Keyword), 0)
+	err := forEachChunk(data, func(typ string, chunk []byte, offset int) bool {
+		if typ != "iTXt" || !bytes.HasPrefix(chunk, prefix) {
+			return true
+		}
+		// Skip the compression flag and method, language tag and translated keyword
+		rest := chunk[len(prefix):]
+		if len(rest) < 2 || rest[0] != 0 {
+			return true
+		}
+		rest = rest[2:]
+		for skip := 0; skip < 2; skip++ {
+			i := bytes.IndexByte(rest, 0)
+			if i < 0 {
+				return true
+			}
+			rest = rest[i+1:]
+		}
+		manifest = rest
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, errors.New("bundle: the image has no manifest, it isn't a bundle")
+	}
+	return manifest, nil
+}
+
+// Calls fn with every chunk and its offset in the PNG until it returns false
+func forEachChunk(png []byte, fn func(typ string, data []byte, offset int) bool) error {
+	if !bytes.HasPrefix(png, []byte(Magic)) {
+		return errors.New("bundle: not a PNG image")
+	}
+	for offset := len(Magic); offset < len(png); {
+		if len(png)-offset < 12 {
+			return errors.New("bundle: truncated PNG chunk")
+		}
+		length := binary.BigEndian.Uint32(png[offset:])
+		if uint64(length) > uint64(len(png)-offset-12) {
+			return errors.New("bundle: truncated PNG chunk")
+		}
+		typ := string(png[offset+4 : offset+8])
+		data := png[offset+8 : offset+8+int(length)]
+		sum := binary.BigEndian.Uint32(png[offset+8+int(length):])
+		if crc32.ChecksumIEEE(png[offset+4:offset+8+int(length)]) != sum {
+			return fmt.Errorf("bundle: %s chunk has a bad checksum", typ)
+		}
+		if !fn(typ, data, offset) {
+			return nil
+		}
+		offset += 12 + int(length)
+	}
+	return nil
+}
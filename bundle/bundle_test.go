@@ -0,0 +1,86 @@
+//go:build !js && !wasm
+
+package bundle
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+var testBundle = Bundle{
+	Title:    "Count",
+	Authors:  []string{"Someone"},
+	Platform: "modernChip8",
+	Speed:    900,
+	Quirks:   &Quirks{Shift: true},
+	Keys:     map[string]int{"up": 2, "bad": 16},
+	Palette:  "#000000,#FFFFFF",
+	ROM:      []byte{0x60, 0x05, 0x70, 0x01, 0x12, 0x02}, // V0 = 5, then counts up
+}
+
+func encode(t *testing.T, b Bundle) []byte {
+	cover := image.NewGray(image.Rect(0, 0, 8, 4))
+	cover.SetGray(1, 1, color.Gray{Y: 255})
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, b, cover))
+	return buf.Bytes()
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	data := encode(t, testBundle)
+
+	// Still an image of the cover
+	cover, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 8, 4), cover.Bounds())
+	require.Equal(t, color.Gray{Y: 255}, cover.At(1, 1))
+
+	b, err := Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, testBundle, b)
+}
+
+func TestLoadROM_Bundle(t *testing.T) {
+	machine := chip8.New(chip8.Options{})
+	require.NoError(t, machine.LoadROM(encode(t, testBundle)))
+	require.Equal(t, 900, machine.Speed())
+	require.Equal(t, chip8.Quirks{Shift: true}, machine.Quirks())
+	require.Equal(t, testBundle.ROM, machine.ROM())
+
+	cart, ok := machine.Cartridge()
+	require.True(t, ok)
+	require.Equal(t, "bundle", cart.Format)
+	require.Equal(t, "Count", cart.Title)
+	require.Equal(t, "#000000,#FFFFFF", cart.Palette)
+	require.Equal(t, map[string]chip8.Key{"up": chip8.KEY_2}, cart.Keys)
+
+	require.EqualError(t, machine.LoadROM(encode(t, Bundle{Title: "Empty"})), "bundle: the bundle has no ROM")
+}
+
+func TestReadManifest_Errors(t *testing.T) {
+	// A plain PNG
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	_, err := ReadManifest(buf.Bytes())
+	require.EqualError(t, err, "bundle: the image has no manifest, it isn't a bundle")
+
+	data := encode(t, testBundle)
+	_, err = ReadManifest(data[:len(data)-20])
+	require.EqualError(t, err, "bundle: truncated PNG chunk")
+
+	// Flip a bit of the manifest
+	i := bytes.Index(data, []byte(`"title"`))
+	data[i+1] ^= 1
+	_, err = ReadManifest(data)
+	require.EqualError(t, err, "bundle: iTXt chunk has a bad checksum")
+
+	_, err = ReadManifest([]byte{0x60, 0x05})
+	require.EqualError(t, err, "bundle: not a PNG image")
+}
@@ -0,0 +1,24 @@
+//go:build !js && !wasm
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+)
+
+// Encode writes the bundle as a PNG of cover
+func Encode(w io.Writer, b Bundle, cover image.Image) error {
+	manifest, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, cover); err != nil {
+		return err
+	}
+	return WriteManifest(w, buf.Bytes(), manifest)
+}
@@ -0,0 +1,38 @@
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/wasmjson"
+)
+
+// ------------------------------------------------
+// The JSON manifest. Decoded with wasmjson on both builds, Encode is
+// command-line only, see encode.go.
+// ------------------------------------------------
+
+func init() {
+	chip8.RegisterFormat("bundle", Magic, decodeChip8)
+}
+
+// Decode reads a bundle's manifest
+func Decode(data []byte) (Bundle, error) {
+	manifest, err := ReadManifest(data)
+	if err != nil {
+		return Bundle{}, err
+	}
+	var b Bundle
+	if err := wasmjson.Unmarshal(manifest, &b); err != nil {
+		return Bundle{}, fmt.Errorf("bundle: invalid manifest: %w", err)
+	}
+	return b, nil
+}
+
+func decodeChip8(data []byte) (chip8.Cartridge, error) {
+	b, err := Decode(data)
+	if err != nil {
+		return chip8.Cartridge{}, err
+	}
+	return b.Chip8()
+}
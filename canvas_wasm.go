@@ -0,0 +1,95 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"image"
+	"syscall/js"
+
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+)
+
+// ------------------------------------------------
+// Canvas output for the browser.
+// Frames are rasterised into a Go-side RGBA buffer, copied to JavaScript in
+// one go and drawn with a single putImageData, instead of crossing the
+// Go/JS boundary once per lit pixel. The canvas is scaled up with CSS
+// (image-rendering: pixelated), so the buffer only needs one pixel per
+// CHIP-8 pixel unless grid or scanline effects need room between pixels.
+// ------------------------------------------------
+
+const (
+	cssScale    = 10 // Displayed size of a CHIP-8 pixel, matches the SDL window
+	effectScale = 4  // Raster scale when grid or scanline gaps are drawn
+)
+
+type canvasRenderer struct {
+	canvas    js.Value
+	ctx       js.Value
+	screen    *render.Screen
+	pixels    *image.RGBA
+	jsPixels  js.Value // Uint8ClampedArray backing imageData
+	imageData js.Value
+	dirty     bool    // Set when the canvas has to be redrawn whether the frame changed or not
+	frame     [][]int // Last frame presented, nil before the first
+}
+
+func newCanvasRenderer(canvas, ctx js.Value, opts options) *canvasRenderer {
+	renderer := &canvasRenderer{
+		canvas: canvas,
+		ctx:    ctx,
+		screen: render.NewScreen(opts.screenOptions(rasterScale(opts.effects)), opts.effects),
+	}
+	renderer.resize()
+	return renderer
+}
+
+func rasterScale(effects render.Effects) int {
+	if effects.Grid || effects.Scanlines {
+		return effectScale
+	}
+	return 1
+}
+
+// setOptions applies new display options, resizing the canvas if the raster scale changes
+func (r *canvasRenderer) setOptions(opts options) {
+	scale := rasterScale(opts.effects)
+	resize := scale != r.screen.Options().Scale
+
+	r.screen.SetOptions(opts.screenOptions(scale))
+	r.screen.SetEffects(opts.effects)
+	if resize {
+		r.resize()
+	}
+	r.dirty = true
+}
+
+// Allocates the pixel buffers and sizes the canvas for the current raster scale
+func (r *canvasRenderer) resize() {
+	r.pixels = r.screen.NewRGBA()
+	width, height := r.pixels.Bounds().Dx(), r.pixels.Bounds().Dy()
+
+	r.canvas.Set("width", width)
+	r.canvas.Set("height", height)
+
+	r.jsPixels = js.Global().Get("Uint8ClampedArray").New(len(r.pixels.Pix))
+	r.imageData = js.Global().Get("ImageData").New(r.jsPixels, width, height)
+}
+
+// Present paints the frame if it changed or pixels are still fading, see host.Display
+func (r *canvasRenderer) Present(frame [][]int, changed bool) error {
+	r.frame = frame
+	fading := r.screen.Fade(frame)
+	if changed || fading || r.dirty {
+		r.dirty = false
+		r.draw(frame)
+	}
+	return nil
+}
+
+// draw presents a display or presentation buffer on the canvas
+func (r *canvasRenderer) draw(buffer [][]int) {
+	r.screen.Rasterize(buffer, r.pixels)
+	js.CopyBytesToJS(r.jsPixels, r.pixels.Pix)
+	r.ctx.Call("putImageData", r.imageData, 0, 0)
+}
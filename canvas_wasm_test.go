@@ -0,0 +1,112 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Compares the old per-pixel fillRect rendering with the ImageData renderer.
+// Run under Node with:
+//
+//	GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" -bench Render -run '^$' .
+//
+// Node has no canvas, so both renderers draw into a stub context whose
+// methods do nothing: what is measured is the Go side and the Go/JS calls.
+// ------------------------------------------------
+
+func stubCanvas() (canvas, ctx js.Value) {
+	js.Global().Call("eval", `
+		if (typeof ImageData === "undefined") {
+			globalThis.ImageData = class {
+				constructor(data, width, height) {
+					this.data = data;
+					this.width = width;
+					this.height = height;
+				}
+			};
+		}
+	`)
+	canvas = js.Global().Get("Object").New()
+	ctx = js.Global().Call("eval", `({ fillStyle: "", fillRect() {}, putImageData() {} })`)
+	return canvas, ctx
+}
+
+// A frame with half the pixels lit, busier than most games
+func benchmarkDisplay() [][]int {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	for y := range display {
+		for x := range display[y] {
+			display[y][x] = (x + y) % 2
+		}
+	}
+	return display
+}
+
+// The renderer main_wasm.go used before ImageData: one fillRect per lit pixel
+func renderFillRect(ctx js.Value, display [][]int, modifier int) {
+	ctx.Set("fillStyle", "#FF0000")
+	ctx.Call("fillRect", 0, 0, chip8.DISPLAY_COLS*modifier, chip8.DISPLAY_ROWS*modifier)
+
+	ctx.Set("fillStyle", "#FFFF00")
+	for j := 0; j < len(display); j++ {
+		for i := 0; i < len(display[j]); i++ {
+			if display[j][i] != 0 {
+				ctx.Call("fillRect", i*modifier, j*modifier, modifier, modifier)
+			}
+		}
+	}
+}
+
+func BenchmarkRenderFillRect(b *testing.B) {
+	_, ctx := stubCanvas()
+	display := benchmarkDisplay()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderFillRect(ctx, display, cssScale)
+	}
+}
+
+func BenchmarkRenderImageData(b *testing.B) {
+	canvas, ctx := stubCanvas()
+	display := benchmarkDisplay()
+	opts, err := parseOptions(nil)
+	require.NoError(b, err)
+	renderer := newCanvasRenderer(canvas, ctx, opts)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderer.draw(display)
+	}
+}
+
+func TestCanvasRenderer_Draw(t *testing.T) {
+	canvas, ctx := stubCanvas()
+	opts, err := parseOptions(nil)
+	require.NoError(t, err)
+
+	// One canvas pixel per CHIP-8 pixel
+	renderer := newCanvasRenderer(canvas, ctx, opts)
+	require.Equal(t, chip8.DISPLAY_COLS, canvas.Get("width").Int())
+	require.Equal(t, chip8.DISPLAY_ROWS, canvas.Get("height").Int())
+
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[0][1] = 1
+	renderer.draw(display)
+
+	// Second pixel is yellow in the default palette
+	pixels := renderer.jsPixels
+	rgba := []int{pixels.Index(4).Int(), pixels.Index(5).Int(), pixels.Index(6).Int(), pixels.Index(7).Int()}
+	require.Equal(t, []int{255, 255, 0, 255}, rgba)
+
+	// Effects with gaps need a larger raster
+	opts.effects.Grid = true
+	renderer.setOptions(opts)
+	require.Equal(t, chip8.DISPLAY_COLS*effectScale, canvas.Get("width").Int())
+}
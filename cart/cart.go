@@ -0,0 +1,184 @@
+// Package cart reads and writes Octo-style cartridges: GIF images that show
+// a label and carry a program and the options to run it with hidden in the
+// pixels.
+//
+// Every pixel's palette index holds a label colour in its upper bits and
+// two bits of data in its lowest two, the palette repeats each label colour
+// four times so the data doesn't show. The data runs through the pixels of
+// every frame in order, most significant bits first: a 32-bit big-endian
+// length and then that many bytes of JSON:
+//
+//	{"program": "Octo source", "binary": "hex of the assembled ROM", "options": {"tickrate": 20, ...}}
+//
+// Octo's own cartridges carry only the source, which needs Octo to assemble.
+// Importing the package registers the format with chip8.RegisterFormat, so
+// chip8.LoadROM runs cartridges that have a binary. Encode is command-line
+// only, the WASM build reads cartridges but doesn't write them.
+package cart
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+const (
+	MaxLabelColors = 64 // Each takes four of the 256 palette entries
+	bitsPerPixel   = 2
+	pixelsPerByte  = 8 / bitsPerPixel
+	headerSize     = 4 // Payload length
+)
+
+// Cartridge is a program and the options it is run with
+type Cartridge struct {
+	ROM     []byte // The assembled program, nil if the cartridge has only source
+	Source  string // Octo source, "" if the cartridge has only a ROM
+	Options Options
+}
+
+// ------------------------------------------------
+// Options are the settings Octo saves with a program, under Octo's names.
+// Octo's shift and jump quirks describe the SUPER-CHIP behaviour,
+// chip8.Quirks the COSMAC VIP one, see Chip8.
+// ------------------------------------------------
+type Options struct {
+	Tickrate        int    `json:"tickrate,omitempty"` // Instructions per 60 Hz frame
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	FillColor       string `json:"fillColor,omitempty"`
+	ShiftQuirks     *bool  `json:"shiftQuirks,omitempty"`
+	JumpQuirks      *bool  `json:"jumpQuirks,omitempty"`
+}
+
+// NewOptions returns the Options that run a ROM at speedHz with the quirks
+// and "BACKGROUND,FOREGROUND" palette given
+func NewOptions(speedHz int, quirks chip8.Quirks, background, foreground string) Options {
+	shift, jump := !quirks.Shift, !quirks.Jump
+	return Options{
+		Tickrate:        speedHz / chip8.TIMER_HZ,
+		BackgroundColor: background,
+		FillColor:       foreground,
+		ShiftQuirks:     &shift,
+		JumpQuirks:      &jump,
+	}
+}
+
+// Chip8 returns the cartridge as chip8.LoadROM applies it
+func (c Cartridge) Chip8() (chip8.Cartridge, error) {
+	if len(c.ROM) == 0 {
+		if c.Source != "" {
+			return chip8.Cartridge{}, errors.New("cart: the cartridge only has Octo source, assemble it with Octo")
+		}
+		return chip8.Cartridge{}, errors.New("cart: the cartridge has no program")
+	}
+
+	opts := c.Options
+	cart := chip8.Cartridge{ROM: c.ROM, Speed: opts.Tickrate * chip8.TIMER_HZ}
+	if opts.ShiftQuirks != nil || opts.JumpQuirks != nil {
+		cart.Quirks = &chip8.Quirks{
+			Shift: opts.ShiftQuirks == nil || !*opts.ShiftQuirks,
+			Jump:  opts.JumpQuirks == nil || !*opts.JumpQuirks,
+		}
+	}
+	if opts.BackgroundColor != "" && opts.FillColor != "" {
+		cart.Palette = opts.BackgroundColor + "," + opts.FillColor
+	}
+	return cart, nil
+}
+
+// ------------------------------------------------
+// WritePayload writes a GIF showing label with payload hidden in it, in as
+// many frames as it takes. The label has at most MaxLabelColors colours.
+// ------------------------------------------------
+func WritePayload(w io.Writer, label *image.Paletted, payload []byte) error {
+	if len(label.Palette) > MaxLabelColors {
+		return fmt.Errorf("cart: the label has %d colours, at most %d fit", len(label.Palette), MaxLabelColors)
+	}
+	bounds := label.Bounds()
+	if bounds.Empty() {
+		return errors.New("cart: the label is empty")
+	}
+
+	palette := make(color.Palette, 0, len(label.Palette)<<bitsPerPixel)
+	for _, c := range label.Palette {
+		for i := 0; i < 1<<bitsPerPixel; i++ {
+			palette = append(palette, c)
+		}
+	}
+
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	data = append(data, payload...)
+	bits := bitReader{data: data}
+
+	anim := &gif.GIF{}
+	for bits.more() {
+		frame := image.NewPaletted(bounds, palette)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				frame.SetColorIndex(x, y, label.ColorIndexAt(x, y)<<bitsPerPixel|bits.next())
+			}
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 0)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// Hands out the bits of data two at a time, zeros after the end
+type bitReader struct {
+	data []byte
+	pos  int // In pixels
+}
+
+func (r *bitReader) more() bool {
+	return r.pos < len(r.data)*pixelsPerByte
+}
+
+func (r *bitReader) next() uint8 {
+	if !r.more() {
+		return 0
+	}
+	b := r.data[r.pos/pixelsPerByte]
+	shift := 8 - bitsPerPixel*(r.pos%pixelsPerByte+1)
+	r.pos++
+	return b >> shift & (1<<bitsPerPixel - 1)
+}
+
+// ReadPayload returns the payload hidden in a GIF written by WritePayload
+func ReadPayload(data []byte) ([]byte, error) {
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var hidden []byte
+	var b uint8
+	var n int
+	for _, frame := range anim.Image {
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				b = b<<bitsPerPixel | frame.ColorIndexAt(x, y)&(1<<bitsPerPixel-1)
+				if n++; n%pixelsPerByte == 0 {
+					hidden = append(hidden, b)
+					b = 0
+				}
+			}
+		}
+	}
+
+	if len(hidden) < headerSize {
+		return nil, errors.New("cart: the image is too small to be a cartridge")
+	}
+	size := binary.BigEndian.Uint32(hidden)
+	if uint64(size) > uint64(len(hidden)-headerSize) {
+		return nil, fmt.Errorf("cart: the cartridge says it holds %d bytes, the image only has room for %d", size, len(hidden)-headerSize)
+	}
+	return hidden[headerSize : headerSize+int(size)], nil
+}
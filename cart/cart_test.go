@@ -0,0 +1,139 @@
+//go:build !js && !wasm
+
+package cart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+var (
+	black = color.RGBA{A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+func testLabel() *image.Paletted {
+	label := image.NewPaletted(image.Rect(0, 0, 16, 8), color.Palette{black, white})
+	label.SetColorIndex(3, 4, 1)
+	return label
+}
+
+func encode(t *testing.T, c Cartridge) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, c, testLabel()))
+	return buf.Bytes()
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	rom, err := os.ReadFile(filepath.Join("..", "roms", "PONG"))
+	require.NoError(t, err)
+
+	c := Cartridge{
+		ROM:     rom,
+		Source:  ": main jump main",
+		Options: NewOptions(900, chip8.Quirks{Shift: true}, "#000000", "#FFCC00"),
+	}
+	data := encode(t, c)
+
+	// PONG doesn't fit in one 16x8 frame
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Greater(t, len(anim.Image), 1)
+	for _, frame := range anim.Image {
+		// The label still shows
+		require.Equal(t, black, frame.At(0, 0))
+		require.Equal(t, white, frame.At(3, 4))
+	}
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, c, decoded)
+}
+
+func TestCartridge_Chip8(t *testing.T) {
+	yes, no := true, false
+	cart, err := Cartridge{ROM: []byte{0x00, 0xE0}, Options: Options{
+		Tickrate:        15,
+		BackgroundColor: "#112233",
+		FillColor:       "#445566",
+		ShiftQuirks:     &yes,
+		JumpQuirks:      &no,
+	}}.Chip8()
+	require.NoError(t, err)
+	require.Equal(t, chip8.Cartridge{
+		ROM:     []byte{0x00, 0xE0},
+		Speed:   900,
+		Quirks:  &chip8.Quirks{Shift: false, Jump: true},
+		Palette: "#112233,#445566",
+	}, cart)
+
+	// Options left out leave the machine's settings alone
+	cart, err = Cartridge{ROM: []byte{0x00, 0xE0}}.Chip8()
+	require.NoError(t, err)
+	require.Equal(t, chip8.Cartridge{ROM: []byte{0x00, 0xE0}}, cart)
+
+	_, err = Cartridge{Source: ": main"}.Chip8()
+	require.EqualError(t, err, "cart: the cartridge only has Octo source, assemble it with Octo")
+}
+
+func TestLoadROM_Cartridge(t *testing.T) {
+	// 6005: V0 = 5
+	data := encode(t, Cartridge{
+		ROM:     []byte{0x60, 0x05},
+		Options: NewOptions(1200, chip8.Quirks{Jump: true}, "#000000", "#FFFFFF"),
+	})
+
+	machine := chip8.New(chip8.Options{})
+	require.NoError(t, machine.LoadROM(data))
+	require.Equal(t, 1200, machine.Speed())
+	require.Equal(t, chip8.Quirks{Jump: true}, machine.Quirks())
+
+	c, ok := machine.Cartridge()
+	require.True(t, ok)
+	require.Equal(t, "octo", c.Format)
+	require.Equal(t, "#000000,#FFFFFF", c.Palette)
+
+	machine.Step()
+	require.Equal(t, uint8(5), machine.State().V[0])
+
+	// Source only
+	err := machine.LoadROM(encode(t, Cartridge{Source: ": main"}))
+	require.ErrorContains(t, err, "only has Octo source")
+}
+
+func TestReadPayload_Errors(t *testing.T) {
+	// A GIF without a payload reads a length of zero
+	var buf bytes.Buffer
+	require.NoError(t, WritePayload(&buf, testLabel(), nil))
+	payload, err := ReadPayload(buf.Bytes())
+	require.NoError(t, err)
+	require.Empty(t, payload)
+
+	_, err = Decode(buf.Bytes())
+	require.ErrorContains(t, err, "invalid payload")
+
+	// Data bits all set read as a length far larger than the image
+	frame := image.NewPaletted(image.Rect(0, 0, 16, 8), color.Palette{black, black, black, black})
+	for i := range frame.Pix {
+		frame.Pix[i] = 3
+	}
+	buf.Reset()
+	require.NoError(t, gif.EncodeAll(&buf, &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{0}}))
+	_, err = ReadPayload(buf.Bytes())
+	require.ErrorContains(t, err, "the image only has room for 28")
+
+	_, err = ReadPayload([]byte("GIF89a"))
+	require.Error(t, err)
+
+	tooManyColors := image.NewPaletted(image.Rect(0, 0, 1, 1), make(color.Palette, MaxLabelColors+1))
+	require.Error(t, WritePayload(&buf, tooManyColors, nil))
+}
@@ -0,0 +1,19 @@
+//go:build !js && !wasm
+
+package cart
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"io"
+)
+
+// Encode writes the cartridge as a GIF showing label, see WritePayload
+func Encode(w io.Writer, c Cartridge, label *image.Paletted) error {
+	data, err := json.Marshal(payload{Program: c.Source, Binary: hex.EncodeToString(c.ROM), Options: c.Options})
+	if err != nil {
+		return err
+	}
+	return WritePayload(w, label, data)
+}
@@ -0,0 +1,50 @@
+package cart
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/wasmjson"
+)
+
+// ------------------------------------------------
+// The JSON payload. Decoded with wasmjson on both builds, Encode is
+// command-line only, see encode.go.
+// ------------------------------------------------
+
+func init() {
+	chip8.RegisterFormat("octo", "GIF8?a", decodeChip8)
+}
+
+type payload struct {
+	Program string  `json:"program,omitempty"`
+	Binary  string  `json:"binary,omitempty"` // Hex
+	Options Options `json:"options"`
+}
+
+// Decode reads a cartridge GIF
+func Decode(data []byte) (Cartridge, error) {
+	hidden, err := ReadPayload(data)
+	if err != nil {
+		return Cartridge{}, err
+	}
+
+	var p payload
+	if err := wasmjson.Unmarshal(hidden, &p); err != nil {
+		return Cartridge{}, fmt.Errorf("cart: invalid payload: %w", err)
+	}
+	rom, err := hex.DecodeString(p.Binary)
+	if err != nil {
+		return Cartridge{}, fmt.Errorf("cart: invalid binary: %w", err)
+	}
+	return Cartridge{ROM: rom, Source: p.Program, Options: p.Options}, nil
+}
+
+func decodeChip8(data []byte) (chip8.Cartridge, error) {
+	c, err := Decode(data)
+	if err != nil {
+		return chip8.Cartridge{}, err
+	}
+	return c.Chip8()
+}
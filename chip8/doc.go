@@ -0,0 +1,23 @@
+// Package chip8 is the CHIP-8 interpreter core, with no dependencies on a
+// window system, so it can be embedded in other programs.
+//
+// Create a machine with New, load a program with LoadROM and drive it either
+// one instruction at a time with Step and TickTimers from a 60 Hz frame loop
+// of your own, or with Run. Press keys with UpdateKeyboardState and read the
+// display with GetDisplay, or Frame in the blended present modes. State
+// returns a copy of everything else a debugger needs, SetState writes it
+// back and SetHooks lets it stop at breakpoints.
+//
+// LoadROM also accepts programs in container formats registered with
+// RegisterFormat and applies the settings they carry. Import package cart
+// for Octo-style cartridges.
+//
+// # Compatibility
+//
+// New, Options, LoadROM, Step, TickTimers, Run, Start, Stop, Reset, State,
+// RegisterFormat, ErrHalted, the Key, Opcode and Cartridge types, the save
+// state format and the exported constants follow semantic versioning: they
+// won't change incompatibly without a new major version. NewChip8, LoadBytes, Fetch, ExecuteInstruction and the PC
+// and I fields are kept for existing callers, new code should prefer the
+// functions above.
+package chip8
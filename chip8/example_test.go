@@ -0,0 +1,54 @@
+package chip8_test
+
+import (
+	"fmt"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func Example() {
+	// 6005: V0 = 5, 7003: V0 += 3, 1204: jump to itself
+	rom := []byte{0x60, 0x05, 0x70, 0x03, 0x12, 0x04}
+
+	machine := chip8.New(chip8.Options{})
+	if err := machine.LoadROM(rom); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// One 60 Hz frame
+	for i := 0; i < machine.Speed()/chip8.TIMER_HZ; i++ {
+		machine.Step()
+	}
+	machine.TickTimers()
+
+	state := machine.State()
+	fmt.Printf("V0=%d PC=%#x\n", state.V[0], state.PC)
+	// Output: V0=8 PC=0x204
+}
+
+func ExampleChip8_UpdateKeyboardState() {
+	// F00A: wait for a key and store it in V0
+	machine := chip8.New(chip8.Options{})
+	if err := machine.LoadROM([]byte{0xF0, 0x0A}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	machine.Step()
+	fmt.Printf("waiting: PC=%#x\n", machine.State().PC)
+
+	machine.UpdateKeyboardState(chip8.KEY_A, true)
+	machine.Step()
+	state := machine.State()
+	fmt.Printf("pressed: PC=%#x V0=%#x\n", state.PC, state.V[0])
+	// Output:
+	// waiting: PC=0x200
+	// pressed: PC=0x202 V0=0xa
+}
+
+func ExampleOpcode() {
+	op := chip8.Opcode(0xD12F)
+	fmt.Println(op, op.X(), op.Y(), op.N(), op.NNN())
+	// Output: D12F 1 2 15 303
+}
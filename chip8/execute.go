@@ -1,24 +1,12 @@
 package chip8
 
 import (
-	"fmt"
 	"os"
-	"os/exec"
-	"time"
-
-	"golang.org/x/exp/rand"
+	"strconv"
+	"strings"
 )
 
-func (chip8 *Chip8) Initialize() {
-	// Delay timer and sound timer will keep running
-	go chip8.initDelaySoundTimers()
-
-	if chip8.speedHz <= 0 {
-		chip8.speedHz = 700 // fallback default
-	}
-}
-
-func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
+func (chip8 *Chip8) ExecuteInstruction(instruction Opcode) {
 	switch {
 	case instruction == 0x00E0:
 		chip8.clearDisplay()
@@ -57,12 +45,20 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 	// 2NNN
 	case instruction.firstNibble().equals(0x2):
 		nnn := instruction.nnn()
+		if len(chip8.stack) == STACK_SIZE {
+			warn("2NNN: stack overflow, more than " + strconv.Itoa(STACK_SIZE) + " nested calls")
+			break
+		}
 		// Push the address of the next instruction (PC already points to it)
 		chip8.stack = append(chip8.stack, chip8.PC)
 		chip8.jumpTo(nnn)
 
 	// 00EE
 	case instruction == 0x00EE:
+		if len(chip8.stack) == 0 {
+			warn("00EE: return with an empty stack")
+			break
+		}
 		poppedInstruction := chip8.popStack()
 		chip8.setPC(poppedInstruction)
 
@@ -112,14 +108,14 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 	case instruction.firstNibble().equals(0xC):
 		nn := instruction.nn()
 		x := instruction.x()
-		randVal := byte(rand.Intn(256))
+		randVal := chip8.randomByte()
 		chip8.setRegister(x, randVal&nn)
 
 	// EX9E: Skip next instruction if key in VX is pressed
 	case instruction.firstNibble().equals(0xE) && instruction.nn() == 0x9E:
 		x := instruction.x()
 		vx := chip8.registers[x]
-		if chip8.isKeyPressed(vx) {
+		if chip8.IsKeyPressed(Key(vx)) {
 			chip8.PC += 2
 		}
 
@@ -127,7 +123,7 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 	case instruction.firstNibble().equals(0xE) && instruction.nn() == 0xA1:
 		x := instruction.x()
 		vx := chip8.registers[x]
-		if !chip8.isKeyPressed(vx) {
+		if !chip8.IsKeyPressed(Key(vx)) {
 			chip8.PC += 2
 		}
 
@@ -164,10 +160,10 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 		keyFound := false
 		for _, chip8Key := range keys {
 			chip8.keyboardMu.Lock()
-			state := chip8.keyboardState[uint8(chip8Key)]
+			state := chip8.keyboardState[chip8Key]
 			chip8.keyboardMu.Unlock()
 			if state {
-				chip8.setRegister(x, chip8Key)
+				chip8.setRegister(x, uint8(chip8Key))
 				keyFound = true
 				break
 			}
@@ -192,7 +188,7 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 			chip8.memory[chip8.I+1] = (vx / 10) % 10
 			chip8.memory[chip8.I+2] = vx % 10
 		} else {
-			fmt.Printf("[WARN] FX33: I out of bounds: I=0x%X\n", chip8.I)
+			warn("FX33: I out of bounds: I=0x" + hex(chip8.I))
 		}
 
 	// FX55: Store V0 through VX in memory starting at I (modern: I unchanged)
@@ -203,7 +199,7 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 				chip8.memory[chip8.I+uint16(i)] = chip8.registers[i]
 			}
 		} else {
-			fmt.Printf("[WARN] FX55: I+X out of bounds: I=0x%X, X=0x%X\n", chip8.I, x)
+			warn("FX55: I+X out of bounds: I=0x" + hex(chip8.I) + ", X=0x" + hex(uint16(x)))
 		}
 
 	// FX65: Load V0 through VX from memory starting at I (modern: I unchanged)
@@ -214,7 +210,7 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 				chip8.registers[i] = chip8.memory[chip8.I+uint16(i)]
 			}
 		} else {
-			fmt.Printf("[WARN] FX65: I+X out of bounds: I=0x%X, X=0x%X\n", chip8.I, x)
+			warn("FX65: I+X out of bounds: I=0x" + hex(chip8.I) + ", X=0x" + hex(uint16(x)))
 		}
 
 	}
@@ -224,7 +220,7 @@ func (chip8 *Chip8) ExecuteInstruction(instruction instruction) {
 // Fetches the 16-byte instruction
 // TODO: What happens if PC overshoots the cycle?
 // ------------------------------------------------
-func (chip8 *Chip8) Fetch() instruction {
+func (chip8 *Chip8) Fetch() Opcode {
 	var rawInstruction uint16
 
 	firstByte := chip8.memory[chip8.PC]
@@ -237,7 +233,7 @@ func (chip8 *Chip8) Fetch() instruction {
 	secondByteExtended := uint16(secondByte)
 	rawInstruction |= secondByteExtended
 
-	inst := instruction(rawInstruction)
+	inst := Opcode(rawInstruction)
 
 	return inst
 }
@@ -343,27 +339,6 @@ func isBitOn(val uint8, idx int) int {
 	return 0
 }
 
-func (chip8 *Chip8) printDisplay() {
-	for i := 0; i < DISPLAY_ROWS; i++ {
-		for j := 0; j < DISPLAY_COLS; j++ {
-			if chip8.display[i][j] == 0 {
-				fmt.Printf("%d", 0)
-			} else {
-				fmt.Printf("%d", 1)
-			}
-
-		}
-		fmt.Println()
-	}
-}
-
-// Function to clear the console screen
-func (chip8 *Chip8) clearConsole() {
-	cmd := exec.Command("clear") // "cls" for Windows
-	cmd.Stdout = os.Stdout
-	cmd.Run()
-}
-
 func (chip8 *Chip8) pcToStack() {
 	chip8.stack = append(chip8.stack, chip8.PC)
 }
@@ -414,7 +389,7 @@ func (chip8 *Chip8) skipInstructionIfRegistersNotEqualEachOther(regXIdx, regYIdx
 }
 
 // 8X set of instructions
-func (chip8 *Chip8) logicalAndArithmetic(i instruction) {
+func (chip8 *Chip8) logicalAndArithmetic(i Opcode) {
 	x := i.x()
 	y := i.y()
 	n := i.n()
@@ -504,35 +479,54 @@ func (chip8 *Chip8) logicalAndArithmetic(i instruction) {
 	}
 }
 
-// Add new function for keyboard state management
-func (chip8 *Chip8) UpdateKeyboardState(key uint8, state bool) {
+// UpdateKeyboardState presses or releases a key of the hex keypad. It is
+// safe to call while the machine runs, keys above KEY_F are ignored.
+func (chip8 *Chip8) UpdateKeyboardState(key Key, state bool) {
+	if key > KEY_F {
+		return
+	}
 	chip8.keyboardMu.Lock()
 	chip8.keyboardState[key] = state
 	chip8.keyboardMu.Unlock()
 }
 
-// Update isKeyPressed to use internal state
-func (chip8 *Chip8) isKeyPressed(key uint8) bool {
+// IsKeyPressed reports whether a key of the hex keypad is held, keys above
+// KEY_F never are
+func (chip8 *Chip8) IsKeyPressed(key Key) bool {
+	if key > KEY_F {
+		return false
+	}
 	chip8.keyboardMu.Lock()
 	state := chip8.keyboardState[key]
 	chip8.keyboardMu.Unlock()
 	return state
 }
 
-func (chip8 *Chip8) initDelaySoundTimers() {
-	delaySpeedHz := 60
-	delayTicker := time.NewTicker(time.Second / time.Duration(delaySpeedHz))
-	defer delayTicker.Stop()
-	for {
-		select {
-		case <-delayTicker.C:
-			if chip8.delayTimer > 0 {
-				chip8.delayTimer -= 1
-			}
-			if chip8.soundTimer > 0 {
-				chip8.soundTimer -= 1
-				fmt.Print("\a") // ASCII Bell character - make computer beep as long as > 0
-			}
-		}
+// ------------------------------------------------
+// Decrements the delay and sound timers by one 60 Hz tick.
+// Returns true if the sound timer was active, i.e. the buzzer should sound.
+// Front-ends that drive their own frame clock call this once per frame, Run
+// calls it on its own 60 Hz clock.
+// ------------------------------------------------
+func (chip8 *Chip8) TickTimers() bool {
+	if chip8.delayTimer > 0 {
+		chip8.delayTimer -= 1
+	}
+	if chip8.soundTimer > 0 {
+		chip8.soundTimer -= 1
+		return true
 	}
+	return false
+}
+
+// ------------------------------------------------
+// Warnings are built by hand rather than with fmt, which relies on
+// reflection and is slow and large under TinyGo
+// ------------------------------------------------
+func warn(msg string) {
+	os.Stdout.WriteString("[WARN] " + msg + "\n")
+}
+
+func hex(val uint16) string {
+	return strings.ToUpper(strconv.FormatUint(uint64(val), 16))
 }
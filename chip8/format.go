@@ -0,0 +1,102 @@
+package chip8
+
+import "sync"
+
+// ------------------------------------------------
+// Container formats programs are distributed in, e.g. Octo cartridges.
+// LoadBytes recognises a registered format by its magic prefix and loads
+// the program inside it with the settings it was saved with. Formats
+// register themselves from an init function like image decoders do, so the
+// core doesn't link their dependencies unless a program imports them.
+// ------------------------------------------------
+
+// Cartridge is a program decoded from a container along with its settings
+type Cartridge struct {
+	Format  string  // Name the format was registered with
+	ROM     []byte  // The program, loaded at PROGRAM_START
+	Speed   int     // Instructions per second, 0 to keep the current speed
+	Quirks  *Quirks // nil to keep the current quirks
+	Palette string  // "BACKGROUND,FOREGROUND" hex colours, "" if none. Front-ends apply it.
+
+	// Shown and bound by front-ends, both optional
+	Title string
+	Keys  map[string]Key // Keys the program uses by their role, e.g. "up" or "a"
+}
+
+type format struct {
+	name   string
+	magic  string
+	decode func(data []byte) (Cartridge, error)
+}
+
+var (
+	formatsMu sync.Mutex
+	formats   []format
+)
+
+// ------------------------------------------------
+// RegisterFormat makes LoadBytes and LoadROM decode data starting with
+// magic with decode. A "?" in magic matches any byte.
+// ------------------------------------------------
+func RegisterFormat(name, magic string, decode func(data []byte) (Cartridge, error)) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats = append(formats, format{name: name, magic: magic, decode: decode})
+}
+
+// The registered format data is in, false for a plain ROM
+func sniffFormat(data []byte) (format, bool) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	for _, f := range formats {
+		if matchMagic(f.magic, data) {
+			return f, true
+		}
+	}
+	return format{}, false
+}
+
+func matchMagic(magic string, data []byte) bool {
+	if len(data) < len(magic) {
+		return false
+	}
+	for i := 0; i < len(magic); i++ {
+		if magic[i] != '?' && magic[i] != data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f format) decodeCartridge(data []byte) (Cartridge, error) {
+	cart, err := f.decode(data)
+	if err != nil {
+		return Cartridge{}, err
+	}
+	cart.Format = f.name
+	return cart, nil
+}
+
+// Applies the settings of the container the loaded ROM came from, nil for a
+// plain ROM
+func (chip8 *Chip8) applyCartridge(cart *Cartridge) {
+	chip8.cartridge = cart
+	if cart == nil {
+		return
+	}
+	if cart.Speed != 0 {
+		chip8.SetSpeed(cart.Speed)
+	}
+	if cart.Quirks != nil {
+		chip8.SetQuirks(*cart.Quirks)
+	}
+}
+
+// Cartridge returns the container the loaded program came from, false if it
+// was loaded as a plain ROM
+func (chip8 *Chip8) Cartridge() (Cartridge, bool) {
+	if chip8.cartridge == nil {
+		return Cartridge{}, false
+	}
+	return *chip8.cartridge, true
+}
@@ -0,0 +1,63 @@
+package chip8
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// A container holding the ROM after a 4 byte header, "TST" and a byte that
+// selects the decoded settings
+func init() {
+	RegisterFormat("test", "TST?", func(data []byte) (Cartridge, error) {
+		switch data[3] {
+		case 0:
+			return Cartridge{ROM: data[4:]}, nil
+		case 1:
+			return Cartridge{ROM: data[4:], Speed: 1200, Quirks: &Quirks{Shift: true}, Palette: "#000000,#FFFFFF"}, nil
+		default:
+			return Cartridge{}, errors.New("bad header")
+		}
+	})
+}
+
+func TestChip8_LoadROM_Cartridge(t *testing.T) {
+	chip8 := New(Options{Speed: 700, Quirks: Quirks{Jump: true}})
+	require.NoError(t, chip8.LoadROM([]byte{'T', 'S', 'T', 1, 0x60, 0x05}))
+
+	require.Equal(t, []byte{0x60, 0x05}, chip8.memory[PROGRAM_START:PROGRAM_START+2])
+	require.Equal(t, byte(0), chip8.memory[PROGRAM_START+2])
+	require.Equal(t, 1200, chip8.Speed())
+	require.Equal(t, Quirks{Shift: true}, chip8.Quirks())
+
+	cart, ok := chip8.Cartridge()
+	require.True(t, ok)
+	require.Equal(t, "test", cart.Format)
+	require.Equal(t, "#000000,#FFFFFF", cart.Palette)
+	require.Equal(t, []byte{0x60, 0x05}, chip8.ROM())
+
+	// Reset restores the decoded program, not the container
+	chip8.memory[PROGRAM_START] = 0
+	chip8.Reset()
+	require.Equal(t, []byte{0x60, 0x05}, chip8.memory[PROGRAM_START:PROGRAM_START+2])
+
+	// Settings the container leaves out are kept
+	chip8 = New(Options{Speed: 700, Quirks: Quirks{Jump: true}})
+	require.NoError(t, chip8.LoadROM([]byte{'T', 'S', 'T', 0, 0x60, 0x05}))
+	require.Equal(t, 700, chip8.Speed())
+	require.Equal(t, Quirks{Jump: true}, chip8.Quirks())
+
+	// A plain ROM forgets the cartridge
+	require.NoError(t, chip8.LoadROM([]byte{0x60, 0x05}))
+	_, ok = chip8.Cartridge()
+	require.False(t, ok)
+
+	err := chip8.LoadROM([]byte{'T', 'S', 'T', 2})
+	require.EqualError(t, err, "bad header")
+
+	// Too short to match the magic
+	require.NoError(t, chip8.LoadROM([]byte{'T', 'S', 'T'}))
+	_, ok = chip8.Cartridge()
+	require.False(t, ok)
+}
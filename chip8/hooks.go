@@ -0,0 +1,21 @@
+package chip8
+
+// ------------------------------------------------
+// Hooks let debuggers and profilers follow the fetch/execute cycle of Step.
+// Both are optional and are called on the goroutine that calls Step.
+// ------------------------------------------------
+type Hooks struct {
+	// Fetch is called with the instruction at pc before it executes.
+	// Returning false skips it and leaves PC at pc, e.g. at a breakpoint.
+	// A Runner keeps calling Step until its frame is done, so the hook is
+	// asked again.
+	Fetch func(pc uint16, op Opcode) bool
+
+	// Execute is called once the instruction fetched at pc has executed
+	Execute func(pc uint16, op Opcode)
+}
+
+// SetHooks replaces the machine's hooks, the zero Hooks removes them
+func (chip8 *Chip8) SetHooks(hooks Hooks) {
+	chip8.hooks = hooks
+}
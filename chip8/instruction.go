@@ -1,5 +1,7 @@
 package chip8
 
+import "strings"
+
 // ------------------------------------------------
 // Methods for extracting emulator instruction
 // into constituent parts
@@ -7,7 +9,8 @@ package chip8
 
 type nibble uint8 // leftmost 4 bits will always be zero
 
-type instruction uint16
+// Opcode is a raw 16-bit instruction as returned by Fetch
+type Opcode uint16
 
 const (
 	NIBBLE_0 = nibble(0)
@@ -31,7 +34,7 @@ const (
 // ------------------------------------------------
 // First nibble doesn't seem to have any specific name unlike other nibbles
 // ------------------------------------------------
-func (i instruction) firstNibble() nibble {
+func (i Opcode) firstNibble() nibble {
 	mask := uint16(0xF000)
 	val := uint16(i)
 	res := val & mask
@@ -43,7 +46,7 @@ func (i instruction) firstNibble() nibble {
 // ------------------------------------------------
 // The second nibble is called x
 // ------------------------------------------------
-func (i instruction) x() nibble {
+func (i Opcode) x() nibble {
 	mask := uint16(0x0F00)
 	val := uint16(i)
 	res := val & mask
@@ -55,7 +58,7 @@ func (i instruction) x() nibble {
 // ------------------------------------------------
 // The third nibble is called y
 // ------------------------------------------------
-func (i instruction) y() nibble {
+func (i Opcode) y() nibble {
 	mask := uint16(0x00F0)
 	val := uint16(i)
 	res := val & mask
@@ -67,7 +70,7 @@ func (i instruction) y() nibble {
 // ------------------------------------------------
 // The fourth nibble is called n
 // ------------------------------------------------
-func (i instruction) n() nibble {
+func (i Opcode) n() nibble {
 	mask := uint16(0x000F)
 	val := uint16(i)
 	res := val & mask
@@ -78,7 +81,7 @@ func (i instruction) n() nibble {
 // ------------------------------------------------
 // The second byte is called nn
 // ------------------------------------------------
-func (i instruction) nn() byte {
+func (i Opcode) nn() byte {
 	mask := uint16(0x00FF)
 	val := uint16(i)
 	res := val & mask
@@ -96,7 +99,7 @@ func (i instruction) nn() byte {
 // ------------------------------------------------
 // The second, third and fourth nibble - leftmost nibble is always 0
 // ------------------------------------------------
-func (i instruction) nnn() uint16 {
+func (i Opcode) nnn() uint16 {
 	mask := uint16(0x0FFF)
 	val := uint16(i)
 	res := val & mask
@@ -120,3 +123,38 @@ func (n nibble) equals(val uint8) bool {
 	nVal := uint8(n)
 	return nVal == val
 }
+
+// ------------------------------------------------
+// Exported accessors, for tools that decode instructions themselves
+// ------------------------------------------------
+
+// X is the second nibble, usually a register index
+func (i Opcode) X() uint8 {
+	return uint8(i.x())
+}
+
+// Y is the third nibble, usually a register index
+func (i Opcode) Y() uint8 {
+	return uint8(i.y())
+}
+
+// N is the fourth nibble
+func (i Opcode) N() uint8 {
+	return uint8(i.n())
+}
+
+// NN is the second byte
+func (i Opcode) NN() uint8 {
+	return i.nn()
+}
+
+// NNN is the lower 12 bits, usually an address
+func (i Opcode) NNN() uint16 {
+	return i.nnn()
+}
+
+// String formats the opcode as four hex digits, e.g. 00E0
+func (i Opcode) String() string {
+	digits := hex(uint16(i))
+	return strings.Repeat("0", 4-len(digits)) + digits
+}
@@ -9,7 +9,7 @@ import (
 func TestInstruction_firstNibble(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect nibble
 	}{
 		{"all zeros", 0x0000, 0},
@@ -27,7 +27,7 @@ func TestInstruction_firstNibble(t *testing.T) {
 func TestInstruction_x(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect nibble
 	}{
 		{"all zeros", 0x0000, 0},
@@ -45,7 +45,7 @@ func TestInstruction_x(t *testing.T) {
 func TestInstruction_y(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect nibble
 	}{
 		{"all zeros", 0x0000, 0},
@@ -63,7 +63,7 @@ func TestInstruction_y(t *testing.T) {
 func TestInstruction_n(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect nibble
 	}{
 		{"all zeros", 0x0000, 0},
@@ -81,7 +81,7 @@ func TestInstruction_n(t *testing.T) {
 func TestInstruction_nn(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect byte
 	}{
 		{"all zeros", 0x0000, 0x00},
@@ -99,7 +99,7 @@ func TestInstruction_nn(t *testing.T) {
 func TestInstruction_nnn(t *testing.T) {
 	tests := []struct {
 		name   string
-		instr  instruction
+		instr  Opcode
 		expect uint16
 	}{
 		{"all zeros", 0x0000, 0x000},
@@ -132,3 +132,20 @@ func TestNibble_Equals(t *testing.T) {
 		})
 	}
 }
+
+func TestHex(t *testing.T) {
+	require.Equal(t, "0", hex(0))
+	require.Equal(t, "FFF", hex(0x0FFF))
+	require.Equal(t, "1A", hex(0x1A))
+}
+
+func TestOpcode_Exported(t *testing.T) {
+	op := Opcode(0x8AB4)
+	require.Equal(t, uint8(0xA), op.X())
+	require.Equal(t, uint8(0xB), op.Y())
+	require.Equal(t, uint8(0x4), op.N())
+	require.Equal(t, uint8(0xB4), op.NN())
+	require.Equal(t, uint16(0xAB4), op.NNN())
+	require.Equal(t, "8AB4", op.String())
+	require.Equal(t, "00E0", Opcode(0x00E0).String())
+}
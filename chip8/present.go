@@ -0,0 +1,116 @@
+package chip8
+
+import "fmt"
+
+// ------------------------------------------------
+// Presentation buffer used to reduce flicker.
+// CHIP-8 games erase and redraw sprites with XOR, so the live display is
+// often caught half drawn. In the blended modes front-ends call Vblank at
+// 60 Hz and draw Frame() instead of GetDisplay(), so only complete frames
+// are shown and pixels that are briefly off do not disappear.
+// ------------------------------------------------
+
+type PresentMode int
+
+const (
+	PRESENT_LIVE  PresentMode = iota // Frame is the display as it was at the last vblank
+	PRESENT_BLEND                    // Frame is the OR of the display at the last two vblanks
+	PRESENT_FADE                     // Pixels fade out over a number of vblanks after being turned off
+)
+
+const FRAME_MAX = 255 // Brightness of a fully lit pixel in the presentation buffer
+
+func (mode PresentMode) String() string {
+	switch mode {
+	case PRESENT_LIVE:
+		return "live"
+	case PRESENT_BLEND:
+		return "blend"
+	case PRESENT_FADE:
+		return "fade"
+	}
+	return "unknown"
+}
+
+// ParsePresentMode is the inverse of PresentMode.String
+func ParsePresentMode(name string) (PresentMode, error) {
+	for _, mode := range []PresentMode{PRESENT_LIVE, PRESENT_BLEND, PRESENT_FADE} {
+		if mode.String() == name {
+			return mode, nil
+		}
+	}
+	return PRESENT_LIVE, fmt.Errorf("unknown present mode %q", name)
+}
+
+// ------------------------------------------------
+// fadeFrames is the number of vblanks a pixel takes to fade out in
+// PRESENT_FADE mode and is ignored by the other modes
+// ------------------------------------------------
+func (chip8 *Chip8) SetPresentMode(mode PresentMode, fadeFrames int) {
+	if fadeFrames < 1 {
+		fadeFrames = 1
+	}
+	chip8.presentMode = mode
+	chip8.fadeFrames = fadeFrames
+
+	// Start fading from scratch so pixels don't carry over levels from the old mode
+	for y := 0; y < DISPLAY_ROWS; y++ {
+		for x := 0; x < DISPLAY_COLS; x++ {
+			chip8.fade[y][x] = 0
+		}
+	}
+}
+
+func (chip8 *Chip8) PresentMode() PresentMode {
+	return chip8.presentMode
+}
+
+// ------------------------------------------------
+// Frame returns the presentation buffer as of the last Vblank.
+// Values are pixel brightness from 0 (off) to FRAME_MAX (fully lit).
+// ------------------------------------------------
+func (chip8 *Chip8) Frame() [][]int {
+	return chip8.frame
+}
+
+// ------------------------------------------------
+// Vblank composes the presentation buffer from the live display.
+// Front-ends call it once per 60 Hz frame, it returns true if the frame
+// changed and needs to be presented.
+// ------------------------------------------------
+func (chip8 *Chip8) Vblank() bool {
+	changed := false
+	for y := 0; y < DISPLAY_ROWS; y++ {
+		for x := 0; x < DISPLAY_COLS; x++ {
+			lit := chip8.display[y][x] != 0
+
+			level := 0
+			switch chip8.presentMode {
+			case PRESENT_LIVE:
+				if lit {
+					level = FRAME_MAX
+				}
+
+			case PRESENT_BLEND:
+				if lit || chip8.lastVblank[y][x] != 0 {
+					level = FRAME_MAX
+				}
+
+			case PRESENT_FADE:
+				if lit {
+					chip8.fade[y][x] = chip8.fadeFrames
+				} else if chip8.fade[y][x] > 0 {
+					chip8.fade[y][x] -= 1
+				}
+				level = FRAME_MAX * chip8.fade[y][x] / chip8.fadeFrames
+			}
+
+			chip8.lastVblank[y][x] = chip8.display[y][x]
+			if chip8.frame[y][x] != level {
+				chip8.frame[y][x] = level
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,73 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVblank_Live(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+
+	chip8.display[0][0] = 1
+	require.True(t, chip8.Vblank())
+	require.Equal(t, FRAME_MAX, chip8.Frame()[0][0])
+
+	// Changes between vblanks are not visible until the next vblank
+	chip8.display[0][0] = 0
+	require.Equal(t, FRAME_MAX, chip8.Frame()[0][0])
+	require.True(t, chip8.Vblank())
+	require.Equal(t, 0, chip8.Frame()[0][0])
+
+	require.False(t, chip8.Vblank())
+}
+
+func TestVblank_Blend(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	chip8.SetPresentMode(PRESENT_BLEND, 0)
+
+	// Sprite erased and redrawn one pixel to the right
+	chip8.display[0][0] = 1
+	chip8.Vblank()
+	chip8.display[0][0] = 0
+	chip8.display[0][1] = 1
+	require.True(t, chip8.Vblank())
+	require.Equal(t, FRAME_MAX, chip8.Frame()[0][0])
+	require.Equal(t, FRAME_MAX, chip8.Frame()[0][1])
+
+	// Off for two vblanks
+	chip8.Vblank()
+	require.Equal(t, 0, chip8.Frame()[0][0])
+	require.Equal(t, FRAME_MAX, chip8.Frame()[0][1])
+}
+
+func TestVblank_Fade(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	chip8.SetPresentMode(PRESENT_FADE, 4)
+
+	chip8.display[5][5] = 1
+	chip8.Vblank()
+	require.Equal(t, FRAME_MAX, chip8.Frame()[5][5])
+
+	chip8.display[5][5] = 0
+	expected := []int{FRAME_MAX * 3 / 4, FRAME_MAX * 2 / 4, FRAME_MAX * 1 / 4, 0}
+	for _, level := range expected {
+		require.True(t, chip8.Vblank())
+		require.Equal(t, level, chip8.Frame()[5][5])
+	}
+	require.False(t, chip8.Vblank())
+}
+
+func TestPresentMode_String(t *testing.T) {
+	require.Equal(t, "live", PRESENT_LIVE.String())
+	require.Equal(t, "blend", PRESENT_BLEND.String())
+	require.Equal(t, "fade", PRESENT_FADE.String())
+
+	for _, mode := range []PresentMode{PRESENT_LIVE, PRESENT_BLEND, PRESENT_FADE} {
+		parsed, err := ParsePresentMode(mode.String())
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed)
+	}
+	_, err := ParsePresentMode("flicker")
+	require.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package chip8
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ------------------------------------------------
+// Lifecycle.
+// Front-ends with a frame loop of their own (SDL, WASM, the terminal) call
+// Step and TickTimers from it. Run is for hosts that just want the machine
+// to run, and Start/Stop run it in the background. Nothing here leaves a
+// goroutine behind: Run returns when its context is done and Stop waits for
+// the goroutine started by Start.
+// ------------------------------------------------
+
+const (
+	TIMER_HZ      = 60  // Rate the delay and sound timers count down at
+	DEFAULT_SPEED = 700 // Instructions per second when the speed isn't set
+)
+
+// ErrHalted is returned by Run once PC has run off the end of memory
+var ErrHalted = errors.New("program counter is outside memory")
+
+// Step fetches and executes one instruction, see SetHooks
+func (chip8 *Chip8) Step() {
+	pc := chip8.PC
+	instr := chip8.Fetch()
+	if chip8.hooks.Fetch != nil && !chip8.hooks.Fetch(pc, instr) {
+		return
+	}
+	chip8.NextInstruction()
+	chip8.ExecuteInstruction(instr)
+	if chip8.hooks.Execute != nil {
+		chip8.hooks.Execute(pc, instr)
+	}
+}
+
+// ------------------------------------------------
+// Run executes instructions at the machine's speed and ticks the timers at
+// TIMER_HZ until ctx is done and returns ctx.Err(), or until PC runs off the
+// end of memory and returns ErrHalted. Nothing else may touch the machine
+// while it runs, apart from the keyboard.
+// ------------------------------------------------
+func (chip8 *Chip8) Run(ctx context.Context) error {
+	speedHz := chip8.speedHz
+	if speedHz <= 0 {
+		speedHz = DEFAULT_SPEED
+	}
+
+	instrTicker := time.NewTicker(time.Second / time.Duration(speedHz))
+	defer instrTicker.Stop()
+	timerTicker := time.NewTicker(time.Second / TIMER_HZ)
+	defer timerTicker.Stop()
+
+	for {
+		// Checked first so a cancelled context stops the machine even if both tickers are ready
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-instrTicker.C:
+			// Fetch reads two bytes at PC
+			if chip8.PC > RAM-2 {
+				return ErrHalted
+			}
+			chip8.Step()
+		case <-timerTicker.C:
+			chip8.TickTimers()
+		}
+	}
+}
+
+// Start runs the machine on a new goroutine until Stop is called or ctx is
+// done. A machine that is already running is stopped first.
+func (chip8 *Chip8) Start(ctx context.Context) {
+	chip8.Stop()
+
+	chip8.runMu.Lock()
+	defer chip8.runMu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	chip8.cancelRun = cancel
+	chip8.runDone = done
+
+	go func() {
+		defer close(done)
+		chip8.Run(ctx)
+	}()
+}
+
+// Stop stops a machine started with Start and waits until it has stopped.
+// It does nothing if the machine isn't running.
+func (chip8 *Chip8) Stop() {
+	chip8.runMu.Lock()
+	defer chip8.runMu.Unlock()
+
+	if chip8.cancelRun == nil {
+		return
+	}
+	chip8.cancelRun()
+	<-chip8.runDone
+	chip8.cancelRun = nil
+	chip8.runDone = nil
+}
+
+// ------------------------------------------------
+// Reset restarts the loaded ROM as if it had just been loaded: registers,
+// timers, stack, display and keys are cleared, memory is restored to the
+// fonts and the ROM, and PC is set to 0x200.
+// ------------------------------------------------
+func (chip8 *Chip8) Reset() {
+	chip8.clearMachine()
+	copy(chip8.memory[PROGRAM_START:], chip8.rom)
+}
+
+// HardReset clears memory as well and forgets the ROM, a new one has to be
+// loaded with LoadROM
+func (chip8 *Chip8) HardReset() {
+	chip8.rom = chip8.rom[:0]
+	chip8.cartridge = nil
+	chip8.clearMachine()
+}
+
+// Clears everything in place, front-ends may hold on to the display
+func (chip8 *Chip8) clearMachine() {
+	clear(chip8.memory)
+	copy(chip8.memory[SPRITE_START_LOC:], font)
+
+	for x := range chip8.registers {
+		chip8.registers[x] = 0
+	}
+	chip8.stack = chip8.stack[:0]
+	chip8.PC = PROGRAM_START
+	chip8.I = 0
+	chip8.delayTimer = 0
+	chip8.soundTimer = 0
+
+	chip8.clearDisplay()
+	for _, buffer := range [][][]int{chip8.frame, chip8.lastVblank, chip8.fade} {
+		for _, row := range buffer {
+			clear(row)
+		}
+	}
+	chip8.redraw = true
+
+	chip8.keyboardMu.Lock()
+	clear(chip8.keyboardState)
+	chip8.keyboardMu.Unlock()
+}
@@ -0,0 +1,184 @@
+package chip8
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// The js/wasm runtime parks a goroutine of its own in runtime.handleEvent
+var ignoreJSEvents = goleak.IgnoreAnyFunction("runtime.handleEvent")
+
+// Counts V0 up forever: 7001 (V0 += 1), 1200 (jump to 0x200)
+var countROM = []byte{0x70, 0x01, 0x12, 0x00}
+
+func newCountingChip8(t *testing.T) *Chip8 {
+	t.Helper()
+	chip8 := NewChip8(false, false, 2000)
+	require.NoError(t, chip8.LoadBytes(countROM))
+	chip8.PC = 0x200
+	return chip8
+}
+
+func TestChip8_Step(t *testing.T) {
+	chip8 := newCountingChip8(t)
+	chip8.Step()
+	require.Equal(t, uint16(0x202), chip8.PC)
+	require.Equal(t, uint8(1), chip8.registers[0])
+	chip8.Step()
+	require.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestChip8_Hooks(t *testing.T) {
+	chip8 := newCountingChip8(t)
+	var fetched, executed []uint16
+	chip8.SetHooks(Hooks{
+		Fetch: func(pc uint16, op Opcode) bool {
+			fetched = append(fetched, pc)
+			return op != 0x1200 // Break at the jump
+		},
+		Execute: func(pc uint16, op Opcode) {
+			executed = append(executed, pc)
+		},
+	})
+
+	chip8.Step()
+	chip8.Step()
+	require.Equal(t, []uint16{0x200, 0x202}, fetched)
+	require.Equal(t, []uint16{0x200}, executed)
+	require.Equal(t, uint16(0x202), chip8.PC)
+
+	chip8.SetHooks(Hooks{})
+	chip8.Step()
+	require.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestChip8_Run(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	chip8 := newCountingChip8(t)
+	chip8.delayTimer = 255
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, chip8.Run(ctx), context.DeadlineExceeded)
+
+	// Both clocks ran
+	require.NotZero(t, chip8.registers[0])
+	require.Less(t, chip8.DelayTimer(), byte(255))
+}
+
+func TestChip8_RunCancelled(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	chip8 := newCountingChip8(t)
+	require.ErrorIs(t, chip8.Run(ctx), context.Canceled)
+	require.Equal(t, uint16(0x200), chip8.PC)
+}
+
+func TestChip8_RunHalted(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	// Runs 0000 off the end of memory
+	chip8 := New(Options{})
+	require.NoError(t, chip8.LoadROM([]byte{0x00, 0x00}))
+	chip8.PC = RAM - 4
+	require.ErrorIs(t, chip8.Run(context.Background()), ErrHalted)
+	require.Equal(t, uint16(RAM), chip8.PC)
+}
+
+func TestChip8_StartStop(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	var steps atomic.Int32
+	var stopped, late atomic.Bool
+	chip8 := newCountingChip8(t)
+	chip8.SetHooks(Hooks{Fetch: func(pc uint16, op Opcode) bool {
+		late.Store(stopped.Load())
+		steps.Add(1)
+		return true
+	}})
+	chip8.Stop() // not running, nothing to do
+
+	chip8.Start(context.Background())
+	chip8.Start(context.Background()) // restarts rather than running twice
+	require.Eventually(t, func() bool { return steps.Load() >= 10 }, 5*time.Second, time.Millisecond)
+	chip8.Stop()
+	stopped.Store(true)
+
+	// Nothing runs after Stop returns, the goroutine is gone
+	chip8.Stop()
+	require.False(t, late.Load())
+}
+
+func TestChip8_StartCancelled(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	chip8 := newCountingChip8(t)
+	chip8.Start(ctx)
+	cancel()
+
+	// Stop still waits for the goroutine that the context stopped
+	chip8.Stop()
+}
+
+func TestChip8_Reset(t *testing.T) {
+	chip8 := newCountingChip8(t)
+	fresh := append([]byte(nil), chip8.memory...)
+
+	chip8.Step()
+	chip8.I = 0x300
+	chip8.delayTimer = 10
+	chip8.soundTimer = 10
+	chip8.stack = append(chip8.stack, 0x202)
+	chip8.memory[0x200] = 0xFF // self-modifying ROM
+	chip8.memory[0x400] = 0xAB
+	chip8.display[3][4] = 1
+	chip8.frame[3][4] = FRAME_MAX
+	chip8.UpdateKeyboardState(0xA, true)
+	display := chip8.GetDisplay()
+
+	chip8.Reset()
+	require.Equal(t, fresh, chip8.memory)
+	require.Equal(t, [16]uint8{}, chip8.Registers())
+	require.Equal(t, uint16(0x200), chip8.PC)
+	require.Zero(t, chip8.I)
+	require.Zero(t, chip8.DelayTimer())
+	require.Zero(t, chip8.SoundTimer())
+	require.Empty(t, chip8.Stack())
+	require.Zero(t, display[3][4], "display is cleared in place")
+	require.Zero(t, chip8.Frame()[3][4])
+	require.False(t, chip8.IsKeyPressed(0xA))
+	require.True(t, chip8.ShouldRedraw())
+
+	// The ROM runs again from the start
+	chip8.Step()
+	require.Equal(t, uint8(1), chip8.registers[0])
+}
+
+func TestChip8_HardReset(t *testing.T) {
+	chip8 := newCountingChip8(t)
+	chip8.Step()
+
+	chip8.HardReset()
+	require.Equal(t, font, chip8.memory[SPRITE_START_LOC:SPRITE_START_LOC+len(font)])
+	require.Equal(t, make([]byte, RAM-0x200), chip8.memory[0x200:])
+	require.Equal(t, [16]uint8{}, chip8.Registers())
+	require.Equal(t, uint16(0x200), chip8.PC)
+
+	// Nothing to restore after a hard reset
+	chip8.Reset()
+	require.Equal(t, make([]byte, RAM-0x200), chip8.memory[0x200:])
+
+	require.NoError(t, chip8.LoadBytes(countROM))
+	chip8.Step()
+	require.Equal(t, uint8(1), chip8.registers[0])
+}
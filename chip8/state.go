@@ -0,0 +1,164 @@
+package chip8
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ------------------------------------------------
+// State is a snapshot of the machine for debuggers and tools. It is a copy,
+// changing it doesn't change the machine.
+// ------------------------------------------------
+type State struct {
+	PC         uint16
+	I          uint16
+	V          [16]uint8
+	Stack      []uint16 // Return addresses pushed by 2NNN, innermost last
+	DelayTimer byte
+	SoundTimer byte
+	Memory     [RAM]byte
+	Keys       [16]bool // Indexed by Key
+}
+
+// State returns a copy of the registers, timers, stack, memory and keys
+func (chip8 *Chip8) State() State {
+	state := State{
+		PC:         chip8.PC,
+		I:          chip8.I,
+		V:          chip8.Registers(),
+		Stack:      chip8.Stack(),
+		DelayTimer: chip8.delayTimer,
+		SoundTimer: chip8.soundTimer,
+	}
+	copy(state.Memory[:], chip8.memory)
+
+	chip8.keyboardMu.Lock()
+	for key, pressed := range chip8.keyboardState {
+		state.Keys[key] = pressed
+	}
+	chip8.keyboardMu.Unlock()
+	return state
+}
+
+// SetState changes the registers, timers, stack, memory and keys to those
+// of state, e.g. for a debugger writing registers. The machine is left
+// untouched if the stack is deeper than STACK_SIZE.
+func (chip8 *Chip8) SetState(state State) error {
+	if len(state.Stack) > STACK_SIZE {
+		return fmt.Errorf("the stack holds at most %d return addresses", STACK_SIZE)
+	}
+	chip8.PC = state.PC
+	chip8.I = state.I
+	for x, v := range state.V {
+		chip8.registers[nibble(x)] = v
+	}
+	chip8.stack = append(chip8.stack[:0], state.Stack...)
+	chip8.delayTimer = state.DelayTimer
+	chip8.soundTimer = state.SoundTimer
+	copy(chip8.memory, state.Memory[:])
+
+	chip8.keyboardMu.Lock()
+	for key, pressed := range state.Keys {
+		chip8.keyboardState[Key(key)] = pressed
+	}
+	chip8.keyboardMu.Unlock()
+	return nil
+}
+
+// ------------------------------------------------
+// Save states.
+// A state holds everything a running ROM can observe: memory, registers,
+// timers, the stack and the display. Keys, speed, quirks and the present
+// mode are up to the front-end and are not saved.
+//
+// Layout, multi-byte values big endian:
+//
+//	"CH8S" version
+//	memory[RAM] V0-VF I PC delayTimer soundTimer
+//	stackDepth stack[stackDepth]
+//	display, 8 pixels per byte row by row, leftmost pixel in the high bit
+// ------------------------------------------------
+
+const (
+	stateMagic   = "CH8S"
+	stateVersion = 1
+	stateFixed   = len(stateMagic) + 1 + RAM + 16 + 2 + 2 + 1 + 1 + 1
+	displayBytes = DISPLAY_ROWS * DISPLAY_COLS / 8
+)
+
+// MarshalBinary saves the machine state, see encoding.BinaryMarshaler
+func (chip8 *Chip8) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, stateFixed+len(chip8.stack)*2+displayBytes)
+	data = append(data, stateMagic...)
+	data = append(data, stateVersion)
+	data = append(data, chip8.memory...)
+	for x := nibble(0); x < 16; x++ {
+		data = append(data, chip8.registers[x])
+	}
+	data = binary.BigEndian.AppendUint16(data, chip8.I)
+	data = binary.BigEndian.AppendUint16(data, chip8.PC)
+	data = append(data, chip8.delayTimer, chip8.soundTimer)
+
+	data = append(data, byte(len(chip8.stack)))
+	for _, addr := range chip8.stack {
+		data = binary.BigEndian.AppendUint16(data, addr)
+	}
+
+	for _, row := range chip8.display {
+		for col := 0; col < DISPLAY_COLS; col += 8 {
+			var packed byte
+			for bit := 0; bit < 8; bit++ {
+				if row[col+bit] != 0 {
+					packed |= 0x80 >> bit
+				}
+			}
+			data = append(data, packed)
+		}
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores a state saved by MarshalBinary. The emulator is
+// left untouched if the state is invalid.
+func (chip8 *Chip8) UnmarshalBinary(data []byte) error {
+	if len(data) < stateFixed || string(data[:len(stateMagic)]) != stateMagic {
+		return fmt.Errorf("not a CHIP-8 save state")
+	}
+	if version := data[len(stateMagic)]; version != stateVersion {
+		return fmt.Errorf("unsupported save state version %d", version)
+	}
+	depth := int(data[stateFixed-1])
+	if depth > STACK_SIZE || len(data) != stateFixed+depth*2+displayBytes {
+		return fmt.Errorf("save state is corrupt")
+	}
+
+	pos := len(stateMagic) + 1
+	copy(chip8.memory, data[pos:pos+RAM])
+	pos += RAM
+	for x := nibble(0); x < 16; x++ {
+		chip8.registers[x] = data[pos]
+		pos++
+	}
+	chip8.I = binary.BigEndian.Uint16(data[pos:])
+	chip8.PC = binary.BigEndian.Uint16(data[pos+2:])
+	chip8.delayTimer = data[pos+4]
+	chip8.soundTimer = data[pos+5]
+	pos += 7
+
+	chip8.stack = chip8.stack[:0]
+	for i := 0; i < depth; i++ {
+		chip8.stack = append(chip8.stack, binary.BigEndian.Uint16(data[pos:]))
+		pos += 2
+	}
+
+	for _, row := range chip8.display {
+		for col := 0; col < DISPLAY_COLS; col += 8 {
+			for bit := 0; bit < 8; bit++ {
+				row[col+bit] = int(data[pos]>>(7-bit)) & 1
+			}
+			pos++
+		}
+	}
+	chip8.redraw = true
+	return nil
+}
@@ -0,0 +1,128 @@
+package chip8
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChip8_SaveState(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	require.NoError(t, chip8.LoadBytes([]byte{0x60, 0x05, 0x22, 0x08}))
+	chip8.PC = 0x200
+	chip8.I = 0x0ABC
+	chip8.delayTimer = 30
+	chip8.soundTimer = 4
+	chip8.display[0][0] = 1
+	chip8.display[31][63] = 1
+	chip8.ExecuteInstruction(chip8.Fetch()) // V0 = 5
+	chip8.NextInstruction()
+	chip8.NextInstruction()
+	chip8.ExecuteInstruction(0x2208) // call 0x208
+
+	state, err := chip8.MarshalBinary()
+	require.NoError(t, err)
+
+	restored := NewChip8(false, false, 700)
+	require.NoError(t, restored.UnmarshalBinary(state))
+	require.Equal(t, chip8.memory, restored.memory)
+	require.Equal(t, chip8.Registers(), restored.Registers())
+	require.Equal(t, uint16(0x0ABC), restored.I)
+	require.Equal(t, uint16(0x208), restored.PC)
+	require.Equal(t, byte(30), restored.DelayTimer())
+	require.Equal(t, byte(4), restored.SoundTimer())
+	require.Equal(t, []uint16{0x204}, restored.Stack())
+	require.Equal(t, chip8.GetDisplay(), restored.GetDisplay())
+	require.True(t, restored.ShouldRedraw())
+
+	// The state round trips byte for byte
+	again, err := restored.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, state, again)
+}
+
+func TestChip8_SaveStateFullStack(t *testing.T) {
+	// Calls itself forever: 2200
+	chip8 := New(Options{})
+	require.NoError(t, chip8.LoadROM([]byte{0x22, 0x00}))
+	for i := 0; i < 300; i++ {
+		chip8.Step()
+	}
+	require.Len(t, chip8.Stack(), STACK_SIZE)
+
+	data, err := chip8.MarshalBinary()
+	require.NoError(t, err)
+	restored := New(Options{})
+	require.NoError(t, restored.UnmarshalBinary(data))
+	require.Equal(t, chip8.Stack(), restored.Stack())
+}
+
+func TestChip8_LoadInvalidState(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	state, err := chip8.MarshalBinary()
+	require.NoError(t, err)
+
+	require.ErrorContains(t, chip8.UnmarshalBinary([]byte("PNG")), "not a CHIP-8 save state")
+	require.ErrorContains(t, chip8.UnmarshalBinary(state[:len(state)-1]), "corrupt")
+
+	future := append([]byte(nil), state...)
+	future[4] = 2
+	require.ErrorContains(t, chip8.UnmarshalBinary(future), "version 2")
+}
+
+func TestChip8_State(t *testing.T) {
+	chip8 := New(Options{})
+	require.NoError(t, chip8.LoadROM([]byte{0x6A, 0x42, 0xA3, 0x00, 0x22, 0x08}))
+	chip8.UpdateKeyboardState(KEY_F, true)
+	for i := 0; i < 3; i++ {
+		chip8.Step()
+	}
+
+	state := chip8.State()
+	require.Equal(t, uint16(0x208), state.PC)
+	require.Equal(t, uint16(0x300), state.I)
+	require.Equal(t, uint8(0x42), state.V[0xA])
+	require.Equal(t, []uint16{0x206}, state.Stack)
+	require.Equal(t, byte(0x6A), state.Memory[PROGRAM_START])
+	require.True(t, state.Keys[KEY_F])
+
+	// The state is a copy
+	state.Memory[PROGRAM_START] = 0
+	state.Stack[0] = 0
+	require.Equal(t, byte(0x6A), chip8.memory[PROGRAM_START])
+	require.Equal(t, []uint16{0x206}, chip8.Stack())
+}
+
+func TestChip8_KeysAboveF(t *testing.T) {
+	chip8 := New(Options{})
+	require.NotPanics(t, func() {
+		chip8.UpdateKeyboardState(Key(16), true)
+		chip8.UpdateKeyboardState(Key(0xFF), true)
+	})
+	require.False(t, chip8.IsKeyPressed(Key(16)))
+	require.NotPanics(t, func() { chip8.State() })
+	require.Equal(t, [16]bool{}, chip8.State().Keys)
+}
+
+func TestChip8_SetState(t *testing.T) {
+	chip8 := New(Options{})
+	require.NoError(t, chip8.LoadROM([]byte{0x60, 0x05}))
+	state := chip8.State()
+	state.V[0xA] = 0x42
+	state.I = 0x300
+	state.PC = 0x208
+	state.Stack = []uint16{0x204}
+	state.DelayTimer = 9
+	state.Memory[0x300] = 0xFF
+	state.Keys[KEY_3] = true
+	require.NoError(t, chip8.SetState(state))
+	require.Equal(t, state, chip8.State())
+
+	// The stack is copied
+	state.Stack[0] = 0x400
+	require.Equal(t, []uint16{0x204}, chip8.Stack())
+
+	state.Stack = make([]uint16, STACK_SIZE+1)
+	require.ErrorContains(t, chip8.SetState(state), "at most 100")
+	require.Equal(t, uint16(0x208), chip8.PC)
+}
@@ -1,6 +1,9 @@
 package chip8
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
 	"sync"
 )
 
@@ -19,6 +22,7 @@ const (
 	DISPLAY_ROWS     = 32
 	SPRITE_START_LOC = 0x00
 	SPRITE_END_LOC   = 0x4F
+	PROGRAM_START    = 0x200 // ROMs are loaded and start running here
 )
 
 var font = []uint8{
@@ -40,23 +44,48 @@ var font = []uint8{
 	0xF0, 0x80, 0xF0, 0x80, 0x80, // F
 }
 
-var keys = []uint8{
-	0x1,
-	0x2,
-	0x3,
-	0xC,
-	0x4,
-	0x5,
-	0x6,
-	0xD,
-	0x7,
-	0x8,
-	0x9,
-	0xE,
-	0xA,
-	0x0,
-	0xB,
-	0xF,
+// Key is one of the 16 keys of the hex keypad
+type Key uint8
+
+const (
+	KEY_0 Key = iota
+	KEY_1
+	KEY_2
+	KEY_3
+	KEY_4
+	KEY_5
+	KEY_6
+	KEY_7
+	KEY_8
+	KEY_9
+	KEY_A
+	KEY_B
+	KEY_C
+	KEY_D
+	KEY_E
+	KEY_F
+)
+
+// Keys in keypad order, left to right and top to bottom:
+//
+//	1 2 3 C
+//	4 5 6 D
+//	7 8 9 E
+//	A 0 B F
+var keys = []Key{
+	KEY_1, KEY_2, KEY_3, KEY_C,
+	KEY_4, KEY_5, KEY_6, KEY_D,
+	KEY_7, KEY_8, KEY_9, KEY_E,
+	KEY_A, KEY_0, KEY_B, KEY_F,
+}
+
+// ------------------------------------------------
+// Quirks select between the original COSMAC VIP behaviour and the one most
+// later interpreters (and most ROMs) expect
+// ------------------------------------------------
+type Quirks struct {
+	Shift bool // 8XY6 and 8XYE set VX to VY before shifting, as on the COSMAC VIP
+	Jump  bool // BNNN jumps to NNN + V0 as on the COSMAC VIP, otherwise BXNN jumps to XNN + VX
 }
 
 // ------------------------------------------------
@@ -64,6 +93,8 @@ var keys = []uint8{
 // ------------------------------------------------
 type Chip8 struct {
 	memory        []byte
+	rom           []byte     // As last loaded, restored by Reset
+	cartridge     *Cartridge // The container rom came from, nil for a plain ROM
 	stack         []uint16
 	display       [][]int
 	registers     map[nibble]uint8
@@ -72,22 +103,56 @@ type Chip8 struct {
 	speedHz       int // Instructions per second
 	delayTimer    byte
 	soundTimer    byte
-	shift1        bool           // Configurable behaviour for shift instructions (8XY6 and 8XYE) - consider Y register or not
-	bnnn1         bool           // Configurable behaviour for BNNN instruction - BNNN or not (if not then BXNN)
-	keyboardState map[uint8]bool // Track state of each key (true if pressed)
+	shift1        bool         // Configurable behaviour for shift instructions (8XY6 and 8XYE) - consider Y register or not
+	bnnn1         bool         // Configurable behaviour for BNNN instruction - BNNN or not (if not then BXNN)
+	keyboardState map[Key]bool // Track state of each key (true if pressed)
 	keyboardMu    sync.Mutex
 	redraw        bool // main loop references this each time to determine if to redraw or not
+	presentMode   PresentMode
+	fadeFrames    int        // Number of vblanks a pixel takes to fade out in PRESENT_FADE mode
+	frame         [][]int    // Presentation buffer composed at each vblank, see present.go
+	lastVblank    [][]int    // Display as it was at the previous vblank
+	fade          [][]int    // Vblanks left until each pixel has faded out
+	random        *rand.Rand // Source of CXNN, the global one unless seeded with SetSeed
+	hooks         Hooks
+	runMu         sync.Mutex
+	cancelRun     context.CancelFunc // Stops the goroutine started by Start
+	runDone       chan struct{}      // Closed when that goroutine has returned
+}
+
+// ------------------------------------------------
+// Options configure a new Chip8, the zero value runs at DEFAULT_SPEED with
+// the modern quirks and the live display
+// ------------------------------------------------
+type Options struct {
+	Speed       int // Instructions per second, DEFAULT_SPEED if zero
+	Quirks      Quirks
+	PresentMode PresentMode
+	FadeFrames  int // See SetPresentMode
+}
+
+// New creates a Chip8 with an empty memory apart from the fonts, load a ROM
+// with LoadROM
+func New(opts Options) *Chip8 {
+	if opts.Speed == 0 {
+		opts.Speed = DEFAULT_SPEED
+	}
+	chip8 := NewChip8(opts.Quirks.Shift, opts.Quirks.Jump, opts.Speed)
+	chip8.SetPresentMode(opts.PresentMode, opts.FadeFrames)
+	return chip8
 }
 
+// NewChip8 is New with only the quirks and speed set, kept for existing callers
 func NewChip8(shift1, bnnn1 bool, speedHz int) *Chip8 {
 	chip8 := &Chip8{
 		memory:        make([]byte, RAM),
-		stack:         make([]uint16, STACK_SIZE),
+		stack:         make([]uint16, 0, STACK_SIZE),
 		display:       make([][]int, DISPLAY_ROWS),
 		speedHz:       speedHz,
 		shift1:        shift1,
 		bnnn1:         bnnn1,
-		keyboardState: make(map[uint8]bool),
+		keyboardState: make(map[Key]bool),
+		fadeFrames:    1,
 	}
 	chip8.initialize()
 	return chip8
@@ -116,6 +181,11 @@ func (chip8 *Chip8) initialize() {
 		display[i] = make([]int, DISPLAY_COLS)
 	}
 
+	// Presentation buffers have the same shape as the display
+	chip8.frame = newDisplayBuffer()
+	chip8.lastVblank = newDisplayBuffer()
+	chip8.fade = newDisplayBuffer()
+
 	// Initialize registers
 	chip8.registers = make(map[nibble]uint8)
 	registers := chip8.registers
@@ -127,10 +197,45 @@ func (chip8 *Chip8) initialize() {
 	chip8.redraw = true
 }
 
+func newDisplayBuffer() [][]int {
+	buffer := make([][]int, DISPLAY_ROWS)
+	for i := range buffer {
+		buffer[i] = make([]int, DISPLAY_COLS)
+	}
+	return buffer
+}
+
 func (chip8 *Chip8) Speed() int {
 	return chip8.speedHz
 }
 
+func (chip8 *Chip8) SetSpeed(speedHz int) {
+	chip8.speedHz = speedHz
+}
+
+func (chip8 *Chip8) Quirks() Quirks {
+	return Quirks{Shift: chip8.shift1, Jump: chip8.bnnn1}
+}
+
+func (chip8 *Chip8) SetQuirks(quirks Quirks) {
+	chip8.shift1 = quirks.Shift
+	chip8.bnnn1 = quirks.Jump
+}
+
+// SetSeed makes CXNN deterministic: machines seeded alike and given the
+// same keys run alike, as netplay needs
+func (chip8 *Chip8) SetSeed(seed int64) {
+	chip8.random = rand.New(rand.NewSource(seed))
+}
+
+// A random byte for CXNN
+func (chip8 *Chip8) randomByte() byte {
+	if chip8.random != nil {
+		return byte(chip8.random.Intn(256))
+	}
+	return byte(rand.Intn(256))
+}
+
 func (chip8 *Chip8) ProgramCounter() uint16 {
 	return chip8.PC
 }
@@ -147,13 +252,70 @@ func (chip8 *Chip8) GetDisplay() [][]int {
 	return chip8.display
 }
 
+// Registers returns a copy of V0-VF
+func (chip8 *Chip8) Registers() [16]uint8 {
+	var registers [16]uint8
+	for i := range registers {
+		registers[i] = chip8.registers[nibble(i)]
+	}
+	return registers
+}
+
+// Stack returns a copy of the return addresses pushed by 2NNN, innermost last
+func (chip8 *Chip8) Stack() []uint16 {
+	return append([]uint16(nil), chip8.stack...)
+}
+
+func (chip8 *Chip8) DelayTimer() byte {
+	return chip8.delayTimer
+}
+
+func (chip8 *Chip8) SoundTimer() byte {
+	return chip8.soundTimer
+}
+
 func (chip8 *Chip8) NextInstruction() {
 	chip8.PC += 2
 }
 
-// LoadBytes loads a ROM directly from a byte slice
+// ------------------------------------------------
+// LoadBytes loads a ROM directly from a byte slice, PC is left alone. Data
+// in a registered container format is decoded and its settings applied, see
+// RegisterFormat.
+// ------------------------------------------------
 func (chip8 *Chip8) LoadBytes(data []byte) error {
+	var cart *Cartridge
+	if f, ok := sniffFormat(data); ok {
+		decoded, err := f.decodeCartridge(data)
+		if err != nil {
+			return err
+		}
+		cart = &decoded
+		data = cart.ROM
+	}
+
+	if len(data) > RAM-PROGRAM_START {
+		return fmt.Errorf("ROM is %d bytes, only %d fit in memory", len(data), RAM-PROGRAM_START)
+	}
+
 	// Copy ROM data to memory starting at 0x200
-	copy(chip8.memory[0x200:], data)
+	copy(chip8.memory[PROGRAM_START:], data)
+	chip8.rom = append(chip8.rom[:0], data...)
+	chip8.applyCartridge(cart)
 	return nil
 }
+
+// LoadROM loads a ROM and points PC at its start, ready to run
+func (chip8 *Chip8) LoadROM(rom []byte) error {
+	if err := chip8.LoadBytes(rom); err != nil {
+		return err
+	}
+	chip8.PC = PROGRAM_START
+	return nil
+}
+
+// ROM returns a copy of the loaded program, as decoded if it came in a
+// container format
+func (chip8 *Chip8) ROM() []byte {
+	return append([]byte(nil), chip8.rom...)
+}
@@ -26,3 +26,89 @@ func TestNewChip8Initialization(t *testing.T) {
 		require.Equal(t, uint8(0), chip8.registers[nibble(i)])
 	}
 }
+
+func TestChip8_QuirksAndSpeed(t *testing.T) {
+	chip8 := NewChip8(true, false, 700)
+	require.Equal(t, Quirks{Shift: true, Jump: false}, chip8.Quirks())
+
+	chip8.SetQuirks(Quirks{Shift: false, Jump: true})
+	require.Equal(t, Quirks{Shift: false, Jump: true}, chip8.Quirks())
+	require.False(t, chip8.shift1)
+	require.True(t, chip8.bnnn1)
+
+	chip8.SetSpeed(1400)
+	require.Equal(t, 1400, chip8.Speed())
+}
+
+func TestChip8_SetSeed(t *testing.T) {
+	// C0FF: V0 = random byte
+	randoms := func(seed int64) []uint8 {
+		chip8 := NewChip8(false, false, 700)
+		chip8.SetSeed(seed)
+		var vals []uint8
+		for i := 0; i < 8; i++ {
+			chip8.ExecuteInstruction(0xC0FF)
+			vals = append(vals, chip8.registers[0])
+		}
+		return vals
+	}
+	require.Equal(t, randoms(42), randoms(42))
+	require.NotEqual(t, randoms(42), randoms(43))
+}
+
+func TestChip8_Stack(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	require.Empty(t, chip8.Stack())
+
+	// 2NNN pushes the address of the next instruction
+	chip8.PC = 0x202
+	chip8.ExecuteInstruction(0x2300)
+	require.Equal(t, []uint16{0x202}, chip8.Stack())
+	require.Equal(t, uint16(0x300), chip8.PC)
+
+	// The copy does not alias the emulator's stack
+	chip8.Stack()[0] = 0
+	chip8.ExecuteInstruction(0x00EE)
+	require.Equal(t, uint16(0x202), chip8.PC)
+	require.Empty(t, chip8.Stack())
+
+	// Returning with an empty stack does nothing
+	chip8.ExecuteInstruction(0x00EE)
+	require.Equal(t, uint16(0x202), chip8.PC)
+}
+
+func TestChip8_LoadBytes(t *testing.T) {
+	chip8 := NewChip8(false, false, 700)
+	require.NoError(t, chip8.LoadBytes([]byte{0x00, 0xE0}))
+	chip8.PC = 0x200
+	require.Equal(t, Opcode(0x00E0), chip8.Fetch())
+
+	require.NoError(t, chip8.LoadBytes(make([]byte, RAM-0x200)))
+	require.Error(t, chip8.LoadBytes(make([]byte, RAM-0x200+1)))
+}
+
+func TestNew(t *testing.T) {
+	chip8 := New(Options{})
+	require.Equal(t, DEFAULT_SPEED, chip8.Speed())
+	require.Equal(t, Quirks{}, chip8.Quirks())
+	require.Equal(t, PRESENT_LIVE, chip8.PresentMode())
+
+	chip8 = New(Options{Speed: 1000, Quirks: Quirks{Jump: true}, PresentMode: PRESENT_FADE, FadeFrames: 4})
+	require.Equal(t, 1000, chip8.Speed())
+	require.True(t, chip8.Quirks().Jump)
+	require.Equal(t, PRESENT_FADE, chip8.PresentMode())
+	require.Equal(t, 4, chip8.fadeFrames)
+}
+
+func TestChip8_LoadROM(t *testing.T) {
+	chip8 := New(Options{})
+	chip8.PC = 0x300
+	require.NoError(t, chip8.LoadROM([]byte{0x00, 0xE0}))
+	require.Equal(t, uint16(PROGRAM_START), chip8.PC)
+	require.Equal(t, Opcode(0x00E0), chip8.Fetch())
+
+	// A ROM that doesn't fit leaves PC alone
+	chip8.PC = 0x300
+	require.Error(t, chip8.LoadROM(make([]byte, RAM)))
+	require.Equal(t, uint16(0x300), chip8.PC)
+}
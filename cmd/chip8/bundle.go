@@ -0,0 +1,88 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/yuvrajchettri/chip-8-emulator/bundle"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+)
+
+const coverScale = 4 // The cover is a 256x128 screenshot
+
+// ------------------------------------------------
+// Bundles a ROM with the settings the ROM database, or the cartridge it
+// came in, recommends for it and a cover screenshot of it running
+// ------------------------------------------------
+func runBundle(args []string) error {
+	flags := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	title := flags.String("title", "", "title, the ROM database's or the file name if empty")
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the cartridge, the ROM database or 700")
+	palette := flags.String("palette", "", "palette preset or BACKGROUND,FOREGROUND hex colours, the ROM's colours or classic if empty")
+	frames := flags.Int("frames", 120, "number of 60 Hz frames to run before taking the cover screenshot")
+	out := flags.String("o", "", "output .png file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *out == "" {
+		return errors.New("expected -o OUT.png and a single ROM path")
+	}
+
+	emulator, entry, err := loadROMFile(flags.Arg(0), *speed)
+	if err != nil {
+		return err
+	}
+	colors, err := romPalette(*palette, emulator, entry)
+	if err != nil {
+		return err
+	}
+
+	// Rebundling, or bundling a cartridge, keeps what the database doesn't know
+	cart, _ := emulator.Cartridge()
+	settings := entry.Settings(cart)
+	quirks := emulator.Quirks()
+	b := bundle.Bundle{
+		Title:    settings.Title,
+		Authors:  entry.Authors,
+		Platform: entry.Platform.ID,
+		Speed:    emulator.Speed(),
+		Quirks:   &bundle.Quirks{Shift: quirks.Shift, Jump: quirks.Jump},
+		ROM:      emulator.ROM(),
+	}
+	for role, key := range settings.Keys {
+		if b.Keys == nil {
+			b.Keys = make(map[string]int, len(settings.Keys))
+		}
+		b.Keys[role] = int(key)
+	}
+	if *palette != "" || settings.Palette != "" {
+		// Left out if the ROM has no colours of its own, so players' choice applies
+		b.Palette = render.Hex(colors.Background) + "," + render.Hex(colors.Foreground)
+	}
+	if *title != "" {
+		b.Title = *title
+	}
+	if b.Title == "" {
+		b.Title = filepath.Base(flags.Arg(0))
+	}
+
+	if err := runFrames(host.NewRunner(emulator, &recordDisplay{}, nil, nil), *frames); err != nil {
+		return err
+	}
+	cover := render.NewImage(emulator.GetDisplay(), render.Options{Palette: colors, Scale: coverScale}).Paletted()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := bundle.Encode(file, b, cover); err != nil {
+		return err
+	}
+	return file.Close()
+}
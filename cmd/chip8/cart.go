@@ -0,0 +1,142 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/cart"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+)
+
+const labelScale = 2 // The label is a 128x64 screenshot
+
+// ------------------------------------------------
+// Packs a ROM into an Octo-style cartridge GIF labelled with a screenshot of
+// the ROM running, or unpacks the ROM and its settings from one
+// ------------------------------------------------
+func runCart(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "pack":
+			return runCartPack(args[1:])
+		case "unpack":
+			return runCartUnpack(args[1:])
+		}
+	}
+	return errors.New("expected pack or unpack")
+}
+
+func runCartPack(args []string) error {
+	flags := flag.NewFlagSet("cart pack", flag.ContinueOnError)
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the ROM database or 700")
+	palette := flags.String("palette", "", "palette preset or BACKGROUND,FOREGROUND hex colours, the ROM's colours or classic if empty")
+	frames := flags.Int("frames", 120, "number of 60 Hz frames to run before taking the label's screenshot")
+	source := flags.String("source", "", "Octo source file to include")
+	out := flags.String("o", "", "output .gif file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *out == "" {
+		return errors.New("expected -o OUT.gif and a single ROM path")
+	}
+
+	emulator, entry, err := loadROMFile(flags.Arg(0), *speed)
+	if err != nil {
+		return err
+	}
+	colors, err := romPalette(*palette, emulator, entry)
+	if err != nil {
+		return err
+	}
+
+	c := cart.Cartridge{
+		ROM:     emulator.ROM(),
+		Options: cart.NewOptions(emulator.Speed(), emulator.Quirks(), render.Hex(colors.Background), render.Hex(colors.Foreground)),
+	}
+	if *source != "" {
+		src, err := os.ReadFile(*source)
+		if err != nil {
+			return err
+		}
+		c.Source = string(src)
+	}
+
+	if err := runFrames(host.NewRunner(emulator, &recordDisplay{}, nil, nil), *frames); err != nil {
+		return err
+	}
+	label := render.NewImage(emulator.GetDisplay(), render.Options{Palette: colors, Scale: labelScale}).Paletted()
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := cart.Encode(file, c, label); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func runCartUnpack(args []string) error {
+	flags := flag.NewFlagSet("cart unpack", flag.ContinueOnError)
+	out := flags.String("o", "", "output ROM file, the cartridge's name with .ch8 if empty. Octo source is saved next to it as .8o")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected a single cartridge path")
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	c, err := cart.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	romPath := *out
+	if romPath == "" {
+		romPath = strings.TrimSuffix(flags.Arg(0), filepath.Ext(flags.Arg(0))) + ".ch8"
+	}
+	if len(c.ROM) > 0 {
+		if err := os.WriteFile(romPath, c.ROM, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("ROM: %s (%d bytes)\n", romPath, len(c.ROM))
+	}
+	if c.Source != "" {
+		srcPath := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".8o"
+		if err := os.WriteFile(srcPath, []byte(c.Source), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Octo source: %s\n", srcPath)
+	}
+
+	if len(c.ROM) == 0 && c.Source != "" {
+		// The settings are only used to run a ROM
+		return nil
+	}
+	settings, err := c.Chip8()
+	if err != nil {
+		return err
+	}
+	if settings.Speed != 0 {
+		fmt.Printf("Speed: %d Hz\n", settings.Speed)
+	}
+	if settings.Quirks != nil {
+		fmt.Printf("Quirks: shift %t, jump %t\n", settings.Quirks.Shift, settings.Quirks.Jump)
+	}
+	if settings.Palette != "" {
+		fmt.Printf("Palette: %s\n", settings.Palette)
+	}
+	return nil
+}
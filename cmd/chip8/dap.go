@@ -0,0 +1,94 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/dap"
+	"github.com/yuvrajchettri/chip-8-emulator/spectate"
+)
+
+// ------------------------------------------------
+// Runs a Debug Adapter Protocol server for editors, on stdin and stdout or
+// one session at a time on a TCP address. Launch requests name a ROM file
+// or a built-in ROM.
+// ------------------------------------------------
+func runDAP(args []string) error {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	listen := flags.String("listen", "", "serve sessions over TCP on this address instead of stdin and stdout, e.g. 127.0.0.1:4711")
+	spectateAddr := flags.String("spectate", "", "stream the debugged ROM to spectate.html viewers on this address, e.g. :8081")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("the ROM is given by the editor's launch request")
+	}
+
+	// The machine of the current session, the hub's Display reads it
+	var emulator *chip8.Chip8
+	var hub *spectate.Hub
+	opts := dap.Options{
+		Open: func(program string, speedHz int) (*chip8.Chip8, error) {
+			machine, entry, err := loadROMFile(program, speedHz)
+			if err != nil {
+				return nil, err
+			}
+			if hub != nil {
+				info, err := spectateInfo(program, machine, entry)
+				if err != nil {
+					return nil, err
+				}
+				hub.SetInfo(info)
+			}
+			emulator = machine
+			return machine, nil
+		},
+	}
+	if *spectateAddr != "" {
+		hub = spectate.NewHub(spectate.Info{})
+		stop, err := spectate.Serve(*spectateAddr, hub)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		opts.Display = hub.Display(discardDisplay{}, func() *chip8.Chip8 { return emulator })
+	}
+
+	if *listen == "" {
+		return dap.Serve(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, opts)
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Fprintf(os.Stderr, "chip8: debug adapter listening on %s\n", listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		if err := dap.Serve(conn, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "chip8: debug session: %v\n", err)
+		}
+		conn.Close()
+	}
+}
+
+// Debugged and profiled ROMs are shown to spectators at most
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/gdbserver"
+)
+
+// ------------------------------------------------
+// Serves a ROM to GDB as a remote target, one client at a time. The
+// machine keeps its state between clients, until one kills it.
+// ------------------------------------------------
+func runGDBServer(args []string) error {
+	flags := flag.NewFlagSet("gdbserver", flag.ContinueOnError)
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the cartridge, the ROM database or 700")
+	spectateAddr := flags.String("spectate", "", "stream the debugged ROM to spectate.html viewers on this address, e.g. :8081")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("expected an address to listen on and a ROM path")
+	}
+
+	emulator, entry, err := loadROMFile(flags.Arg(1), *speed)
+	if err != nil {
+		return err
+	}
+	var opts gdbserver.Options
+	if *spectateAddr != "" {
+		hub, stop, err := startSpectating(*spectateAddr, flags.Arg(1), emulator, entry)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		opts.Display = hub.Display(discardDisplay{}, func() *chip8.Chip8 { return emulator })
+	}
+
+	listener, err := net.Listen("tcp", flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Fprintf(os.Stderr, "chip8: debugging %s, connect with: target remote %s\n", flags.Arg(1), listener.Addr())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		if err := gdbserver.Serve(conn, emulator, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "chip8: gdb session: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+)
+
+// ------------------------------------------------
+// Lists the built-in ROMs with their recommended settings. The other
+// commands take their names in place of a ROM path.
+// ------------------------------------------------
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("list takes no arguments")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tTITLE\tSPEED\tQUIRKS\tDESCRIPTION")
+	for _, rom := range roms.List() {
+		speed := "-"
+		if rom.Speed != 0 {
+			speed = fmt.Sprintf("%d Hz", rom.Speed)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", rom.Name, rom.Size, rom.Title, speed, quirkNames(rom.Quirks.Shift, rom.Quirks.Jump), rom.Description)
+	}
+	return w.Flush()
+}
+
+func quirkNames(shift, jump bool) string {
+	switch {
+	case shift && jump:
+		return "shift,jump"
+	case shift:
+		return "shift"
+	case jump:
+		return "jump"
+	}
+	return "-"
+}
@@ -0,0 +1,57 @@
+//go:build !js && !wasm
+
+// Command chip8 runs the CHIP-8 emulator without SDL, in a terminal or
+// headlessly for scripted use. The SDL build lives in the repository root.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"bundle", "bundle [-title T] [-speed HZ] [-palette P] [-frames N] -o OUT.png ROM", runBundle},
+	{"cart", "cart pack [-speed HZ] [-palette P] [-frames N] [-source FILE.8o] -o OUT.gif ROM | cart unpack [-o OUT.ch8] CART.gif", runCart},
+	{"dap", "dap [-listen HOST:PORT] [-spectate ADDR]", runDAP},
+	{"gdbserver", "gdbserver [-speed HZ] [-spectate ADDR] HOST:PORT ROM", runGDBServer},
+	{"list", "list", runList},
+	{"profile", "profile [-frames N] [-speed HZ] [-top N] [-o OUT.pb.gz] ROM", runProfile},
+	{"record", "record [-frames N] [-speed HZ] [-scale N] [-palette P] -o OUT.gif|OUT.png ROM", runRecord},
+	{"serve", "serve [-addr HOST:PORT] [-speed HZ] [ROM...]", runServe},
+	{"tui", "tui [-speed HZ] [-present MODE] [-spectate ADDR] [-host ADDR | -join ADDR] [-delay N] ROM", runTUI},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "chip8 %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "chip8: unknown command %q\n", name)
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  chip8 %s\n", cmd.usage)
+	}
+}
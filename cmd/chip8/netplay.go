@@ -0,0 +1,54 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/netplay"
+)
+
+// ------------------------------------------------
+// Starts a two-player game: with hostAddr it waits for the other player to
+// connect, with joinAddr it connects to the host. Both players need the
+// same ROM, the joining player takes over the host's settings.
+// ------------------------------------------------
+func startNetplay(hostAddr, joinAddr string, delay int, emulator *chip8.Chip8) (*netplay.Session, error) {
+	opts := netplay.Options{InputDelay: delay}
+	switch {
+	case hostAddr != "" && joinAddr != "":
+		return nil, errors.New("-host and -join can't be used together")
+
+	case hostAddr != "":
+		listener, err := net.Listen("tcp", hostAddr)
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+		fmt.Fprintf(os.Stderr, "Waiting for the other player on %s...\n", listener.Addr())
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		session, err := netplay.Host(conn, emulator, opts)
+		if err != nil {
+			conn.Close()
+		}
+		return session, err
+
+	default:
+		conn, err := net.Dial("tcp", joinAddr)
+		if err != nil {
+			return nil, err
+		}
+		session, err := netplay.Join(conn, emulator, opts)
+		if err != nil {
+			conn.Close()
+		}
+		return session, err
+	}
+}
@@ -0,0 +1,55 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/profile"
+)
+
+// ------------------------------------------------
+// Runs a ROM headlessly for a number of 60 Hz frames with the profiler on,
+// prints its report and saves a pprof profile with -o
+// ------------------------------------------------
+func runProfile(args []string) error {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	frames := flags.Int("frames", 600, "number of 60 Hz frames to run")
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the cartridge, the ROM database or 700")
+	top := flags.Int("top", 20, "number of hot spots to list")
+	out := flags.String("o", "", "also write a pprof profile to this file, e.g. rom.pb.gz for go tool pprof")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected a single ROM path")
+	}
+
+	emulator, _, err := loadROMFile(flags.Arg(0), *speed)
+	if err != nil {
+		return err
+	}
+	profiler := profile.Start(emulator)
+	if err := runFrames(host.NewRunner(emulator, profiler.Display(discardDisplay{}), nil, nil), *frames); err != nil {
+		return err
+	}
+	profiler.Stop()
+
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := profiler.WritePprof(file); err != nil {
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+	return profiler.WriteReport(os.Stdout, *top)
+}
@@ -0,0 +1,144 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+)
+
+// ------------------------------------------------
+// Runs a ROM headlessly for a number of 60 Hz frames and saves the session
+// as an animated GIF, or the last frame as a PNG screenshot
+// ------------------------------------------------
+func runRecord(args []string) error {
+	flags := flag.NewFlagSet("record", flag.ContinueOnError)
+	frames := flags.Int("frames", 600, "number of 60 Hz frames to run")
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the cartridge, the ROM database or 700")
+	scale := flags.Int("scale", render.DefaultScale, "image pixels per CHIP-8 pixel")
+	palette := flags.String("palette", "", "palette preset or BACKGROUND,FOREGROUND hex colours, the ROM's colours or classic if empty")
+	out := flags.String("o", "", "output file, .gif records the session, .png saves the last frame")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *out == "" {
+		return errors.New("expected -o OUT and a single ROM path")
+	}
+
+	ext := strings.ToLower(filepath.Ext(*out))
+	if ext != ".gif" && ext != ".png" {
+		return errors.New("output must be a .gif or .png file")
+	}
+
+	emulator, entry, err := loadROMFile(flags.Arg(0), *speed)
+	if err != nil {
+		return err
+	}
+
+	colors, err := romPalette(*palette, emulator, entry)
+	if err != nil {
+		return err
+	}
+
+	opts := render.Options{Palette: colors, Scale: *scale}
+	display := &recordDisplay{gif: ext == ".gif", recorder: render.NewRecorder(opts)}
+	if err := runFrames(host.NewRunner(emulator, display, nil, nil), *frames); err != nil {
+		return err
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if ext == ".gif" {
+		err = display.recorder.Encode(file)
+	} else {
+		err = render.WritePNG(file, emulator.GetDisplay(), opts)
+	}
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Runs up to frames 60 Hz frames, stopping early if the ROM halts
+func runFrames(runner *host.Runner, frames int) error {
+	for frame := 0; frame < frames; frame++ {
+		if err := runner.Frame(); errors.Is(err, host.ErrHalted) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Headless host.Display, records every frame of a GIF
+type recordDisplay struct {
+	gif      bool
+	recorder *render.Recorder
+}
+
+func (d *recordDisplay) Present(frame [][]int, changed bool) error {
+	if d.gif {
+		d.recorder.AddFrame(frame)
+	}
+	return nil
+}
+
+// ------------------------------------------------
+// Loads a ROM or cartridge with the settings the ROM database or else the
+// cartridge recommends for it, see romdb.LoadUser and
+// romdb.Database.NewMachine. speedHz overrides their speed unless it is 0.
+// The entry is the zero Entry for ROMs the database doesn't know. Names
+// from chip8 list load the built-in ROMs unless a file has that path.
+// ------------------------------------------------
+func loadROMFile(path string, speedHz int) (*chip8.Chip8, romdb.Entry, error) {
+	romBytes, err := readROM(path)
+	if err != nil {
+		return nil, romdb.Entry{}, err
+	}
+
+	db, err := romdb.LoadUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chip8: ignoring ROM database overrides: %v\n", err)
+	}
+	emulator, entry, err := db.NewMachine(romBytes, chip8.Options{Speed: chip8.DEFAULT_SPEED})
+	if err != nil {
+		return nil, romdb.Entry{}, err
+	}
+	if speedHz != 0 {
+		emulator.SetSpeed(speedHz)
+	}
+	return emulator, entry, nil
+}
+
+// Reads a ROM file, or the built-in ROM of that name if there's no such file
+func readROM(path string) ([]byte, error) {
+	rom, err := os.ReadFile(path)
+	if _, builtIn := roms.Lookup(path); errors.Is(err, fs.ErrNotExist) && builtIn {
+		return roms.Load(path)
+	}
+	return rom, err
+}
+
+// The palette given with -palette, or else the ROM database's colours, the
+// cartridge's or classic
+func romPalette(spec string, emulator *chip8.Chip8, entry romdb.Entry) (render.Palette, error) {
+	cart, _ := emulator.Cartridge()
+	return render.ParsePalette(cmp.Or(spec, entry.Settings(cart).Palette, "classic"))
+}
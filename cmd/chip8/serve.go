@@ -0,0 +1,46 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/server"
+)
+
+// ------------------------------------------------
+// Serves the HTTP API of package server, with a machine for each ROM given
+// on the command line. Machines only run when a client asks them to.
+// ------------------------------------------------
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on, keep it local as the API has no authentication")
+	speed := flags.Int("speed", 0, "instructions per second of the ROMs given, 0 uses the cartridge, the ROM database or 700")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := romdb.LoadUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chip8: ignoring ROM database overrides: %v\n", err)
+	}
+	api := server.New(db)
+	for _, path := range flags.Args() {
+		rom, err := readROM(path)
+		if err != nil {
+			return err
+		}
+		id, err := api.Add(rom, *speed)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "chip8: machine %s runs %s\n", id, path)
+	}
+
+	fmt.Fprintf(os.Stderr, "chip8: serving on http://%s/machines\n", *addr)
+	return http.ListenAndServe(*addr, api)
+}
@@ -0,0 +1,44 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"cmp"
+	"path/filepath"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/spectate"
+)
+
+// ------------------------------------------------
+// Streams the session of the ROM at path to spectate.html viewers on
+// addr/spectate. Frames reach the hub through Hub.Display, stop closes the
+// connections.
+// ------------------------------------------------
+func startSpectating(addr, path string, emulator *chip8.Chip8, entry romdb.Entry) (*spectate.Hub, func(), error) {
+	info, err := spectateInfo(path, emulator, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	hub := spectate.NewHub(info)
+	stop, err := spectate.Serve(addr, hub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hub, stop, nil
+}
+
+// The title and colours of the ROM at path
+func spectateInfo(path string, emulator *chip8.Chip8, entry romdb.Entry) (spectate.Info, error) {
+	palette, err := romPalette("", emulator, entry)
+	if err != nil {
+		return spectate.Info{}, err
+	}
+	cart, _ := emulator.Cartridge()
+	return spectate.Info{
+		Title:   cmp.Or(entry.Settings(cart).Title, filepath.Base(path)),
+		Palette: render.Hex(palette.Background) + "," + render.Hex(palette.Foreground),
+	}, nil
+}
@@ -0,0 +1,173 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/netplay"
+	"github.com/yuvrajchettri/chip-8-emulator/spectate"
+	"github.com/yuvrajchettri/chip-8-emulator/tui"
+)
+
+// ------------------------------------------------
+// Runs a ROM in the terminal: the display is drawn with half blocks and the
+// keyboard is read in raw mode, so no SDL or window system is needed
+// ------------------------------------------------
+func runTUI(args []string) error {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	speed := flags.Int("speed", 0, "instructions per second, 0 uses the cartridge, the ROM database or 700")
+	present := flags.String("present", "blend", "anti-flicker mode: live, blend or fade")
+	spectateAddr := flags.String("spectate", "", "stream the session to spectate.html viewers on this address, e.g. :8081")
+	hostAddr := flags.String("host", "", "host a two-player game on this address, e.g. :7070")
+	joinAddr := flags.String("join", "", "join the two-player game hosted on this address")
+	delay := flags.Int("delay", netplay.DefaultInputDelay, "frames before keys apply in a two-player game, hides network latency, 0 for none")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("expected a single ROM path")
+	}
+
+	presentMode, err := chip8.ParsePresentMode(*present)
+	if err != nil {
+		return err
+	}
+
+	emulator, entry, err := loadROMFile(flags.Arg(0), *speed)
+	if err != nil {
+		return err
+	}
+	emulator.SetPresentMode(presentMode, 2)
+
+	var hub *spectate.Hub
+	if *spectateAddr != "" {
+		var stop func()
+		if hub, stop, err = startSpectating(*spectateAddr, flags.Arg(0), emulator, entry); err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return errors.New("stdin is not a terminal")
+	}
+
+	// Connect before the terminal is taken over, so progress and errors show
+	var session *netplay.Session
+	if *hostAddr != "" || *joinAddr != "" {
+		if session, err = startNetplay(*hostAddr, *joinAddr, *delay, emulator); err != nil {
+			return err
+		}
+		defer session.Close()
+	}
+
+	width, height, err := term.GetSize(stdin)
+	if err == nil && (width < chip8.DISPLAY_COLS || height < tui.Rows+tui.StatusRows) {
+		return fmt.Errorf("terminal must be at least %dx%d", chip8.DISPLAY_COLS, tui.Rows+tui.StatusRows)
+	}
+
+	oldState, err := term.MakeRaw(stdin)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(stdin, oldState)
+
+	screen := tui.NewScreen(os.Stdout)
+	if err := screen.Start(); err != nil {
+		return err
+	}
+	defer screen.Stop()
+
+	return tuiLoop(emulator, screen, readInput(os.Stdin), hub, session)
+}
+
+// Streams the session to the hub's spectators unless it is nil, and plays
+// the netplay session with the other player's keys unless that is nil
+func tuiLoop(emulator *chip8.Chip8, screen *tui.Screen, input <-chan []byte, hub *spectate.Hub, session *netplay.Session) error {
+	frontend := &tuiFrontend{emulator: emulator, screen: screen, keyboard: tui.NewKeyboard(), input: input}
+	var display host.Display = frontend
+	if hub != nil {
+		display = hub.Display(frontend, func() *chip8.Chip8 { return emulator })
+	}
+	var keys host.Input = frontend
+	if session != nil {
+		keys = session.Input(frontend)
+	}
+	runner := host.NewRunner(emulator, display, keys, frontend)
+	err := runner.Run(context.Background(), host.NewRealtimeClock(host.FrameRate))
+	if errors.Is(err, host.ErrQuit) || errors.Is(err, host.ErrHalted) || errors.Is(err, netplay.ErrPeerLeft) {
+		return nil
+	}
+	return err
+}
+
+// ------------------------------------------------
+// The terminal front-end: the host.Display, host.Input and host.AudioSink
+// the Runner drives
+// ------------------------------------------------
+type tuiFrontend struct {
+	emulator *chip8.Chip8
+	screen   *tui.Screen
+	keyboard *tui.Keyboard
+	input    <-chan []byte
+	beeping  bool
+}
+
+// Poll handles the input read since the last frame, Ctrl-C and EOF quit
+func (f *tuiFrontend) Poll() ([16]bool, error) {
+	now := time.Now()
+	for {
+		select {
+		case data, ok := <-f.input:
+			if !ok || bytes.IndexByte(data, tui.CtrlC) >= 0 {
+				return [16]bool{}, host.ErrQuit
+			}
+			f.keyboard.Feed(data, now)
+
+		default:
+			return f.keyboard.Keys(now), nil
+		}
+	}
+}
+
+// Half blocks have no shades, a pixel is drawn while it is lit at all
+func (f *tuiFrontend) Present(frame [][]int, changed bool) error {
+	return f.screen.Draw(frame, tui.Status(f.emulator))
+}
+
+// Rings the terminal bell once when the sound timer starts
+func (f *tuiFrontend) SetTone(on bool) {
+	if on && !f.beeping {
+		os.Stdout.WriteString("\a")
+	}
+	f.beeping = on
+}
+
+// Reads raw terminal input in the background, the channel is closed on EOF
+func readInput(file *os.File) <-chan []byte {
+	input := make(chan []byte)
+	go func() {
+		defer close(input)
+		buf := make([]byte, 64)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			input <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	return input
+}
@@ -0,0 +1,730 @@
+// Package dap is a Debug Adapter Protocol server, so editors such as VS
+// Code can debug CHIP-8 programs: launch a ROM, stop at breakpoints, step
+// through it and inspect its registers, timers, stack and memory.
+//
+// A session debugs one machine with a single thread. Breakpoints are set by
+// address with setInstructionBreakpoints, or by source line when the launch
+// request names a symbol map, see symbols.go. The launch arguments are:
+//
+//	program      the ROM, as understood by Options.Open
+//	speed        instructions per second, 0 for the recommended speed
+//	stopOnEntry  stop before the first instruction
+//	symbols      path of a symbol map
+//
+// Variables come in three scopes: Registers (V0-VF, I and PC), Timers and
+// Stack. I, PC and the return addresses have memory references for
+// readMemory.
+package dap
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+const (
+	threadID = 1
+
+	// variablesReference of the scopes
+	registersRef = 1
+	timersRef    = 2
+	stackRef     = 3
+)
+
+// Options configure a debug session
+type Options struct {
+	// Open loads the program of a launch request, speedHz overrides the
+	// recommended speed unless it is 0
+	Open func(program string, speedHz int) (*chip8.Chip8, error)
+
+	Display host.Display // Shows the frames run, may be nil
+	Clock   host.Clock   // Paces the frames, real time if nil
+}
+
+type session struct {
+	conn     *conn
+	opts     Options
+	handlers map[string]func(args json.RawMessage) (any, error)
+	wake     chan struct{} // Signalled when the machine is resumed
+
+	mu          sync.Mutex
+	machine     *chip8.Chip8 // nil until launched
+	runner      *host.Runner
+	symbols     *symbols // nil without a symbol map
+	stopOnEntry bool
+	running     bool
+	resuming    bool                 // The instruction at PC runs even if it has a breakpoint
+	until       func(pc uint16) bool // Ends a step, nil when continuing
+	done        bool                 // The client disconnected
+
+	sourceBreakpoints      map[string]map[uint16]int // Breakpoint IDs by file and address
+	instructionBreakpoints map[uint16]int
+	nextID                 int
+
+	handling bool    // Events wait for the response to the request being handled
+	pending  []event // Those events
+}
+
+// ------------------------------------------------
+// Serve runs a debug session over rw until the client disconnects or the
+// connection breaks. The machine runs at its speed between requests, on a
+// goroutine that is gone when Serve returns.
+// ------------------------------------------------
+func Serve(rw io.ReadWriter, opts Options) error {
+	if opts.Display == nil {
+		opts.Display = discardDisplay{}
+	}
+	if opts.Clock == nil {
+		opts.Clock = host.NewRealtimeClock(host.FrameRate)
+	}
+	s := &session{
+		conn:                   newConn(rw),
+		opts:                   opts,
+		wake:                   make(chan struct{}, 1),
+		sourceBreakpoints:      make(map[string]map[uint16]int),
+		instructionBreakpoints: make(map[uint16]int),
+	}
+	s.handlers = map[string]func(json.RawMessage) (any, error){
+		"initialize":                s.initialize,
+		"launch":                    s.launch,
+		"setBreakpoints":            s.setBreakpoints,
+		"setInstructionBreakpoints": s.setInstructionBreakpoints,
+		"setExceptionBreakpoints":   s.setExceptionBreakpoints,
+		"configurationDone":         s.configurationDone,
+		"threads":                   s.threads,
+		"stackTrace":                s.stackTrace,
+		"scopes":                    s.scopes,
+		"variables":                 s.variables,
+		"readMemory":                s.readMemory,
+		"continue":                  s.continueRequest,
+		"next":                      s.stepper(false, false),
+		"stepIn":                    s.stepper(true, false),
+		"stepOut":                   s.stepper(false, true),
+		"pause":                     s.pause,
+		"disconnect":                s.disconnect,
+		"terminate":                 s.terminate,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	for {
+		req, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if done, err := s.handle(req); done || err != nil {
+			return err
+		}
+	}
+}
+
+// Handles a request and sends its response, then the events it caused.
+// Returns true once the client has disconnected.
+func (s *session) handle(req request) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handler, ok := s.handlers[req.Command]
+	if !ok {
+		return false, s.conn.respond(req, nil, fmt.Errorf("%s is not supported", req.Command))
+	}
+	s.handling = true
+	body, err := handler(req.Arguments)
+	s.handling = false
+	if err := s.conn.respond(req, body, err); err != nil {
+		return false, err
+	}
+	for _, e := range s.pending {
+		if err := s.conn.event(e.Event, e.Body); err != nil {
+			return false, err
+		}
+	}
+	s.pending = nil
+	return s.done, nil
+}
+
+// Sends an event, after the response if a request is being handled
+func (s *session) emit(name string, body any) {
+	if s.handling {
+		s.pending = append(s.pending, event{Event: name, Body: body})
+		return
+	}
+	// A broken connection ends Serve's read
+	s.conn.event(name, body)
+}
+
+// ------------------------------------------------
+// Running.
+// The machine runs frames with a host.Runner while it isn't stopped. The
+// Fetch hook stops it at breakpoints and at the end of steps, pausing the
+// Runner so that it skips the rest of the frame, timers included.
+// ------------------------------------------------
+
+func (s *session) run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		running := s.running
+		s.mu.Unlock()
+		if !running {
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := s.opts.Clock.Tick(ctx); err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.running {
+			if err := s.frame(); err != nil {
+				s.stop("exception", err.Error(), nil)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Runs a frame, an instruction that panics fails it
+func (s *session) frame() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return s.runner.Frame()
+}
+
+// Runs the instruction at PC alone, whatever it is
+func (s *session) step() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	// Fetch reads two bytes at PC
+	if s.machine.ProgramCounter() > chip8.RAM-2 {
+		return host.ErrHalted
+	}
+	s.resuming = true
+	s.machine.Step()
+	return nil
+}
+
+func (s *session) fetch(pc uint16, op chip8.Opcode) bool {
+	if s.resuming {
+		s.resuming = false
+		return true
+	}
+	return s.running && !s.stopAt(pc)
+}
+
+// Stops the machine if there is a breakpoint at pc or the step ends there
+func (s *session) stopAt(pc uint16) bool {
+	if hits := s.breakpointsAt(pc); len(hits) > 0 {
+		s.stop("breakpoint", "", hits)
+		return true
+	}
+	if s.until != nil && s.until(pc) {
+		s.stop("step", "", nil)
+		return true
+	}
+	return false
+}
+
+// Runs the machine until a breakpoint, or until the step ends if until isn't
+// nil. skipBreakpoint runs the instruction at PC first whatever it is.
+func (s *session) resume(until func(pc uint16) bool, skipBreakpoint bool) {
+	s.running = true
+	s.resuming = skipBreakpoint
+	s.until = until
+	s.runner.SetPaused(false)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+type stoppedEvent struct {
+	Reason            string `json:"reason"`
+	Text              string `json:"text,omitempty"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+	HitBreakpointIDs  []int  `json:"hitBreakpointIds,omitempty"`
+}
+
+func (s *session) stop(reason, text string, hits []int) {
+	s.running = false
+	s.resuming = false
+	s.until = nil
+	s.runner.SetPaused(true)
+	s.emit("stopped", stoppedEvent{Reason: reason, Text: text, ThreadID: threadID, AllThreadsStopped: true, HitBreakpointIDs: hits})
+}
+
+func (s *session) breakpointsAt(pc uint16) []int {
+	var ids []int
+	if id, ok := s.instructionBreakpoints[pc]; ok {
+		ids = append(ids, id)
+	}
+	for _, breakpoints := range s.sourceBreakpoints {
+		if id, ok := breakpoints[pc]; ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s *session) launched() error {
+	if s.machine == nil {
+		return errors.New("no program has been launched")
+	}
+	return nil
+}
+
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
+
+// ------------------------------------------------
+// Requests
+// ------------------------------------------------
+
+type capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsInstructionBreakpoints   bool `json:"supportsInstructionBreakpoints"`
+	SupportsReadMemoryRequest        bool `json:"supportsReadMemoryRequest"`
+	SupportsSteppingGranularity      bool `json:"supportsSteppingGranularity"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+func (s *session) initialize(args json.RawMessage) (any, error) {
+	return capabilities{true, true, true, true, true}, nil
+}
+
+type launchArguments struct {
+	Program     string `json:"program"`
+	Speed       int    `json:"speed"`
+	StopOnEntry bool   `json:"stopOnEntry"`
+	Symbols     string `json:"symbols"`
+}
+
+// Loads the program, it starts running once the client is done configuring
+func (s *session) launch(args json.RawMessage) (any, error) {
+	var launch launchArguments
+	if err := unmarshal(args, &launch); err != nil {
+		return nil, err
+	}
+	if s.machine != nil {
+		return nil, errors.New("a program has already been launched")
+	}
+	if launch.Program == "" {
+		return nil, errors.New("the launch configuration has no program")
+	}
+
+	machine, err := s.opts.Open(launch.Program, launch.Speed)
+	if err != nil {
+		return nil, err
+	}
+	if launch.Symbols != "" {
+		if s.symbols, err = loadSymbols(launch.Symbols); err != nil {
+			return nil, err
+		}
+	}
+	machine.SetHooks(chip8.Hooks{Fetch: s.fetch})
+	s.machine = machine
+	s.runner = host.NewRunner(machine, s.opts.Display, nil, nil)
+	s.stopOnEntry = launch.StopOnEntry
+	s.emit("initialized", nil)
+	return nil, nil
+}
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+type breakpoint struct {
+	ID                   int    `json:"id,omitempty"`
+	Verified             bool   `json:"verified"`
+	Message              string `json:"message,omitempty"`
+	Line                 int    `json:"line,omitempty"`
+	InstructionReference string `json:"instructionReference,omitempty"`
+}
+
+type breakpointsBody struct {
+	Breakpoints []breakpoint `json:"breakpoints"`
+}
+
+// Replaces the breakpoints of a source file
+func (s *session) setBreakpoints(args json.RawMessage) (any, error) {
+	var set struct {
+		Source      source `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := unmarshal(args, &set); err != nil {
+		return nil, err
+	}
+
+	breakpoints := make(map[uint16]int)
+	body := breakpointsBody{Breakpoints: []breakpoint{}}
+	for _, requested := range set.Breakpoints {
+		if s.symbols == nil {
+			body.Breakpoints = append(body.Breakpoints, breakpoint{Message: "no symbol map, launch with \"symbols\" to set breakpoints by line"})
+			continue
+		}
+		addr, line, ok := s.symbols.address(set.Source.Path, requested.Line)
+		if !ok {
+			body.Breakpoints = append(body.Breakpoints, breakpoint{Message: "no code at or after this line"})
+			continue
+		}
+		id, ok := breakpoints[addr]
+		if !ok {
+			s.nextID++
+			id = s.nextID
+			breakpoints[addr] = id
+		}
+		body.Breakpoints = append(body.Breakpoints, breakpoint{ID: id, Verified: true, Line: line, InstructionReference: formatAddress(addr)})
+	}
+	s.sourceBreakpoints[set.Source.Path] = breakpoints
+	return body, nil
+}
+
+// Replaces the breakpoints set by address
+func (s *session) setInstructionBreakpoints(args json.RawMessage) (any, error) {
+	var set struct {
+		Breakpoints []struct {
+			InstructionReference string `json:"instructionReference"`
+			Offset               int    `json:"offset"`
+		} `json:"breakpoints"`
+	}
+	if err := unmarshal(args, &set); err != nil {
+		return nil, err
+	}
+
+	s.instructionBreakpoints = make(map[uint16]int)
+	body := breakpointsBody{Breakpoints: []breakpoint{}}
+	for _, requested := range set.Breakpoints {
+		addr, err := parseAddress(requested.InstructionReference, requested.Offset)
+		if err != nil || addr < 0 || addr > chip8.RAM-2 {
+			body.Breakpoints = append(body.Breakpoints, breakpoint{Message: "not an address in memory"})
+			continue
+		}
+		id, ok := s.instructionBreakpoints[uint16(addr)]
+		if !ok {
+			s.nextID++
+			id = s.nextID
+			s.instructionBreakpoints[uint16(addr)] = id
+		}
+		body.Breakpoints = append(body.Breakpoints, breakpoint{ID: id, Verified: true, InstructionReference: formatAddress(uint16(addr))})
+	}
+	return body, nil
+}
+
+// CHIP-8 has no exceptions, clients send this anyway
+func (s *session) setExceptionBreakpoints(args json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *session) configurationDone(args json.RawMessage) (any, error) {
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+	if s.stopOnEntry {
+		s.stop("entry", "", nil)
+	} else {
+		s.resume(nil, false)
+	}
+	return nil, nil
+}
+
+type thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *session) threads(args json.RawMessage) (any, error) {
+	return map[string][]thread{"threads": {{threadID, "CHIP-8"}}}, nil
+}
+
+type stackFrame struct {
+	ID                          int     `json:"id"`
+	Name                        string  `json:"name"`
+	Source                      *source `json:"source,omitempty"`
+	Line                        int     `json:"line"`
+	Column                      int     `json:"column"`
+	InstructionPointerReference string  `json:"instructionPointerReference"`
+}
+
+// ------------------------------------------------
+// The frames are PC and the calls on the stack, innermost first. A call's
+// frame points at the 2NNN below its return address.
+// ------------------------------------------------
+func (s *session) stackTrace(args json.RawMessage) (any, error) {
+	var trace struct {
+		StartFrame int `json:"startFrame"`
+		Levels     int `json:"levels"`
+	}
+	if err := unmarshal(args, &trace); err != nil {
+		return nil, err
+	}
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+
+	addrs := []uint16{s.machine.ProgramCounter()}
+	stack := s.machine.Stack()
+	for i := len(stack) - 1; i >= 0; i-- {
+		addrs = append(addrs, stack[i]-2)
+	}
+
+	frames := []stackFrame{}
+	for id, addr := range addrs {
+		frame := stackFrame{ID: id, Name: formatAddress(addr), InstructionPointerReference: formatAddress(addr)}
+		if s.symbols != nil {
+			if name, ok := s.symbols.label(addr); ok {
+				frame.Name = name
+			}
+			if loc, ok := s.symbols.location(addr); ok {
+				frame.Source = &source{Path: loc.file}
+				frame.Line, frame.Column = loc.line, 1
+			}
+		}
+		frames = append(frames, frame)
+	}
+
+	start := min(max(trace.StartFrame, 0), len(frames))
+	end := len(frames)
+	if trace.Levels > 0 {
+		end = min(start+trace.Levels, end)
+	}
+	return map[string]any{"stackFrames": frames[start:end], "totalFrames": len(frames)}, nil
+}
+
+type scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+// Every frame sees the same machine
+func (s *session) scopes(args json.RawMessage) (any, error) {
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+	return map[string][]scope{"scopes": {
+		{Name: "Registers", PresentationHint: "registers", VariablesReference: registersRef},
+		{Name: "Timers", VariablesReference: timersRef},
+		{Name: "Stack", VariablesReference: stackRef},
+	}}, nil
+}
+
+type variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	VariablesReference int    `json:"variablesReference"`
+	MemoryReference    string `json:"memoryReference,omitempty"`
+}
+
+func (s *session) variables(args json.RawMessage) (any, error) {
+	var scope struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := unmarshal(args, &scope); err != nil {
+		return nil, err
+	}
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+
+	state := s.machine.State()
+	variables := []variable{}
+	switch scope.VariablesReference {
+	case registersRef:
+		for x, v := range state.V {
+			variables = append(variables, variable{Name: fmt.Sprintf("V%X", x), Value: fmt.Sprintf("0x%02X", v)})
+		}
+		variables = append(variables,
+			variable{Name: "I", Value: formatAddress(state.I), MemoryReference: formatAddress(state.I)},
+			variable{Name: "PC", Value: formatAddress(state.PC), MemoryReference: formatAddress(state.PC)},
+		)
+	case timersRef:
+		variables = append(variables,
+			variable{Name: "Delay", Value: strconv.Itoa(int(state.DelayTimer))},
+			variable{Name: "Sound", Value: strconv.Itoa(int(state.SoundTimer))},
+		)
+	case stackRef:
+		for depth, addr := range state.Stack {
+			variables = append(variables, variable{Name: strconv.Itoa(depth), Value: formatAddress(addr), MemoryReference: formatAddress(addr)})
+		}
+	default:
+		return nil, fmt.Errorf("unknown variablesReference %d", scope.VariablesReference)
+	}
+	return map[string][]variable{"variables": variables}, nil
+}
+
+type readMemoryBody struct {
+	Address         string `json:"address"`
+	Data            string `json:"data,omitempty"` // Base64
+	UnreadableBytes int    `json:"unreadableBytes,omitempty"`
+}
+
+// Bytes past the end of memory are unreadable
+func (s *session) readMemory(args json.RawMessage) (any, error) {
+	var read struct {
+		MemoryReference string `json:"memoryReference"`
+		Offset          int    `json:"offset"`
+		Count           int    `json:"count"`
+	}
+	if err := unmarshal(args, &read); err != nil {
+		return nil, err
+	}
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+	addr, err := parseAddress(read.MemoryReference, read.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if addr < 0 || addr >= chip8.RAM || read.Count < 0 {
+		return readMemoryBody{Address: fmt.Sprintf("0x%X", addr), UnreadableBytes: max(read.Count, 0)}, nil
+	}
+
+	memory := s.machine.State().Memory
+	end := min(addr+read.Count, chip8.RAM)
+	return readMemoryBody{
+		Address:         formatAddress(uint16(addr)),
+		Data:            base64.StdEncoding.EncodeToString(memory[addr:end]),
+		UnreadableBytes: read.Count - (end - addr),
+	}, nil
+}
+
+func (s *session) continueRequest(args json.RawMessage) (any, error) {
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+	s.resume(nil, true)
+	return map[string]bool{"allThreadsContinued": true}, nil
+}
+
+// ------------------------------------------------
+// Steps run an instruction, or with a symbol map a source line unless the
+// granularity is "instruction". Stepping over runs calls to their return,
+// stepping out runs until the current call returns. The first instruction
+// runs on its own, without ticking the timers, so a single step needs no
+// frame.
+// ------------------------------------------------
+func (s *session) stepper(into, out bool) func(json.RawMessage) (any, error) {
+	return func(args json.RawMessage) (any, error) {
+		var step struct {
+			Granularity string `json:"granularity"`
+		}
+		if err := unmarshal(args, &step); err != nil {
+			return nil, err
+		}
+		if err := s.launched(); err != nil {
+			return nil, err
+		}
+
+		depth := len(s.machine.Stack())
+		var start location
+		byLine := false
+		if s.symbols != nil && step.Granularity != "instruction" && !out {
+			start, byLine = s.symbols.location(s.machine.ProgramCounter())
+		}
+		until := func(pc uint16) bool {
+			switch current := len(s.machine.Stack()); {
+			case out && current >= depth:
+				return false
+			case !into && current > depth:
+				return false
+			}
+			if !byLine {
+				return true
+			}
+			loc, ok := s.symbols.location(pc)
+			return ok && loc != start
+		}
+
+		if err := s.step(); err != nil {
+			s.stop("exception", err.Error(), nil)
+			return nil, nil
+		}
+		s.resume(until, false)
+		s.stopAt(s.machine.ProgramCounter())
+		return nil, nil
+	}
+}
+
+func (s *session) pause(args json.RawMessage) (any, error) {
+	if err := s.launched(); err != nil {
+		return nil, err
+	}
+	if s.running {
+		s.stop("pause", "", nil)
+	}
+	return nil, nil
+}
+
+func (s *session) disconnect(args json.RawMessage) (any, error) {
+	s.done = true
+	return nil, nil
+}
+
+func (s *session) terminate(args json.RawMessage) (any, error) {
+	s.done = true
+	s.emit("terminated", nil)
+	return nil, nil
+}
+
+// Arguments are optional for most requests
+func unmarshal(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("bad arguments: %w", err)
+	}
+	return nil
+}
+
+// Memory and instruction references are addresses such as "0x200"
+func parseAddress(ref string, offset int) (int, error) {
+	addr, err := strconv.ParseInt(ref, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad memory reference %q", ref)
+	}
+	return int(addr) + offset, nil
+}
+
+func formatAddress(addr uint16) string {
+	return fmt.Sprintf("0x%03X", addr)
+}
@@ -0,0 +1,363 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Calls a subroutine and then loops forever:
+//
+//	0x200: 6005  V0 = 5          main.8o:1  main
+//	0x202: 2208  call 0x208      main.8o:2
+//	0x204: 7001  V0 += 1         main.8o:3
+//	0x206: 1206  jump to 0x206   main.8o:4
+//	0x208: 6107  V1 = 7          main.8o:7  sub
+//	0x20A: 00EE  return          main.8o:8
+var callROM = []byte{0x60, 0x05, 0x22, 0x08, 0x70, 0x01, 0x12, 0x06, 0x61, 0x07, 0x00, 0xEE}
+
+const callSymbols = `# main.8o
+0x200 main.8o:1 main
+0x202 main.8o:2
+0x204 main.8o:3
+0x206 main.8o:4
+0x208 main.8o:7 sub
+0x20A main.8o:8
+`
+
+// A scripted DAP client
+type client struct {
+	t        *testing.T
+	conn     net.Conn
+	seq      int
+	messages chan map[string]any
+	served   chan error
+}
+
+func start(t *testing.T, opts Options) *client {
+	t.Helper()
+	server, conn := net.Pipe()
+	c := &client{t: t, conn: conn, messages: make(chan map[string]any, 16), served: make(chan error, 1)}
+	go func() {
+		c.served <- Serve(server, opts)
+		server.Close()
+	}()
+	go func() {
+		defer close(c.messages)
+		r := textproto.NewReader(bufio.NewReader(conn))
+		for {
+			header, err := r.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			length, _ := strconv.Atoi(header.Get("Content-Length"))
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r.R, data); err != nil {
+				return
+			}
+			var msg map[string]any
+			if json.Unmarshal(data, &msg) == nil {
+				c.messages <- msg
+			}
+		}
+	}()
+	t.Cleanup(func() { conn.Close() })
+	return c
+}
+
+func (c *client) next() map[string]any {
+	c.t.Helper()
+	select {
+	case msg, ok := <-c.messages:
+		require.True(c.t, ok, "the server hung up")
+		return msg
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("no message from the server")
+		return nil
+	}
+}
+
+// Sends a request and returns the body of its response
+func (c *client) request(command string, args any) map[string]any {
+	c.t.Helper()
+	msg := c.send(command, args)
+	require.Equal(c.t, true, msg["success"], "%s: %v", command, msg["message"])
+	body, _ := msg["body"].(map[string]any)
+	return body
+}
+
+// Sends a request and returns its response
+func (c *client) send(command string, args any) map[string]any {
+	c.t.Helper()
+	c.seq++
+	data, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	require.NoError(c.t, err)
+
+	msg := c.next()
+	require.Equal(c.t, "response", msg["type"])
+	require.Equal(c.t, command, msg["command"])
+	require.Equal(c.t, float64(c.seq), msg["request_seq"])
+	return msg
+}
+
+func (c *client) expectEvent(name string) map[string]any {
+	c.t.Helper()
+	msg := c.next()
+	require.Equal(c.t, "event", msg["type"])
+	require.Equal(c.t, name, msg["event"])
+	body, _ := msg["body"].(map[string]any)
+	return body
+}
+
+// Waits until the machine stops and returns why and where
+func (c *client) expectStop() (string, string) {
+	c.t.Helper()
+	stopped := c.expectEvent("stopped")
+	frames := c.request("stackTrace", map[string]any{"threadId": threadID})["stackFrames"].([]any)
+	return stopped["reason"].(string), frames[0].(map[string]any)["instructionPointerReference"].(string)
+}
+
+func (c *client) variables(ref int) map[string]string {
+	c.t.Helper()
+	values := make(map[string]string)
+	for _, v := range c.request("variables", map[string]any{"variablesReference": ref})["variables"].([]any) {
+		variable := v.(map[string]any)
+		values[variable["name"].(string)] = variable["value"].(string)
+	}
+	return values
+}
+
+func openROM(rom []byte) func(string, int) (*chip8.Chip8, error) {
+	return func(program string, speedHz int) (*chip8.Chip8, error) {
+		if program != "test" {
+			return nil, errors.New("no such ROM")
+		}
+		return host.NewMachine(rom, chip8.Options{Speed: speedHz})
+	}
+}
+
+func TestServe_Session(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	dir := t.TempDir()
+	symbolsPath := filepath.Join(dir, "main.map")
+	require.NoError(t, os.WriteFile(symbolsPath, []byte(callSymbols), 0o644))
+	source := filepath.Join(dir, "main.8o")
+
+	c := start(t, Options{Open: openROM(callROM), Clock: host.Unthrottled{}})
+	capabilities := c.request("initialize", map[string]any{"adapterID": "chip8"})
+	require.Equal(t, true, capabilities["supportsReadMemoryRequest"])
+
+	c.request("launch", map[string]any{"program": "test", "stopOnEntry": true, "symbols": symbolsPath})
+	c.expectEvent("initialized")
+
+	// Line 5 has no code, the breakpoint moves to line 7
+	set := c.request("setBreakpoints", map[string]any{"source": map[string]any{"path": source}, "breakpoints": []any{map[string]any{"line": 5}}})
+	breakpoint := set["breakpoints"].([]any)[0].(map[string]any)
+	require.Equal(t, true, breakpoint["verified"])
+	require.Equal(t, float64(7), breakpoint["line"])
+	require.Equal(t, "0x208", breakpoint["instructionReference"])
+	set = c.request("setInstructionBreakpoints", map[string]any{"breakpoints": []any{map[string]any{"instructionReference": "0x204"}}})
+	require.Equal(t, true, set["breakpoints"].([]any)[0].(map[string]any)["verified"])
+
+	c.request("configurationDone", nil)
+	reason, pc := c.expectStop()
+	require.Equal(t, "entry", reason)
+	require.Equal(t, "0x200", pc)
+
+	// Runs into the subroutine
+	c.request("continue", map[string]any{"threadId": threadID})
+	stopped := c.expectEvent("stopped")
+	require.Equal(t, "breakpoint", stopped["reason"])
+	require.Equal(t, []any{breakpoint["id"]}, stopped["hitBreakpointIds"])
+
+	trace := c.request("stackTrace", map[string]any{"threadId": threadID})
+	require.Equal(t, float64(2), trace["totalFrames"])
+	frames := trace["stackFrames"].([]any)
+	require.Equal(t, "sub", frames[0].(map[string]any)["name"])
+	require.Equal(t, float64(7), frames[0].(map[string]any)["line"])
+	require.Equal(t, source, frames[0].(map[string]any)["source"].(map[string]any)["path"])
+	caller := frames[1].(map[string]any)
+	require.Equal(t, "main", caller["name"])
+	require.Equal(t, "0x202", caller["instructionPointerReference"])
+	require.Equal(t, float64(2), caller["line"])
+
+	scopes := c.request("scopes", map[string]any{"frameId": 0})["scopes"].([]any)
+	require.Len(t, scopes, 3)
+	registers := c.variables(registersRef)
+	require.Equal(t, "0x05", registers["V0"])
+	require.Equal(t, "0x208", registers["PC"])
+	require.Equal(t, map[string]string{"0": "0x204"}, c.variables(stackRef))
+	require.Equal(t, map[string]string{"Delay": "0", "Sound": "0"}, c.variables(timersRef))
+
+	// Steps a line, then out of the subroutine onto the other breakpoint
+	c.request("next", map[string]any{"threadId": threadID})
+	reason, pc = c.expectStop()
+	require.Equal(t, "step", reason)
+	require.Equal(t, "0x20A", pc)
+	require.Equal(t, "0x07", c.variables(registersRef)["V1"])
+	c.request("stepOut", map[string]any{"threadId": threadID})
+	reason, pc = c.expectStop()
+	require.Equal(t, "breakpoint", reason)
+	require.Equal(t, "0x204", pc)
+
+	// The jump loops forever until paused
+	c.request("stepIn", map[string]any{"threadId": threadID, "granularity": "instruction"})
+	_, pc = c.expectStop()
+	require.Equal(t, "0x206", pc)
+	c.request("continue", map[string]any{"threadId": threadID})
+	c.request("pause", map[string]any{"threadId": threadID})
+	reason, pc = c.expectStop()
+	require.Equal(t, "pause", reason)
+	require.Equal(t, "0x206", pc)
+	require.Equal(t, "0x06", c.variables(registersRef)["V0"])
+
+	memory := c.request("readMemory", map[string]any{"memoryReference": "0x200", "offset": 2, "count": 4})
+	require.Equal(t, "0x202", memory["address"])
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x22, 0x08, 0x70, 0x01}), memory["data"])
+	memory = c.request("readMemory", map[string]any{"memoryReference": "0xFFE", "count": 4})
+	require.Equal(t, float64(2), memory["unreadableBytes"])
+
+	c.request("disconnect", nil)
+	require.NoError(t, <-c.served)
+}
+
+func TestServe_NoSymbols(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	c := start(t, Options{Open: openROM(callROM), Clock: host.Unthrottled{}})
+	c.request("initialize", nil)
+
+	// Requests that need a machine fail until one is launched
+	resp := c.send("stackTrace", map[string]any{"threadId": threadID})
+	require.Equal(t, false, resp["success"])
+	require.Equal(t, "no program has been launched", resp["message"])
+	resp = c.send("launch", map[string]any{"program": "other"})
+	require.Equal(t, "no such ROM", resp["message"])
+	resp = c.send("evaluate", map[string]any{"expression": "V0"})
+	require.Equal(t, "evaluate is not supported", resp["message"])
+
+	c.request("launch", map[string]any{"program": "test"})
+	c.expectEvent("initialized")
+	set := c.request("setBreakpoints", map[string]any{"source": map[string]any{"path": "main.8o"}, "breakpoints": []any{map[string]any{"line": 1}}})
+	require.Equal(t, false, set["breakpoints"].([]any)[0].(map[string]any)["verified"])
+
+	// Breakpoints by address still work, including one at the first instruction
+	c.request("setInstructionBreakpoints", map[string]any{"breakpoints": []any{map[string]any{"instructionReference": "0x200"}}})
+	c.request("configurationDone", nil)
+	reason, pc := c.expectStop()
+	require.Equal(t, "breakpoint", reason)
+	require.Equal(t, "0x200", pc)
+
+	// Without symbols, steps are instructions
+	c.request("stepIn", map[string]any{"threadId": threadID})
+	_, pc = c.expectStop()
+	require.Equal(t, "0x202", pc)
+	c.request("stepIn", map[string]any{"threadId": threadID})
+	_, pc = c.expectStop()
+	require.Equal(t, "0x208", pc)
+
+	c.request("terminate", nil)
+	c.expectEvent("terminated")
+	require.NoError(t, <-c.served)
+}
+
+func TestServe_Halted(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// Jumps to the last instruction, whose fetch would read past memory
+	c := start(t, Options{Open: openROM([]byte{0x1F, 0xFE}), Clock: host.Unthrottled{}})
+	c.request("launch", map[string]any{"program": "test", "speed": 600})
+	c.expectEvent("initialized")
+	c.request("configurationDone", nil)
+	stopped := c.expectEvent("stopped")
+	require.Equal(t, "exception", stopped["reason"])
+	require.Equal(t, host.ErrHalted.Error(), stopped["text"])
+
+	// The client hanging up ends the session too
+	c.conn.Close()
+	require.NoError(t, <-c.served)
+}
+
+func TestServe_Exception(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// Sets the delay timer, then draws sprites read past memory:
+	//
+	//	0x200: 6005  V0 = 5
+	//	0x202: F015  DT = V0
+	//	0x204: AFFF  I = 0xFFF
+	//	0x206: D015  draw
+	//	0x208: D015  draw
+	rom := []byte{0x60, 0x05, 0xF0, 0x15, 0xAF, 0xFF, 0xD0, 0x15, 0xD0, 0x15}
+	c := start(t, Options{Open: openROM(rom), Clock: host.Unthrottled{}})
+	c.request("launch", map[string]any{"program": "test", "stopOnEntry": true})
+	c.expectEvent("initialized")
+	c.request("configurationDone", nil)
+	c.expectStop()
+
+	// Steps leave the timers alone
+	c.request("stepIn", map[string]any{"threadId": threadID})
+	c.expectStop()
+	c.request("stepIn", map[string]any{"threadId": threadID})
+	_, pc := c.expectStop()
+	require.Equal(t, "0x204", pc)
+	require.Equal(t, "5", c.variables(timersRef)["Delay"])
+
+	// Instructions that panic stop the machine, running or stepping
+	c.request("continue", map[string]any{"threadId": threadID})
+	stopped := c.expectEvent("stopped")
+	require.Equal(t, "exception", stopped["reason"])
+	require.Contains(t, stopped["text"], "out of range")
+	c.request("stepIn", map[string]any{"threadId": threadID})
+	stopped = c.expectEvent("stopped")
+	require.Equal(t, "exception", stopped["reason"])
+
+	c.request("disconnect", nil)
+	require.NoError(t, <-c.served)
+}
+
+func TestParseSymbols(t *testing.T) {
+	syms, err := parseSymbols(strings.NewReader(callSymbols), "/src")
+	require.NoError(t, err)
+
+	loc, ok := syms.location(0x20A)
+	require.True(t, ok)
+	require.Equal(t, location{filepath.Clean("/src/main.8o"), 8}, loc)
+	_, ok = syms.location(0x20C)
+	require.False(t, ok)
+
+	addr, line, ok := syms.address("/src/main.8o", 4)
+	require.True(t, ok)
+	require.Equal(t, uint16(0x206), addr)
+	require.Equal(t, 4, line)
+	_, _, ok = syms.address("/src/main.8o", 9)
+	require.False(t, ok)
+
+	name, _ := syms.label(0x206)
+	require.Equal(t, "main", name)
+	name, _ = syms.label(0x20A)
+	require.Equal(t, "sub", name)
+	_, ok = syms.label(0x100)
+	require.False(t, ok)
+
+	_, err = parseSymbols(strings.NewReader("0x200 main.8o:1\n0x10000 main.8o:2\n"), "")
+	require.EqualError(t, err, `line 2: bad address "0x10000"`)
+	_, err = parseSymbols(strings.NewReader("0x200 main.8o\n"), "")
+	require.EqualError(t, err, `line 1: bad source line "main.8o"`)
+}
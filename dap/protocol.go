@@ -0,0 +1,111 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// Messages larger than this are refused, requests are a few hundred bytes
+const maxMessageSize = 1 << 20
+
+// A request from the client, the only messages clients send
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// ------------------------------------------------
+// conn reads and writes the base protocol: JSON messages preceded by a
+// Content-Length header, as in LSP. Writes may come from any goroutine.
+// ------------------------------------------------
+type conn struct {
+	r *textproto.Reader
+
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+}
+
+func newConn(rw io.ReadWriter) *conn {
+	return &conn{r: textproto.NewReader(bufio.NewReader(rw)), w: rw}
+}
+
+func (c *conn) read() (request, error) {
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		return request{}, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 || length > maxMessageSize {
+		return request{}, fmt.Errorf("dap: bad Content-Length %q", header.Get("Content-Length"))
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r.R, data); err != nil {
+		return request{}, err
+	}
+
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return request{}, fmt.Errorf("dap: %w", err)
+	}
+	if req.Type != "request" {
+		return request{}, fmt.Errorf("dap: expected a request, got a %q message", req.Type)
+	}
+	return req, nil
+}
+
+// Sends a response, err makes it a failed one
+func (c *conn) respond(req request, body any, err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	resp := response{Seq: c.seq, Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+		resp.Body = nil
+	}
+	return c.write(resp)
+}
+
+func (c *conn) event(name string, body any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	return c.write(event{Seq: c.seq, Type: "event", Event: name, Body: body})
+}
+
+func (c *conn) write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(data)
+	return err
+}
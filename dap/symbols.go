@@ -0,0 +1,130 @@
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Symbol maps tie addresses to source lines, so breakpoints can be set and
+// stack frames shown in the source. A map is a text file with a line per
+// instruction:
+//
+//	0x200 pong.8o:12 main
+//	0x202 pong.8o:13
+//
+// giving the address, FILE:LINE and optionally a label that names the code
+// from that address on. Relative files are relative to the map. Blank lines
+// and lines starting with # are ignored.
+// ------------------------------------------------
+
+type location struct {
+	file string
+	line int
+}
+
+type label struct {
+	addr uint16
+	name string
+}
+
+type symbols struct {
+	locations map[uint16]location
+	labels    []label // By address
+}
+
+func loadSymbols(path string) (*symbols, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	syms, err := parseSymbols(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return syms, nil
+}
+
+func parseSymbols(r io.Reader, dir string) (*symbols, error) {
+	syms := &symbols{locations: make(map[uint16]location)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected ADDRESS FILE:LINE [LABEL]", n)
+		}
+
+		addr, err := strconv.ParseUint(fields[0], 0, 16)
+		if err != nil || addr >= chip8.RAM {
+			return nil, fmt.Errorf("line %d: bad address %q", n, fields[0])
+		}
+		var loc location
+		if len(fields) > 1 {
+			file, lineText, ok := strings.Cut(fields[1], ":")
+			line, err := strconv.Atoi(lineText)
+			if !ok || err != nil || line < 1 {
+				return nil, fmt.Errorf("line %d: bad source line %q", n, fields[1])
+			}
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(dir, file)
+			}
+			loc = location{filepath.Clean(file), line}
+		}
+		syms.locations[uint16(addr)] = loc
+		if len(fields) == 3 {
+			syms.labels = append(syms.labels, label{uint16(addr), fields[2]})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(syms.labels, func(a, b label) int { return int(a.addr) - int(b.addr) })
+	return syms, nil
+}
+
+// The source line of the instruction at addr
+func (s *symbols) location(addr uint16) (location, bool) {
+	loc, ok := s.locations[addr]
+	return loc, ok && loc.line != 0
+}
+
+// The first instruction of line in file, or of the next line with code if
+// it has none. Also returns the line found.
+func (s *symbols) address(file string, line int) (uint16, int, bool) {
+	file = filepath.Clean(file)
+	var best uint16
+	bestLine := 0
+	for addr, loc := range s.locations {
+		if loc.file != file || loc.line < line {
+			continue
+		}
+		if bestLine == 0 || loc.line < bestLine || loc.line == bestLine && addr < best {
+			best, bestLine = addr, loc.line
+		}
+	}
+	return best, bestLine, bestLine != 0
+}
+
+// The label of the code at addr, the closest one at or before it
+func (s *symbols) label(addr uint16) (string, bool) {
+	i, found := slices.BinarySearchFunc(s.labels, addr, func(l label, addr uint16) int { return int(l.addr) - int(addr) })
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return "", false
+	}
+	return s.labels[i].name, true
+}
@@ -0,0 +1,214 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Gamepads.
+// Buttons and stick directions are mapped to CHIP-8 keys and polled once per
+// animation frame. On the page thread the pads come from
+// navigator.getGamepads(). A worker has no Gamepad API, so the page posts
+// snapshots of the same shape instead: [{buttons: [{pressed}], axes: [...]}].
+// ------------------------------------------------
+
+// A stick has to be pushed this far before it counts as a key press
+const axisThreshold = 0.5
+
+var (
+	gamepadKeys     [16]bool    // Keys held on any gamepad at the last poll
+	gamepadSnapshot = js.Null() // Pads last posted by the page in worker mode
+)
+
+type axisDirection struct {
+	axis     int
+	positive bool
+}
+
+type gamepadMapping struct {
+	buttons map[int]uint8
+	axes    map[axisDirection]uint8
+}
+
+// ------------------------------------------------
+// The default mapping for the standard gamepad layout: the d-pad and left
+// stick press 2/4/6/8, the directions most ROMs use, and the bottom face
+// button presses 5.
+// ------------------------------------------------
+func defaultGamepadMapping() gamepadMapping {
+	return gamepadMapping{
+		buttons: map[int]uint8{
+			0:  0x5, // A
+			12: 0x2, // d-pad up
+			13: 0x8, // d-pad down
+			14: 0x4, // d-pad left
+			15: 0x6, // d-pad right
+		},
+		axes: map[axisDirection]uint8{
+			{axis: 0, positive: false}: 0x4,
+			{axis: 0, positive: true}:  0x6,
+			{axis: 1, positive: false}: 0x2,
+			{axis: 1, positive: true}:  0x8,
+		},
+	}
+}
+
+// Inputs of the standard layout for the key roles of the ROM database
+var (
+	roleButtons = map[string]int{"a": 0, "b": 1, "up": 12, "down": 13, "left": 14, "right": 15}
+	roleAxes    = map[string]axisDirection{
+		"up":    {axis: 1, positive: false},
+		"down":  {axis: 1, positive: true},
+		"left":  {axis: 0, positive: false},
+		"right": {axis: 0, positive: true},
+	}
+)
+
+// ------------------------------------------------
+// The mapping for a ROM the ROM database knows the keys of: the d-pad and
+// left stick press its direction keys and the bottom and right face buttons
+// its a and b keys. ROMs without keys get the default mapping.
+// ------------------------------------------------
+func romGamepadMapping(keys map[string]chip8.Key) gamepadMapping {
+	if len(keys) == 0 {
+		return defaultGamepadMapping()
+	}
+
+	mapping := gamepadMapping{buttons: make(map[int]uint8), axes: make(map[axisDirection]uint8)}
+	for role, key := range keys {
+		if button, ok := roleButtons[role]; ok {
+			mapping.buttons[button] = uint8(key)
+		}
+		if dir, ok := roleAxes[role]; ok {
+			mapping.axes[dir] = uint8(key)
+		}
+	}
+	return mapping
+}
+
+func (mapping gamepadMapping) clone() gamepadMapping {
+	return gamepadMapping{buttons: maps.Clone(mapping.buttons), axes: maps.Clone(mapping.axes)}
+}
+
+// ------------------------------------------------
+// Parses a mapping from JavaScript, laid over the base mapping:
+//
+//	{buttons: {"0": 0x4, "14": null}, axes: {"0-": 0x5, "0+": 0x6}}
+//
+// Buttons are keyed by index, axes by index and direction. A key number binds
+// the input, null unbinds it, inputs that are left out keep the base binding.
+// null or undefined gives the base mapping.
+// ------------------------------------------------
+func parseGamepadMapping(jsMapping js.Value, base gamepadMapping) (gamepadMapping, error) {
+	mapping := base.clone()
+	if jsMapping.IsNull() || jsMapping.IsUndefined() {
+		return mapping, nil
+	}
+	if jsMapping.Type() != js.TypeObject {
+		return mapping, fmt.Errorf("expected a gamepad mapping object")
+	}
+
+	err := forEachBinding(jsMapping.Get("buttons"), func(name string, key js.Value) error {
+		button, err := strconv.Atoi(name)
+		if err != nil || button < 0 {
+			return fmt.Errorf("invalid gamepad button %q", name)
+		}
+		return bind(mapping.buttons, button, key)
+	})
+	if err != nil {
+		return mapping, err
+	}
+
+	err = forEachBinding(jsMapping.Get("axes"), func(name string, key js.Value) error {
+		sign := ""
+		if strings.HasSuffix(name, "-") || strings.HasSuffix(name, "+") {
+			sign = name[len(name)-1:]
+		}
+		axis, err := strconv.Atoi(strings.TrimSuffix(name, sign))
+		if err != nil || axis < 0 || sign == "" {
+			return fmt.Errorf("invalid gamepad axis %q, expected e.g. \"0-\" or \"1+\"", name)
+		}
+		return bind(mapping.axes, axisDirection{axis: axis, positive: sign == "+"}, key)
+	})
+	return mapping, err
+}
+
+func forEachBinding(bindings js.Value, fn func(name string, key js.Value) error) error {
+	if bindings.IsNull() || bindings.IsUndefined() {
+		return nil
+	}
+	names := js.Global().Get("Object").Call("keys", bindings)
+	for i := 0; i < names.Length(); i++ {
+		name := names.Index(i).String()
+		if err := fn(name, bindings.Get(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bind[K comparable](bindings map[K]uint8, input K, key js.Value) error {
+	if key.IsNull() {
+		delete(bindings, input)
+		return nil
+	}
+	chip8Key, err := jsKey([]js.Value{key})
+	if err != nil {
+		return err
+	}
+	bindings[input] = chip8Key
+	return nil
+}
+
+// Returns the pads to poll, holes for disconnected pads are skipped when polling
+func connectedGamepads() js.Value {
+	if isWorker() {
+		return gamepadSnapshot
+	}
+	navigator := js.Global().Get("navigator")
+	if navigator.Get("getGamepads").Type() != js.TypeFunction {
+		return js.Null()
+	}
+	return navigator.Call("getGamepads")
+}
+
+// Returns the keys held on any of the pads
+func pollGamepads(mapping gamepadMapping, pads js.Value) (keys [16]bool) {
+	if pads.Type() != js.TypeObject {
+		return keys
+	}
+
+	for i := 0; i < pads.Length(); i++ {
+		pad := pads.Index(i)
+		if pad.Type() != js.TypeObject {
+			continue
+		}
+
+		buttons := pad.Get("buttons")
+		for button, key := range mapping.buttons {
+			if button < buttons.Length() && buttons.Index(button).Get("pressed").Truthy() {
+				keys[key] = true
+			}
+		}
+
+		axes := pad.Get("axes")
+		for dir, key := range mapping.axes {
+			if dir.axis >= axes.Length() {
+				continue
+			}
+			val := axes.Index(dir.axis).Float()
+			if dir.positive && val > axisThreshold || !dir.positive && val < -axisThreshold {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
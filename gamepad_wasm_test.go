@@ -0,0 +1,101 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// Builds pads shaped like navigator.getGamepads(), null for a disconnected pad
+func stubGamepads(t *testing.T, pads string) js.Value {
+	t.Helper()
+	return js.Global().Call("eval", "("+pads+")")
+}
+
+func TestParseGamepadMapping(t *testing.T) {
+	mapping, err := parseGamepadMapping(js.Null(), defaultGamepadMapping())
+	require.NoError(t, err)
+	require.Equal(t, defaultGamepadMapping(), mapping)
+
+	// Overrides are laid over the default, null unbinds
+	mapping, err = parseGamepadMapping(js.Global().Call("eval", `({
+		buttons: { 0: 0x4, 14: null },
+		axes: { "0+": 0xF, "3-": 0x1 },
+	})`), defaultGamepadMapping())
+	require.NoError(t, err)
+	require.Equal(t, uint8(0x4), mapping.buttons[0])
+	require.NotContains(t, mapping.buttons, 14)
+	require.Equal(t, uint8(0x2), mapping.buttons[12])
+	require.Equal(t, uint8(0xF), mapping.axes[axisDirection{axis: 0, positive: true}])
+	require.Equal(t, uint8(0x1), mapping.axes[axisDirection{axis: 3, positive: false}])
+
+	for _, bad := range []string{
+		`({ buttons: { x: 1 } })`,
+		`({ buttons: { 0: 16 } })`,
+		`({ axes: { "0": 1 } })`,
+		`({ axes: { "-": 1 } })`,
+		`"PONG"`,
+	} {
+		_, err := parseGamepadMapping(js.Global().Call("eval", bad), defaultGamepadMapping())
+		require.Error(t, err, bad)
+	}
+}
+
+func TestROMGamepadMapping(t *testing.T) {
+	require.Equal(t, defaultGamepadMapping(), romGamepadMapping(nil))
+
+	mapping := romGamepadMapping(map[string]chip8.Key{"up": chip8.KEY_1, "down": chip8.KEY_4, "a": chip8.KEY_5, "player2Up": chip8.KEY_C})
+	require.Equal(t, map[int]uint8{0: 0x5, 12: 0x1, 13: 0x4}, mapping.buttons)
+	require.Equal(t, map[axisDirection]uint8{
+		{axis: 1, positive: false}: 0x1,
+		{axis: 1, positive: true}:  0x4,
+	}, mapping.axes)
+
+	// The page's bindings are laid over the ROM's, the base is left alone
+	custom, err := parseGamepadMapping(js.Global().Call("eval", `({ buttons: { 0: 0x6 } })`), mapping)
+	require.NoError(t, err)
+	require.Equal(t, uint8(0x6), custom.buttons[0])
+	require.Equal(t, uint8(0x5), mapping.buttons[0])
+}
+
+func TestPollGamepads(t *testing.T) {
+	mapping := defaultGamepadMapping()
+
+	keys := pollGamepads(mapping, js.Null())
+	require.Equal(t, [16]bool{}, keys)
+
+	// d-pad up on the second pad, left stick pushed right on the first,
+	// a stick resting near the centre doesn't count
+	keys = pollGamepads(mapping, stubGamepads(t, `[
+		{ buttons: [{ pressed: false }], axes: [0.9, 0.2] },
+		null,
+		{ buttons: Array.from({ length: 16 }, (_, i) => ({ pressed: i === 12 })), axes: [] },
+	]`))
+	var want [16]bool
+	want[0x2] = true
+	want[0x6] = true
+	require.Equal(t, want, keys)
+}
+
+func TestAPI_SetGamepadMapping(t *testing.T) {
+	s, api := newTestAPI(t)
+
+	gamepadSnapshot = js.Null()
+	require.True(t, api.Call("setGamepadMapping", js.Global().Call("eval", `({ buttons: { 0: 0xA } })`)).IsNull())
+	require.Equal(t, uint8(0xA), s.gamepad.buttons[0])
+
+	// Gamepad keys reach the emulator alongside the keyboard's
+	gamepadKeys[0xA] = true
+	defer func() { gamepadKeys = [16]bool{} }()
+	require.True(t, api.Call("step").IsNull())
+	require.True(t, s.emulator().IsKeyPressed(0xA))
+
+	require.Equal(t, "expected a gamepad mapping object", api.Call("setGamepadMapping", 1).String())
+	require.True(t, api.Call("setGamepadMapping").IsNull())
+	require.Equal(t, defaultGamepadMapping(), s.gamepad)
+}
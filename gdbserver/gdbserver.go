@@ -0,0 +1,471 @@
+// Package gdbserver is a GDB remote serial protocol stub, so GDB, LLDB and
+// other RSP clients can debug a Chip8 as a remote target:
+//
+//	(gdb) target remote :1234
+//	(gdb) break *0x208
+//	(gdb) continue
+//
+// The registers, numbered in the order of the g packet, are
+//
+//	0-15  v0-vf  8 bits
+//	16    i      16 bits
+//	17    pc     16 bits
+//	18    sp     8 bits, the depth of the stack of return addresses
+//	19    dt     8 bits, the delay timer
+//	20    st     8 bits, the sound timer
+//
+// big endian like CHIP-8's memory, as the target description served with
+// qXfer:features:read says. Memory is the 4 KiB address space.
+//
+// The stub understands ?, g, G, p, P, m, M, Z0 and z0 (software
+// breakpoints), s, c, the ^C interrupt, D, k, qSupported, QStartNoAckMode
+// and qXfer:features:read, and replies to enough queries for GDB's
+// handshake. It answers anything else with an empty packet, as the protocol
+// says, so clients fall back to what is supported.
+package gdbserver
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Stop replies, the numbers are signals
+const (
+	stopTrap      = "S05" // A step finished or a breakpoint was hit
+	stopInterrupt = "S02" // The client sent ^C
+	stopSegv      = "S0B" // PC ran past the end of memory, or an instruction panicked
+)
+
+// Error replies
+const (
+	errBadPacket = "E01"
+	errMemory    = "E0E" // The address is outside memory
+)
+
+// Options configure a stub
+type Options struct {
+	Display host.Display // Shows the frames run by c, may be nil
+	Clock   host.Clock   // Paces c, real time if nil
+}
+
+type session struct {
+	conn    *conn
+	in      chan input
+	opts    Options
+	machine *chip8.Chip8
+	runner  *host.Runner
+
+	breakpoints map[uint16]bool
+	swbreak     bool   // The client understands swbreak stop reasons
+	lastStop    string // Reported again by ?
+	running     bool
+	resuming    bool   // The instruction at PC runs even if it has a breakpoint
+	stop        string // Set by the Fetch hook when it stops the machine
+}
+
+// ------------------------------------------------
+// Serve debugs machine for the RSP client at the other end of rw until it
+// detaches, kills the target or hangs up, and closes rw. Killing restarts
+// the ROM for the next client. The machine only runs on s and c.
+// ------------------------------------------------
+func Serve(rw io.ReadWriteCloser, machine *chip8.Chip8, opts Options) error {
+	if opts.Display == nil {
+		opts.Display = discardDisplay{}
+	}
+	if opts.Clock == nil {
+		opts.Clock = host.NewRealtimeClock(host.FrameRate)
+	}
+	s := &session{
+		conn:        &conn{rw: rw},
+		in:          make(chan input),
+		opts:        opts,
+		machine:     machine,
+		runner:      host.NewRunner(machine, opts.Display, nil, nil),
+		breakpoints: make(map[uint16]bool),
+		lastStop:    stopTrap,
+	}
+	machine.SetHooks(chip8.Hooks{Fetch: s.fetch})
+	defer machine.SetHooks(chip8.Hooks{})
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- s.conn.readLoop(s.in)
+	}()
+	defer func() {
+		rw.Close()
+		for range s.in {
+		}
+	}()
+
+	for {
+		in, ok := <-s.in
+		if !ok {
+			return <-readErr
+		}
+		var err error
+		switch {
+		case in.nack:
+			err = s.conn.resend()
+		case in.packet != "":
+			reply, done := s.handle(in.packet)
+			if done && reply == "" {
+				return nil
+			}
+			if err = s.conn.send(reply); done {
+				return err
+			}
+		}
+		// ^C while stopped has nothing to interrupt
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the reply to a packet, and whether the session is over
+func (s *session) handle(packet string) (string, bool) {
+	switch {
+	case packet == "?":
+		return s.lastStop, false
+	case packet == "g":
+		return hex.EncodeToString(s.registers()), false
+	case strings.HasPrefix(packet, "G"):
+		return s.writeRegisters(packet[1:]), false
+	case strings.HasPrefix(packet, "p"):
+		return s.readRegister(packet[1:]), false
+	case strings.HasPrefix(packet, "P"):
+		return s.writeRegister(packet[1:]), false
+	case strings.HasPrefix(packet, "m"):
+		return s.readMemory(packet[1:]), false
+	case strings.HasPrefix(packet, "M"):
+		return s.writeMemory(packet[1:]), false
+	case strings.HasPrefix(packet, "Z0,"), strings.HasPrefix(packet, "z0,"):
+		return s.breakpoint(packet[0] == 'Z', packet[3:]), false
+	case strings.HasPrefix(packet, "s"):
+		return s.resume(packet[1:], true)
+	case strings.HasPrefix(packet, "c"):
+		return s.resume(packet[1:], false)
+	case packet == "D" || strings.HasPrefix(packet, "D;"):
+		return "OK", true
+	case packet == "k":
+		// No reply, the client hangs up
+		s.machine.Reset()
+		return "", true
+	case strings.HasPrefix(packet, "qSupported"):
+		s.swbreak = strings.Contains(packet, "swbreak+")
+		return fmt.Sprintf("PacketSize=%x;QStartNoAckMode+;qXfer:features:read+;swbreak+", packetSize), false
+	case packet == "QStartNoAckMode":
+		return "OK", false
+	case strings.HasPrefix(packet, "qXfer:features:read:"):
+		return s.readFeatures(strings.TrimPrefix(packet, "qXfer:features:read:")), false
+	case packet == "qAttached":
+		return "1", false
+	case packet == "qC":
+		return "QC1", false
+	case packet == "qfThreadInfo":
+		return "m1", false
+	case packet == "qsThreadInfo":
+		return "l", false
+	case packet == "qSymbol::":
+		return "OK", false
+	case strings.HasPrefix(packet, "H"), strings.HasPrefix(packet, "T"):
+		return "OK", false
+	}
+	return "", false
+}
+
+// ------------------------------------------------
+// Registers
+// ------------------------------------------------
+
+type register struct {
+	name string
+	size int // Bytes
+	typ  string
+}
+
+var registers = func() []register {
+	var regs []register
+	for x := 0; x < 16; x++ {
+		regs = append(regs, register{fmt.Sprintf("v%x", x), 1, "uint8"})
+	}
+	return append(regs,
+		register{"i", 2, "data_ptr"},
+		register{"pc", 2, "code_ptr"},
+		register{"sp", 1, "uint8"},
+		register{"dt", 1, "uint8"},
+		register{"st", 1, "uint8"},
+	)
+}()
+
+// The registers as the g packet sends them
+func (s *session) registers() []byte {
+	state := s.machine.State()
+	data := append([]byte(nil), state.V[:]...)
+	data = append(data, byte(state.I>>8), byte(state.I), byte(state.PC>>8), byte(state.PC))
+	return append(data, byte(len(state.Stack)), state.DelayTimer, state.SoundTimer)
+}
+
+// Sets the registers from the g layout
+func (s *session) setRegisters(data []byte) string {
+	state := s.machine.State()
+	copy(state.V[:], data)
+	state.I = uint16(data[16])<<8 | uint16(data[17])
+	state.PC = uint16(data[18])<<8 | uint16(data[19])
+	if depth := int(data[20]); depth <= len(state.Stack) {
+		state.Stack = state.Stack[:depth]
+	} else {
+		state.Stack = append(state.Stack, make([]uint16, depth-len(state.Stack))...)
+	}
+	state.DelayTimer, state.SoundTimer = data[21], data[22]
+	if err := s.machine.SetState(state); err != nil {
+		return errBadPacket
+	}
+	return "OK"
+}
+
+// Offset of register n in the g layout
+func registerOffset(n int) int {
+	offset := 0
+	for _, reg := range registers[:n] {
+		offset += reg.size
+	}
+	return offset
+}
+
+func (s *session) writeRegisters(args string) string {
+	data, err := hex.DecodeString(args)
+	if err != nil || len(data) != registerOffset(len(registers)) {
+		return errBadPacket
+	}
+	return s.setRegisters(data)
+}
+
+func (s *session) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || int(n) >= len(registers) {
+		return errBadPacket
+	}
+	offset := registerOffset(int(n))
+	return hex.EncodeToString(s.registers()[offset : offset+registers[n].size])
+}
+
+func (s *session) writeRegister(args string) string {
+	number, value, _ := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(number, 16, 8)
+	if err != nil || int(n) >= len(registers) {
+		return errBadPacket
+	}
+	data, err := hex.DecodeString(value)
+	if err != nil || len(data) != registers[n].size {
+		return errBadPacket
+	}
+	regs := s.registers()
+	copy(regs[registerOffset(int(n)):], data)
+	return s.setRegisters(regs)
+}
+
+// ------------------------------------------------
+// Memory
+// ------------------------------------------------
+
+// Parses ADDR,LENGTH, both hex
+func parseRange(args string) (int, int, bool) {
+	addrText, lengthText, ok := strings.Cut(args, ",")
+	addr, err := strconv.ParseUint(addrText, 16, 32)
+	if !ok || err != nil {
+		return 0, 0, false
+	}
+	length, err := strconv.ParseUint(lengthText, 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(addr), int(length), true
+}
+
+// Reads stop at the end of memory, the client asks again for the rest
+func (s *session) readMemory(args string) string {
+	addr, length, ok := parseRange(args)
+	if !ok {
+		return errBadPacket
+	}
+	if addr >= chip8.RAM {
+		return errMemory
+	}
+	end := min(addr+length, chip8.RAM, addr+(packetSize-4)/2)
+	memory := s.machine.State().Memory
+	return hex.EncodeToString(memory[addr:end])
+}
+
+func (s *session) writeMemory(args string) string {
+	where, value, _ := strings.Cut(args, ":")
+	addr, length, ok := parseRange(where)
+	data, err := hex.DecodeString(value)
+	if !ok || err != nil || len(data) != length {
+		return errBadPacket
+	}
+	if addr+length > chip8.RAM {
+		return errMemory
+	}
+	state := s.machine.State()
+	copy(state.Memory[addr:], data)
+	if err := s.machine.SetState(state); err != nil {
+		return errBadPacket
+	}
+	return "OK"
+}
+
+// ------------------------------------------------
+// Execution
+// ------------------------------------------------
+
+// Z0,ADDR,KIND sets a breakpoint, z0 removes it. KIND is the instruction
+// size and is ignored.
+func (s *session) breakpoint(set bool, args string) string {
+	addrText, _, _ := strings.Cut(args, ",")
+	addr, err := strconv.ParseUint(addrText, 16, 32)
+	if err != nil {
+		return errBadPacket
+	}
+	if addr > chip8.RAM-2 {
+		return errMemory
+	}
+	if set {
+		s.breakpoints[uint16(addr)] = true
+	} else {
+		delete(s.breakpoints, uint16(addr))
+	}
+	return "OK"
+}
+
+// s and c, optionally resuming at an address. Steps execute an instruction
+// and leave the timers alone, continuing runs frames until a breakpoint or
+// ^C, at least one. An instruction that panics stops the target with a
+// segmentation fault. Returns the stop reply, and true if the client hung up
+// meanwhile.
+func (s *session) resume(args string, step bool) (reply string, hungUp bool) {
+	if args != "" {
+		addr, err := strconv.ParseUint(args, 16, 16)
+		if err != nil {
+			return errBadPacket, false
+		}
+		s.machine.PC = uint16(addr)
+	}
+
+	s.running, s.resuming, s.stop = true, true, ""
+	s.runner.SetPaused(false)
+	defer func() {
+		s.running = false
+		if r := recover(); r != nil {
+			s.lastStop = stopSegv
+			reply, hungUp = s.lastStop, false
+		}
+	}()
+	if step {
+		s.lastStop = stopTrap
+		if s.machine.ProgramCounter() > chip8.RAM-2 {
+			s.lastStop = stopSegv
+		} else {
+			s.machine.Step()
+		}
+		return s.lastStop, false
+	}
+
+	for {
+		// Never fails without a deadline
+		s.opts.Clock.Tick(context.Background())
+		if err := s.runner.Frame(); err != nil {
+			s.lastStop = stopSegv
+			return s.lastStop, false
+		}
+		if s.stop != "" {
+			s.lastStop = s.stop
+			return s.lastStop, false
+		}
+
+		select {
+		case in, ok := <-s.in:
+			if !ok {
+				return "", true
+			}
+			// All-stop clients send nothing else while the target runs
+			if in.interrupt {
+				s.lastStop = stopInterrupt
+				return s.lastStop, false
+			}
+		default:
+		}
+	}
+}
+
+func (s *session) fetch(pc uint16, op chip8.Opcode) bool {
+	if !s.running {
+		return false
+	}
+	if s.resuming {
+		s.resuming = false
+		return true
+	}
+	if s.breakpoints[pc] {
+		// Skips the rest of the frame, timers included
+		s.running = false
+		s.runner.SetPaused(true)
+		s.stop = stopTrap
+		if s.swbreak {
+			s.stop = "T05swbreak:;"
+		}
+		return false
+	}
+	return true
+}
+
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
+
+// ------------------------------------------------
+// Target description
+// ------------------------------------------------
+
+var targetXML = func() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?>` + "\n")
+	b.WriteString(`<!DOCTYPE target SYSTEM "gdb-target.dtd">` + "\n")
+	b.WriteString(`<target version="1.0">` + "\n")
+	b.WriteString(`  <feature name="org.chip8.core">` + "\n")
+	for n, reg := range registers {
+		fmt.Fprintf(&b, `    <reg name="%s" bitsize="%d" type="%s" regnum="%d"/>`+"\n", reg.name, reg.size*8, reg.typ, n)
+	}
+	b.WriteString("  </feature>\n</target>\n")
+	return b.String()
+}()
+
+// target.xml:OFFSET,LENGTH, replies m with more to come or l for the last part
+func (s *session) readFeatures(args string) string {
+	annex, where, _ := strings.Cut(args, ":")
+	if annex != "target.xml" {
+		return "E00"
+	}
+	offset, length, ok := parseRange(where)
+	if !ok {
+		return errBadPacket
+	}
+	if offset >= len(targetXML) {
+		return "l"
+	}
+	end := min(offset+length, len(targetXML))
+	part := "m"
+	if end == len(targetXML) {
+		part = "l"
+	}
+	return part + escape(targetXML[offset:end])
+}
@@ -0,0 +1,218 @@
+package gdbserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Calls a subroutine and then loops forever:
+//
+//	0x200: 6005  V0 = 5
+//	0x202: 2208  call 0x208
+//	0x204: 7001  V0 += 1
+//	0x206: 1206  jump to 0x206
+//	0x208: 6107  V1 = 7
+//	0x20A: 00EE  return
+var callROM = []byte{0x60, 0x05, 0x22, 0x08, 0x70, 0x01, 0x12, 0x06, 0x61, 0x07, 0x00, 0xEE}
+
+// An RSP client
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	r      *bufio.Reader
+	noAck  bool
+	served chan error
+}
+
+func start(t *testing.T, rom []byte) (*client, *chip8.Chip8) {
+	t.Helper()
+	machine, err := host.NewMachine(rom, chip8.Options{Speed: 600})
+	require.NoError(t, err)
+
+	server, conn := net.Pipe()
+	c := &client{t: t, conn: conn, r: bufio.NewReader(conn), served: make(chan error, 1)}
+	go func() {
+		c.served <- Serve(server, machine, Options{Clock: host.Unthrottled{}})
+	}()
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return c, machine
+}
+
+func (c *client) write(s string) {
+	c.t.Helper()
+	_, err := io.WriteString(c.conn, s)
+	require.NoError(c.t, err)
+}
+
+// Sends a packet and returns the reply
+func (c *client) send(data string) string {
+	c.t.Helper()
+	c.write(fmt.Sprintf("$%s#%02x", data, checksum(data)))
+	if !c.noAck {
+		c.expectByte('+')
+	}
+	return c.reply()
+}
+
+func (c *client) expectByte(want byte) {
+	c.t.Helper()
+	b, err := c.r.ReadByte()
+	require.NoError(c.t, err)
+	require.Equal(c.t, string(want), string(b))
+}
+
+// Reads a packet and checks its checksum
+func (c *client) reply() string {
+	c.t.Helper()
+	c.expectByte('$')
+	data, err := c.r.ReadString('#')
+	require.NoError(c.t, err)
+	data = strings.TrimSuffix(data, "#")
+	sum := make([]byte, 2)
+	_, err = io.ReadFull(c.r, sum)
+	require.NoError(c.t, err)
+	require.Equal(c.t, fmt.Sprintf("%02x", checksum(data)), string(sum))
+	if !c.noAck {
+		c.write("+")
+	}
+	return data
+}
+
+func TestServe_Session(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	c, machine := start(t, callROM)
+
+	require.Contains(t, c.send("qSupported:multiprocess+;swbreak+;xmlRegisters=i386"), "qXfer:features:read+")
+	require.Equal(t, "OK", c.send("QStartNoAckMode"))
+	c.noAck = true
+
+	// The target description comes in parts
+	xml := c.send("qXfer:features:read:target.xml:0,40")
+	require.Equal(t, "m"+targetXML[:0x40], xml)
+	xml = c.send("qXfer:features:read:target.xml:40,fff")
+	require.True(t, strings.HasPrefix(xml, "l"))
+	require.Contains(t, targetXML, `<reg name="pc" bitsize="16" type="code_ptr" regnum="17"/>`)
+
+	// Registers: V0-VF, I, PC, SP, DT, ST
+	require.Equal(t, "S05", c.send("?"))
+	require.Equal(t, strings.Repeat("00", 16)+"0000"+"0200"+"00"+"00"+"00", c.send("g"))
+	require.Equal(t, "OK", c.send("P10=0300"))
+	require.Equal(t, "0300", c.send("p10"))
+	require.Equal(t, "OK", c.send("Pa=2a"))
+	require.Equal(t, uint8(0x2A), machine.Registers()[0xA])
+	require.Equal(t, "E01", c.send("p15"))
+	require.Equal(t, "E01", c.send("P11=02"))
+
+	// Memory
+	require.Equal(t, "60052208", c.send("m200,4"))
+	require.Equal(t, "OK", c.send("M300,2:abcd"))
+	require.Equal(t, "abcd", c.send("m300,2"))
+	require.Equal(t, "0000", c.send("mffe,10")) // Up to the end of memory
+	require.Equal(t, "E0E", c.send("m1000,2"))
+	require.Equal(t, "E0E", c.send("Mfff,2:0000"))
+
+	// Into the subroutine up to the breakpoint, which has swbreak as the
+	// client supports it
+	require.Equal(t, "OK", c.send("Z0,208,2"))
+	require.Equal(t, "T05swbreak:;", c.send("c"))
+	require.Equal(t, "0208", c.send("p11"))
+	require.Equal(t, "01", c.send("p12"))
+	require.Equal(t, "05", c.send("p0"))
+
+	// Steps run the instruction at a breakpoint
+	require.Equal(t, "S05", c.send("s"))
+	require.Equal(t, "020a", c.send("p11"))
+	require.Equal(t, "07", c.send("p1"))
+	require.Equal(t, "S05", c.send("s"))
+	require.Equal(t, "0204", c.send("p11"))
+	require.Equal(t, "00", c.send("p12"))
+
+	// Runs the jump forever until interrupted
+	require.Equal(t, "OK", c.send("z0,208,2"))
+	c.write("$c#63")
+	c.write("\x03")
+	require.Equal(t, "S02", c.reply())
+	require.Equal(t, "0206", c.send("p11"))
+	require.Equal(t, "06", c.send("p0"))
+
+	// Writing the registers back moves PC and SP
+	regs := []byte(c.send("g"))
+	copy(regs[36:], "0208"+"01")
+	require.Equal(t, "OK", c.send("G"+string(regs)))
+	require.Equal(t, uint16(0x208), machine.ProgramCounter())
+	require.Equal(t, []uint16{0}, machine.Stack())
+
+	require.Equal(t, "", c.send("vMustReplyEmpty"))
+	require.Equal(t, "OK", c.send("D"))
+	require.NoError(t, <-c.served)
+}
+
+func TestServe_Acks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	c, machine := start(t, callROM)
+
+	// A corrupt packet is refused, and a reply the client refuses is sent again
+	c.write("$g#00")
+	c.expectByte('-')
+	require.Equal(t, "S05", c.send("?"))
+	c.write("-")
+	require.Equal(t, "S05", c.reply())
+
+	// Killing restarts the ROM and ends the session
+	require.Equal(t, "S05", c.send("s"))
+	c.write(fmt.Sprintf("$k#%02x", checksum("k")))
+	c.expectByte('+')
+	require.NoError(t, <-c.served)
+	require.Equal(t, uint16(0x200), machine.ProgramCounter())
+	require.Equal(t, [16]uint8{}, machine.Registers())
+}
+
+func TestServe_Halted(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// Jumps to the last instruction, whose fetch would read past memory
+	c, _ := start(t, []byte{0x1F, 0xFE})
+	require.Equal(t, "S0B", c.send("c"))
+	require.Equal(t, "1000", c.send("p11"))
+	require.Equal(t, "S0B", c.send("s"))
+
+	// The client hanging up ends the session
+	c.conn.Close()
+	require.NoError(t, <-c.served)
+}
+
+func TestServe_Panic(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// Sets the delay timer, then draws sprites read past memory:
+	//
+	//	0x200: 6005  V0 = 5
+	//	0x202: F015  DT = V0
+	//	0x204: AFFF  I = 0xFFF
+	//	0x206: D015  draw
+	//	0x208: D015  draw
+	c, _ := start(t, []byte{0x60, 0x05, 0xF0, 0x15, 0xAF, 0xFF, 0xD0, 0x15, 0xD0, 0x15})
+
+	// A breakpoint skips the rest of the frame, timers included
+	require.Equal(t, "OK", c.send("Z0,206,2"))
+	require.Equal(t, "S05", c.send("c"))
+	require.Equal(t, "05", c.send("p13"))
+
+	// Instructions that panic stop the target, running or stepping
+	require.Equal(t, "S0B", c.send("c"))
+	require.Equal(t, "S0B", c.send("s"))
+	require.Equal(t, "S0B", c.send("?"))
+
+	c.conn.Close()
+	require.NoError(t, <-c.served)
+}
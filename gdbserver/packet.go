@@ -0,0 +1,126 @@
+package gdbserver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	packetSize = 0x1000 // Largest packet either side sends, announced in qSupported
+	interrupt  = 0x03   // Sent by the client to stop a running target
+)
+
+// What the client sent: a packet, an interrupt or a request to resend
+type input struct {
+	packet    string
+	interrupt bool
+	nack      bool
+}
+
+// ------------------------------------------------
+// conn frames packets as $data#checksum. Until the client asks for
+// QStartNoAckMode every packet is acknowledged with + or, if its checksum is
+// wrong, - to have it sent again.
+// ------------------------------------------------
+type conn struct {
+	rw    io.ReadWriteCloser
+	noAck atomic.Bool
+
+	mu   sync.Mutex
+	last string // The last packet sent, for resending
+}
+
+// Reads the client's input until the connection breaks, the error is nil
+// at EOF
+func (c *conn) readLoop(in chan<- input) error {
+	defer close(in)
+	r := bufio.NewReader(c.rw)
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch b {
+		case interrupt:
+			in <- input{interrupt: true}
+		case '-':
+			in <- input{nack: true}
+		case '$':
+			data, err := r.ReadString('#')
+			if err != nil {
+				return err
+			}
+			var sum [2]byte
+			if _, err := io.ReadFull(r, sum[:]); err != nil {
+				return err
+			}
+			data = strings.TrimSuffix(data, "#")
+			if c.noAck.Load() {
+				in <- input{packet: data}
+				continue
+			}
+			if fmt.Sprintf("%02x", checksum(data)) != strings.ToLower(string(sum[:])) {
+				c.writeRaw("-")
+				continue
+			}
+			c.writeRaw("+")
+			if data == "QStartNoAckMode" {
+				c.noAck.Store(true)
+			}
+			in <- input{packet: data}
+		}
+		// Acknowledgements of our packets are ignored, we never wait for them
+	}
+}
+
+// Sends a packet, data must already be escaped
+func (c *conn) send(data string) error {
+	c.mu.Lock()
+	c.last = data
+	c.mu.Unlock()
+	return c.writeRaw(fmt.Sprintf("$%s#%02x", data, checksum(data)))
+}
+
+func (c *conn) resend() error {
+	c.mu.Lock()
+	last := c.last
+	c.mu.Unlock()
+	return c.send(last)
+}
+
+func (c *conn) writeRaw(s string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := io.WriteString(c.rw, s)
+	return err
+}
+
+func checksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+// Escapes the characters that frame packets, for binary replies
+func escape(data string) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '#', '$', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(c ^ 0x20)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
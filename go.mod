@@ -5,11 +5,14 @@ go 1.23.3
 require (
 	github.com/stretchr/testify v1.10.0
 	github.com/veandco/go-sdl2 v0.4.40
-	golang.org/x/exp v0.0.0-20250718183923-645b1fa84792
+	go.uber.org/goleak v1.3.0
+	golang.org/x/term v0.33.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.34.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,49 @@
+package host
+
+import (
+	"context"
+	"time"
+)
+
+// Clock paces the frames of Runner.Run
+type Clock interface {
+	// Tick blocks until the next frame is due, or returns ctx.Err() once ctx is done
+	Tick(ctx context.Context) error
+}
+
+// ------------------------------------------------
+// RealtimeClock ticks at a fixed rate. A frame that runs late moves the
+// schedule back rather than making the following frames run back to back.
+// ------------------------------------------------
+type RealtimeClock struct {
+	period time.Duration
+	next   time.Time
+}
+
+func NewRealtimeClock(rate int) *RealtimeClock {
+	return &RealtimeClock{period: time.Second / time.Duration(rate)}
+}
+
+func (c *RealtimeClock) Tick(ctx context.Context) error {
+	now := time.Now()
+	if c.next.Before(now) {
+		c.next = now
+	}
+	c.next = c.next.Add(c.period)
+
+	timer := time.NewTimer(c.next.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Unthrottled runs frames as fast as possible, e.g. to record headlessly
+type Unthrottled struct{}
+
+func (Unthrottled) Tick(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,61 @@
+package host
+
+import (
+	"errors"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Front-end independent hosting of a Chip8.
+// A front-end provides a Display, and optionally Input and an AudioSink, and
+// the Runner drives the machine against them one 60 Hz frame at a time, so
+// every build runs ROMs the same way. Frames are either pulled by the
+// front-end (Runner.Frame, e.g. from requestAnimationFrame) or paced by a
+// Clock (Runner.Run).
+// ------------------------------------------------
+
+// Frames per second, the rate the timers count down at
+const FrameRate = chip8.TIMER_HZ
+
+var (
+	// ErrQuit is returned by Input.Poll when the user asked to quit
+	ErrQuit = errors.New("quit")
+
+	// ErrHalted is returned once PC has run off the end of memory, the same
+	// error as chip8.Run's
+	ErrHalted = chip8.ErrHalted
+)
+
+// Display shows the frames
+type Display interface {
+	// Present is called once per frame with the display in PRESENT_LIVE
+	// mode, or the presentation buffer with levels up to chip8.FRAME_MAX in
+	// the others. changed is false if the frame is the same as the last one,
+	// displays with effects of their own may still need to draw.
+	Present(frame [][]int, changed bool) error
+}
+
+// Input reads the keys
+type Input interface {
+	// Poll is called at the start of every frame and returns the CHIP-8 keys
+	// held down, indexed by key. Returning an error, e.g. ErrQuit, stops the Runner.
+	Poll() ([16]bool, error)
+}
+
+// AudioSink plays the buzzer
+type AudioSink interface {
+	// SetTone is called every frame with whether the buzzer sounds
+	SetTone(on bool)
+}
+
+// ------------------------------------------------
+// NewMachine creates a Chip8 with the ROM loaded and PC at its start
+// ------------------------------------------------
+func NewMachine(rom []byte, opts chip8.Options) (*chip8.Chip8, error) {
+	machine := chip8.New(opts)
+	if err := machine.LoadROM(rom); err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
@@ -0,0 +1,108 @@
+package host
+
+import (
+	"context"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// Runner runs a machine against a front-end
+type Runner struct {
+	machine *chip8.Chip8
+	display Display
+	input   Input     // May be nil
+	audio   AudioSink // May be nil
+	paused  bool
+	carry   int // Remainder of Speed()/FrameRate from the frames so far
+}
+
+// NewRunner creates a Runner, input and audio may be nil
+func NewRunner(machine *chip8.Chip8, display Display, input Input, audio AudioSink) *Runner {
+	return &Runner{machine: machine, display: display, input: input, audio: audio}
+}
+
+func (r *Runner) Machine() *chip8.Chip8 {
+	return r.machine
+}
+
+// SetMachine swaps the machine, e.g. to run another ROM
+func (r *Runner) SetMachine(machine *chip8.Chip8) {
+	r.machine = machine
+}
+
+func (r *Runner) Paused() bool {
+	return r.paused
+}
+
+// SetPaused stops and restarts the CPU and timers, a paused Runner keeps
+// polling input and presenting frames. A chip8.Hooks function may pause
+// the Runner mid-frame, the frame then neither runs more instructions nor
+// ticks the timers.
+func (r *Runner) SetPaused(paused bool) {
+	r.paused = paused
+}
+
+// ------------------------------------------------
+// Frame runs one frame: polls the input, executes Speed()/FrameRate
+// instructions, ticks the timers and presents the frame. What the division
+// leaves over carries to the next frames, so 700 Hz runs 11, 12, 12, ...
+// instructions per frame rather than 11.
+// ------------------------------------------------
+func (r *Runner) Frame() error {
+	machine := r.machine
+
+	if r.input != nil {
+		keys, err := r.input.Poll()
+		if err != nil {
+			return err
+		}
+		for key, held := range keys {
+			machine.UpdateKeyboardState(chip8.Key(key), held)
+		}
+	}
+
+	sound := false
+	if !r.paused {
+		r.carry += machine.Speed()
+		steps := r.carry / FrameRate
+		r.carry %= FrameRate
+
+		// A hook pausing the Runner skips the rest of the frame
+		for i := 0; i < steps && !r.paused; i++ {
+			// Fetch reads two bytes at PC
+			if machine.ProgramCounter() > chip8.RAM-2 {
+				return ErrHalted
+			}
+			machine.Step()
+		}
+		if !r.paused {
+			sound = machine.TickTimers()
+		}
+	}
+	if r.audio != nil {
+		r.audio.SetTone(sound)
+	}
+
+	// Blended present modes present the buffer composed at this vblank
+	frame := machine.GetDisplay()
+	changed := machine.ShouldRedraw()
+	machine.ResetRedraw()
+	if machine.PresentMode() != chip8.PRESENT_LIVE {
+		frame = machine.Frame()
+		changed = machine.Vblank()
+	}
+	return r.display.Present(frame, changed)
+}
+
+// Run runs frames paced by the clock until ctx is done or a frame fails,
+// and returns why it stopped
+func (r *Runner) Run(ctx context.Context, clock Clock) error {
+	for {
+		if err := clock.Tick(ctx); err != nil {
+			return err
+		}
+		if err := r.Frame(); err != nil {
+			return err
+		}
+	}
+}
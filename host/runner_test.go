@@ -0,0 +1,211 @@
+package host
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// The js/wasm runtime parks a goroutine of its own in runtime.handleEvent
+var ignoreJSEvents = goleak.IgnoreAnyFunction("runtime.handleEvent")
+
+type fakeDisplay struct {
+	frames  [][][]int
+	changed []bool
+}
+
+func (d *fakeDisplay) Present(frame [][]int, changed bool) error {
+	d.frames = append(d.frames, frame)
+	d.changed = append(d.changed, changed)
+	return nil
+}
+
+type fakeInput struct {
+	keys [16]bool
+	err  error
+}
+
+func (i *fakeInput) Poll() ([16]bool, error) {
+	return i.keys, i.err
+}
+
+type fakeAudio struct {
+	tones []bool
+}
+
+func (a *fakeAudio) SetTone(on bool) {
+	a.tones = append(a.tones, on)
+}
+
+// Sets the sound timer to 2, then counts V0 up:
+//
+//	0x200: 6002  V0 = 2
+//	0x202: F018  ST = V0
+//	0x204: 6000  V0 = 0
+//	0x206: 7001  V0 += 1
+//	0x208: 1206  jump to 0x206
+var soundROM = []byte{0x60, 0x02, 0xF0, 0x18, 0x60, 0x00, 0x70, 0x01, 0x12, 0x06}
+
+func newTestRunner(t *testing.T) (*Runner, *fakeDisplay, *fakeInput, *fakeAudio) {
+	t.Helper()
+	machine, err := NewMachine(soundROM, chip8.Options{Speed: 600}) // 10 instructions per frame
+	require.NoError(t, err)
+
+	display, input, audio := &fakeDisplay{}, &fakeInput{}, &fakeAudio{}
+	return NewRunner(machine, display, input, audio), display, input, audio
+}
+
+func TestNewMachine(t *testing.T) {
+	machine, err := NewMachine(soundROM, chip8.Options{Speed: 600})
+	require.NoError(t, err)
+	require.Equal(t, uint16(0x200), machine.ProgramCounter())
+	require.Equal(t, 600, machine.Speed())
+
+	_, err = NewMachine(make([]byte, chip8.RAM), chip8.Options{Speed: 600})
+	require.Error(t, err)
+}
+
+func TestRunner_Frame(t *testing.T) {
+	runner, display, input, audio := newTestRunner(t)
+	input.keys[0xB] = true
+
+	require.NoError(t, runner.Frame())
+	machine := runner.Machine()
+	require.Equal(t, uint8(4), machine.Registers()[0], "3 setup instructions then 7 alternating 7001/1206")
+	require.True(t, machine.IsKeyPressed(0xB))
+	require.Len(t, display.frames, 1)
+	require.Equal(t, machine.GetDisplay(), display.frames[0])
+
+	// The sound timer was set to 2 and counts down once per frame
+	require.NoError(t, runner.Frame())
+	require.NoError(t, runner.Frame())
+	require.Equal(t, []bool{true, true, false}, audio.tones)
+
+	input.keys[0xB] = false
+	require.NoError(t, runner.Frame())
+	require.False(t, machine.IsKeyPressed(0xB))
+}
+
+func TestRunner_PresentModes(t *testing.T) {
+	runner, display, _, _ := newTestRunner(t)
+
+	// The live display is presented as changed when the first frame is drawn only
+	require.NoError(t, runner.Frame())
+	require.NoError(t, runner.Frame())
+	require.Equal(t, []bool{true, false}, display.changed)
+
+	runner.Machine().SetPresentMode(chip8.PRESENT_BLEND, 1)
+	require.NoError(t, runner.Frame())
+	require.Equal(t, runner.Machine().Frame(), display.frames[2])
+}
+
+func TestRunner_Paused(t *testing.T) {
+	runner, display, input, audio := newTestRunner(t)
+	runner.SetPaused(true)
+	require.True(t, runner.Paused())
+	input.keys[0x1] = true
+
+	require.NoError(t, runner.Frame())
+	machine := runner.Machine()
+	require.Equal(t, uint16(0x200), machine.ProgramCounter())
+	require.True(t, machine.IsKeyPressed(0x1), "input is polled while paused")
+	require.Len(t, display.frames, 1, "frames are presented while paused")
+	require.Equal(t, []bool{false}, audio.tones)
+
+	runner.SetPaused(false)
+	require.NoError(t, runner.Frame())
+	require.NotEqual(t, uint16(0x200), machine.ProgramCounter())
+}
+
+func TestRunner_PausedByHook(t *testing.T) {
+	runner, display, _, audio := newTestRunner(t)
+	machine := runner.Machine()
+	machine.SetHooks(chip8.Hooks{Fetch: func(pc uint16, op chip8.Opcode) bool {
+		if pc == 0x206 {
+			runner.SetPaused(true)
+			return false
+		}
+		return true
+	}})
+
+	// The rest of the frame is skipped, timers included
+	require.NoError(t, runner.Frame())
+	require.Equal(t, uint16(0x206), machine.ProgramCounter())
+	require.Equal(t, byte(2), machine.SoundTimer())
+	require.Len(t, display.frames, 1)
+	require.Equal(t, []bool{false}, audio.tones)
+}
+
+func TestRunner_Speed(t *testing.T) {
+	runner, _, _, _ := newTestRunner(t)
+	machine := runner.Machine()
+	machine.SetSpeed(700)
+	steps := 0
+	machine.SetHooks(chip8.Hooks{Execute: func(pc uint16, op chip8.Opcode) { steps++ }})
+
+	// A second of frames runs exactly the speed, not 60 times 700/60
+	for i := 0; i < FrameRate; i++ {
+		require.NoError(t, runner.Frame())
+	}
+	require.Equal(t, 700, steps)
+}
+
+func TestRunner_Halted(t *testing.T) {
+	runner, display, _, _ := newTestRunner(t)
+	runner.Machine().PC = chip8.RAM - 1
+
+	require.ErrorIs(t, runner.Frame(), ErrHalted)
+	require.Empty(t, display.frames)
+}
+
+func TestRunner_SetMachine(t *testing.T) {
+	runner, _, _, _ := newTestRunner(t)
+	machine, err := NewMachine([]byte{0x60, 0x42}, chip8.Options{Speed: 60})
+	require.NoError(t, err)
+
+	runner.SetMachine(machine)
+	require.NoError(t, runner.Frame())
+	require.Equal(t, uint8(0x42), machine.Registers()[0])
+}
+
+func TestRunner_Run(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	// Stops on input errors
+	runner, display, input, _ := newTestRunner(t)
+	input.err = ErrQuit
+	require.ErrorIs(t, runner.Run(context.Background(), Unthrottled{}), ErrQuit)
+	require.Empty(t, display.frames)
+
+	// And when the context is done
+	runner, display, _, _ = newTestRunner(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := runner.Run(ctx, NewRealtimeClock(FrameRate))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// About 6 frames at 60 Hz, allowing for a slow machine
+	require.GreaterOrEqual(t, len(display.frames), 2)
+	require.LessOrEqual(t, len(display.frames), 7)
+}
+
+func TestRealtimeClock(t *testing.T) {
+	defer goleak.VerifyNone(t, ignoreJSEvents)
+
+	clock := NewRealtimeClock(100)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, clock.Tick(context.Background()))
+	}
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, clock.Tick(ctx), context.Canceled)
+	require.ErrorIs(t, Unthrottled{}.Tick(ctx), context.Canceled)
+}
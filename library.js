@@ -0,0 +1,109 @@
+// The page's ROM library, kept in IndexedDB so uploaded ROMs, their settings
+// and save states survive reloads. ROMs are keyed by the SHA-1 of their bytes,
+// adding the same file again renames it instead of storing a copy.
+//
+// Records look like:
+//
+//   {hash, name, data: Uint8Array, added: Date, lastPlayed: Date | null,
+//    settings: object | null, saveStates: [{saved: Date, state: Uint8Array}]}
+//
+// settings are whatever the page wants restored with the ROM, save states are
+// chip8.saveState() results, newest first.
+const romLibrary = (() => {
+    const DB_NAME = 'chip8';
+    const DB_VERSION = 1;
+    const STORE = 'roms';
+    const MAX_SAVE_STATES = 10;
+
+    let db = null;
+
+    function promisify(request) {
+        return new Promise((resolve, reject) => {
+            request.onsuccess = () => resolve(request.result);
+            request.onerror = () => reject(request.error);
+        });
+    }
+
+    async function open() {
+        if (!db) {
+            const request = indexedDB.open(DB_NAME, DB_VERSION);
+            request.onupgradeneeded = () => {
+                request.result.createObjectStore(STORE, { keyPath: 'hash' });
+            };
+            db = await promisify(request);
+        }
+        return db;
+    }
+
+    async function store(mode) {
+        return (await open()).transaction(STORE, mode).objectStore(STORE);
+    }
+
+    // Reads, changes and writes a record in one transaction
+    async function update(hash, change) {
+        const roms = await store('readwrite');
+        return new Promise((resolve, reject) => {
+            const get = roms.get(hash);
+            get.onsuccess = () => {
+                if (!get.result) {
+                    reject(new Error('ROM is not in the library'));
+                    return;
+                }
+                change(get.result);
+                promisify(roms.put(get.result)).then(() => resolve(get.result), reject);
+            };
+            get.onerror = () => reject(get.error);
+        });
+    }
+
+    async function sha1(data) {
+        const digest = await crypto.subtle.digest('SHA-1', data);
+        return [...new Uint8Array(digest)].map(b => b.toString(16).padStart(2, '0')).join('');
+    }
+
+    // Adds a ROM, or renames it if the same bytes are already in the library
+    async function add(name, data) {
+        const hash = await sha1(data);
+        const existing = await get(hash);
+        if (existing) {
+            return update(hash, rom => { rom.name = name; });
+        }
+
+        const rom = { hash, name, data, added: new Date(), lastPlayed: null, settings: null, saveStates: [] };
+        await promisify((await store('readwrite')).add(rom));
+        return rom;
+    }
+
+    async function get(hash) {
+        return promisify((await store('readonly')).get(hash));
+    }
+
+    // Most recently played first, then most recently added
+    async function list() {
+        const roms = await promisify((await store('readonly')).getAll());
+        const time = date => (date ? date.getTime() : 0);
+        return roms.sort((a, b) => time(b.lastPlayed) - time(a.lastPlayed) || time(b.added) - time(a.added));
+    }
+
+    async function remove(hash) {
+        return promisify((await store('readwrite')).delete(hash));
+    }
+
+    function markPlayed(hash) {
+        return update(hash, rom => { rom.lastPlayed = new Date(); });
+    }
+
+    function saveSettings(hash, settings) {
+        return update(hash, rom => { rom.settings = settings; });
+    }
+
+    // Keeps the newest MAX_SAVE_STATES states
+    function addSaveState(hash, state) {
+        return update(hash, rom => {
+            rom.saveStates.unshift({ saved: new Date(), state });
+            rom.saveStates.length = Math.min(rom.saveStates.length, MAX_SAVE_STATES);
+        });
+    }
+
+    return { add, get, list, remove, markPlayed, saveSettings, addSaveState };
+})();
@@ -3,12 +3,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
 
+	_ "github.com/yuvrajchettri/chip-8-emulator/bundle" // Registers the format of the embedded ROMs
 	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/spectate"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -33,31 +41,45 @@ var keyMap = map[uint8]sdl.Scancode{
 }
 
 func main() {
-	// Default ROM filename
-	romName := DefaultROM
-
-	// If a filename is passed as an argument, use it
-	if len(os.Args) > 1 {
-		romName = os.Args[1]
+	// Errors are printed along with the usage by parseOptions
+	opts, err := parseOptions(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
 	}
 
-	// Validate ROM name
-	isValid := false
-	for _, validROM := range ValidROMs {
-		if romName == validROM {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
-		fmt.Printf("Invalid ROM name. Available ROMs: %v\n", ValidROMs)
+	romBytes, err := namedROM(opts.romName)
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// Get ROM bytes
-	romBytes, err := GetROMBytes(romName)
+	// The ROM database recommends a speed, quirks and colours for known
+	// ROMs, over the ones the embedded ROMs' bundles carry
+	db, err := romdb.LoadUser()
 	if err != nil {
-		log.Fatalf("Failed to read ROM: %v", err)
+		log.Printf("Ignoring ROM database overrides: %v", err)
+	}
+
+	// Create a new chip-8 instance with the ROM loaded
+	emulator, entry, err := db.NewMachine(romBytes, chip8.Options{
+		Speed:       chip8.DEFAULT_SPEED,
+		PresentMode: opts.presentMode,
+		FadeFrames:  opts.fadeFrames,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load ROM: %v", err)
+	}
+
+	cart, _ := emulator.Cartridge()
+	settings := entry.Settings(cart)
+	title, palette := settings.Title, settings.Palette
+	if !opts.paletteSet && palette != "" {
+		if palette, err := render.ParsePalette(palette); err == nil {
+			opts.palette = palette
+		}
+	}
+	if title != "" {
+		log.Printf("Running %s at %d Hz", title, emulator.Speed())
 	}
 
 	// Initialize SDL
@@ -66,114 +88,144 @@ func main() {
 	}
 	defer sdl.Quit()
 
-	// Create a window
-	var modifier = 10
-	window, windowErr := sdl.CreateWindow(
-		"Chip 8",
-		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(chip8.DISPLAY_COLS*modifier), int32(chip8.DISPLAY_ROWS*modifier),
-		sdl.WINDOW_SHOWN,
-	)
-	if windowErr != nil {
-		panic(windowErr)
+	// Create the window and everything needed to draw in it
+	video, err := newVideo(opts)
+	if err != nil {
+		log.Fatalf("Failed to create window: %v", err)
 	}
-	defer window.Destroy()
-
-	// Create render surface - canvas
-	canvas, canvasErr := sdl.CreateRenderer(window, -1, 0)
-	if canvasErr != nil {
-		panic(canvasErr)
+	defer video.destroy()
+
+	// The window, keyboard and timers are updated once per 60 Hz frame
+	frontend := &sdlFrontend{emulator: emulator, video: video}
+	var display host.Display = frontend
+	if opts.spectate != "" {
+		hub := spectate.NewHub(spectate.Info{Title: title, Palette: render.Hex(opts.palette.Background) + "," + render.Hex(opts.palette.Foreground)})
+		display = hub.Display(frontend, func() *chip8.Chip8 { return emulator })
+		if stop, err := spectate.Serve(opts.spectate, hub); err != nil {
+			log.Printf("Not streaming to spectators: %v", err)
+		} else {
+			defer stop()
+			log.Printf("Spectators can watch at ws://%s/spectate, see spectate.html", opts.spectate)
+		}
 	}
-	defer canvas.Destroy()
-
-	// Create a new chip-8 instance
-	emulator := chip8.NewChip8(false, false, 700)
-
-	// Load ROM bytes
-	if err := emulator.LoadBytes(romBytes); err != nil {
-		log.Fatalf("Failed to load ROM: %v", err)
+	runner := host.NewRunner(emulator, display, frontend, frontend)
+	err = runner.Run(context.Background(), host.NewRealtimeClock(host.FrameRate))
+	if !errors.Is(err, host.ErrQuit) {
+		// Not log.Fatal, the window still has to be destroyed
+		log.Printf("Stopped: %v", err)
 	}
-
-	// Set PC to start of ROM
-	emulator.PC = 0x200
-
-	loop(emulator, canvas, int32(modifier))
 }
 
 // ------------------------------------------------
-// Loop for fetch-decode-execute cycle
+// The SDL front-end: the host.Display, host.Input and host.AudioSink the
+// Runner drives
 // ------------------------------------------------
-func loop(emulator *chip8.Chip8, canvas *sdl.Renderer, modifier int32) {
-	emulator.Initialize()
-
-	go updateKeyboardState(emulator)
-
-	speedHz := emulator.Speed()
-	instructionDelay := time.Second / time.Duration(speedHz)
-	ticker := time.NewTicker(instructionDelay)
-	defer ticker.Stop()
+type sdlFrontend struct {
+	emulator *chip8.Chip8
+	video    *video
+	recorder *render.Recorder // Active GIF recording, nil if not recording
+	frame    [][]int          // Last frame presented, what the window shows
+}
 
-	for emulator.ProgramCounter() < chip8.RAM {
-		// Pump events to update keyboard state only from main thread
-		sdl.PumpEvents()
+// Pumps the events from the main thread and reads the keyboard state SDL
+// keeps up to date while doing so
+func (f *sdlFrontend) Poll() ([16]bool, error) {
+	var keys [16]bool
+	if err := f.handleEvents(); err != nil {
+		return keys, err
+	}
 
-		// Render display if redraw is true
-		if emulator.ShouldRedraw() {
-			emulator.ResetRedraw()
-			renderDisplay(emulator, canvas, modifier)
-		}
+	state := sdl.GetKeyboardState()
+	for chip8Key, scancode := range keyMap {
+		keys[chip8Key] = state[scancode] != 0
+	}
+	return keys, nil
+}
 
-		// Main instruction loop
-		select {
-		case <-ticker.C:
-			instruction := emulator.Fetch()
-			emulator.NextInstruction()
+// Renders the frame if it changed or pixels are still fading
+func (f *sdlFrontend) Present(frame [][]int, changed bool) error {
+	f.frame = frame
+	fading := f.video.screen.Fade(frame)
+	if changed || fading || f.video.dirty {
+		f.video.present(frame)
+	}
 
-			emulator.ExecuteInstruction(instruction)
-		}
+	if f.recorder != nil {
+		f.recorder.AddFrame(frame)
 	}
+	return nil
 }
 
-func renderDisplay(emulator *chip8.Chip8, canvas *sdl.Renderer, modifier int32) {
-	canvas.SetDrawColor(255, 0, 0, 255)
-	canvas.Clear()
-
-	// Get the display buffer and render
-	vector := emulator.GetDisplay()
-	for j := 0; j < len(vector); j++ {
-		for i := 0; i < len(vector[j]); i++ {
-			// Values of pixel are stored in 1D array of size 64 * 32
-			if vector[j][i] != 0 {
-				canvas.SetDrawColor(255, 255, 0, 255)
-			} else {
-				canvas.SetDrawColor(255, 0, 0, 255)
-			}
-			canvas.FillRect(&sdl.Rect{
-				Y: int32(j) * modifier,
-				X: int32(i) * modifier,
-				W: modifier,
-				H: modifier,
-			})
-		}
+func (f *sdlFrontend) SetTone(on bool) {
+	if on {
+		fmt.Print("\a") // ASCII Bell character - make computer beep as long as > 0
 	}
-
-	canvas.Present()
 }
 
-func updateKeyboardState(emulator *chip8.Chip8) {
-	ticker := time.NewTicker(time.Millisecond * 16) // ~60Hz refresh rate
-	defer ticker.Stop()
+// ------------------------------------------------
+// Drains the SDL event queue and handles the hotkeys:
+// F12 saves a PNG screenshot, F11 starts/stops a GIF recording,
+// Alt+Enter toggles fullscreen.
+// Returns host.ErrQuit when the window is closed.
+// ------------------------------------------------
+func (f *sdlFrontend) handleEvents() error {
+	video := f.video
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch event := event.(type) {
+		case *sdl.QuitEvent:
+			return host.ErrQuit
+
+		case *sdl.WindowEvent:
+			if event.Event == sdl.WINDOWEVENT_SIZE_CHANGED || event.Event == sdl.WINDOWEVENT_EXPOSED {
+				video.dirty = true
+			}
 
-	for {
-		select {
-		case <-ticker.C:
-			keys := sdl.GetKeyboardState()
+		case *sdl.KeyboardEvent:
+			if event.Type != sdl.KEYDOWN || event.Repeat != 0 {
+				continue
+			}
 
-			// Update internal keyboard state
-			for chip8Key, scancode := range keyMap {
-				state := keys[scancode] != 0
-				emulator.UpdateKeyboardState(chip8Key, state)
+			switch {
+			case event.Keysym.Sym == sdl.K_F12:
+				// The presented frame, as recordings have, not the display
+				frame := f.frame
+				if frame == nil {
+					frame = f.emulator.GetDisplay()
+				}
+				saveCapture("png", func(w io.Writer) error {
+					return render.WritePNG(w, frame, video.screen.Options())
+				})
+
+			case event.Keysym.Sym == sdl.K_F11:
+				if f.recorder == nil {
+					log.Println("Recording started, press F11 again to stop")
+					f.recorder = render.NewRecorder(video.screen.Options())
+				} else {
+					saveCapture("gif", f.recorder.Encode)
+					f.recorder = nil
+				}
+
+			case event.Keysym.Sym == sdl.K_RETURN && event.Keysym.Mod&sdl.KMOD_ALT != 0:
+				video.toggleFullscreen()
 			}
 		}
 	}
+	return nil
+}
+
+// Writes a capture to a timestamped file in the working directory
+func saveCapture(ext string, encode func(w io.Writer) error) {
+	name := fmt.Sprintf("chip8-%s.%s", time.Now().Format("20060102-150405"), ext)
+	file, err := os.Create(name)
+	if err != nil {
+		log.Printf("Failed to save %s: %v", name, err)
+		return
+	}
+	defer file.Close()
+
+	if err := encode(file); err != nil {
+		log.Printf("Failed to save %s: %v", name, err)
+		return
+	}
+	log.Printf("Saved %s", name)
 }
@@ -3,12 +3,15 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"syscall/js"
 
-	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	_ "github.com/yuvrajchettri/chip-8-emulator/bundle" // Registers the format of the embedded ROMs
+	_ "github.com/yuvrajchettri/chip-8-emulator/cart"   // Registers Octo-style cartridges
+	"github.com/yuvrajchettri/chip-8-emulator/render"
 )
 
 var keyMap = map[string]uint8{
@@ -19,58 +22,49 @@ var keyMap = map[string]uint8{
 }
 
 var (
-	ctx         js.Value
 	keyStates   = make(map[uint8]bool)
 	stopChannel = make(chan bool, 1)
 	isRunning   = false
+
+	animationFrame js.Value // ID of the loop's pending animation frame
 )
 
 func main() {
-	// Get the canvas context from JavaScript
-	doc := js.Global().Get("document")
-	canvas := doc.Call("getElementById", "chip8-canvas")
-	ctx = canvas.Call("getContext", "2d")
-
-	// Default ROM filename
-	romName := DefaultROM
-
-	// If a filename is passed as an argument, use it
-	if len(os.Args) > 1 {
-		romName = os.Args[1]
+	// Get the canvas context from JavaScript, in a worker the canvas is an OffscreenCanvas
+	var canvas js.Value
+	if isWorker() {
+		canvas = workerCanvas()
+	} else {
+		canvas = js.Global().Get("document").Call("getElementById", "chip8-canvas")
 	}
+	ctx := canvas.Call("getContext", "2d")
 
-	// Validate ROM name
-	isValid := false
-	for _, validROM := range ValidROMs {
-		if romName == validROM {
-			isValid = true
-			break
-		}
-	}
-	if !isValid {
-		fmt.Printf("Invalid ROM name. Available ROMs: %v\n", ValidROMs)
-		os.Exit(1)
+	// TinyGo's wasm_exec_tiny.js passes no arguments at all, not even the
+	// program name, the page sets options and the ROM once it's running
+	args := os.Args
+	if len(args) > 0 {
+		args = args[1:]
 	}
 
-	// Get ROM bytes
-	romBytes, err := GetROMBytes(romName)
+	// Errors are printed along with the usage by parseOptions
+	opts, err := parseOptions(args)
 	if err != nil {
-		log.Fatalf("Failed to read ROM: %v", err)
+		os.Exit(2)
 	}
 
-	// Create a new chip-8 instance
-	emulator := chip8.NewChip8(false, false, 1400)
-
-	// Load ROM bytes
-	if err := emulator.LoadBytes(romBytes); err != nil {
-		log.Fatalf("Failed to load ROM: %v", err)
+	romBytes, err := namedROM(opts.romName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 
-	// Set PC to start of ROM
-	emulator.PC = 0x200
-
-	// Setup keyboard event listeners
-	setupKeyboardHandlers()
+	// The session creates the chip-8 instance and swaps it when JavaScript loads another ROM
+	renderer := newCanvasRenderer(canvas, ctx, opts)
+	session := newSession(renderer, opts, 1400)
+	if err := session.load(romBytes); err != nil {
+		fmt.Printf("Failed to load ROM: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Expose stop function to JavaScript
 	js.Global().Set("stopEmulator", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
@@ -78,8 +72,33 @@ func main() {
 		return nil
 	}))
 
+	// Expose screenshot function to JavaScript, returns the PNG bytes as a Uint8Array
+	js.Global().Set("screenshotPNG", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return screenshotPNG(session)
+	}))
+
+	// Expose display options to JavaScript so colours and effects can change while running
+	js.Global().Set("setDisplayOptions", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return "expected an options object"
+		}
+		return setDisplayOptions(session, args[0])
+	}))
+
+	// Expose the chip8 control API, see api_wasm.go
+	exportAPI(session)
+
+	// Setup keyboard event listeners, a worker gets key events from the page instead
+	if isWorker() {
+		setupWorkerMessages(session)
+		postMessage("roms", map[string]interface{}{"roms": romCatalogue()})
+		postMessage("running", map[string]interface{}{"rom": session.romInfo()})
+	} else {
+		setupKeyboardHandlers()
+	}
+
 	// Start the emulation loop
-	loop(emulator, 10) // modifier of 10 like in SDL version
+	loop(session)
 
 	// Wait for stop signal instead of blocking indefinitely
 	<-stopChannel
@@ -88,6 +107,7 @@ func main() {
 func stopEmulator() {
 	if isRunning {
 		isRunning = false
+		js.Global().Call("cancelAnimationFrame", animationFrame)
 		select {
 		case stopChannel <- true:
 		default:
@@ -95,26 +115,74 @@ func stopEmulator() {
 	}
 }
 
-func renderDisplay(emulator *chip8.Chip8, modifier int32) {
-	// Clear the canvas
-	ctx.Set("fillStyle", "#FF0000") // Red background
-	ctx.Call("fillRect", 0, 0, chip8.DISPLAY_COLS*modifier, chip8.DISPLAY_ROWS*modifier)
-
-	// Get the display buffer and render
-	vector := emulator.GetDisplay()
-	ctx.Set("fillStyle", "#FFFF00") // Yellow pixels
-
-	for j := 0; j < len(vector); j++ {
-		for i := 0; i < len(vector[j]); i++ {
-			if vector[j][i] != 0 {
-				ctx.Call("fillRect",
-					i*int(modifier), // x
-					j*int(modifier), // y
-					int(modifier),   // width
-					int(modifier))   // height
-			}
-		}
+// Screenshots show the frame on the canvas, which in the blend and fade
+// present modes is not the display
+func screenshotPNG(session *session) interface{} {
+	frame := session.renderer.frame
+	if frame == nil {
+		frame = session.emulator().GetDisplay()
+	}
+	// Screenshots are saved at the displayed size, not the raster size
+	opts := session.renderer.screen.Options()
+	opts.Scale = cssScale
+
+	var buf bytes.Buffer
+	if err := render.WritePNG(&buf, frame, opts); err != nil {
+		return nil
 	}
+
+	return bytesToJS(buf.Bytes())
+}
+
+// ------------------------------------------------
+// Applies {palette, persistence, grid, scanlines, present, fadeFrames} from a
+// JavaScript object. Returns an error message, or null on success.
+// ------------------------------------------------
+func setDisplayOptions(session *session, jsOpts js.Value) interface{} {
+	if jsOpts.Type() != js.TypeObject {
+		return "expected an options object"
+	}
+
+	// Missing options keep their current value
+	screen := session.renderer.screen
+	current := screen.Options().Palette
+	palette := render.Hex(current.Background) + "," + render.Hex(current.Foreground)
+	effects := screen.Effects()
+	present, fadeFrames := session.presentMode.String(), session.fadeFrames
+	err := errors.Join(
+		jsOption(jsOpts, "palette", js.TypeString, func(v js.Value) { palette = v.String() }),
+		jsOption(jsOpts, "persistence", js.TypeNumber, func(v js.Value) { effects.Persistence = v.Float() }),
+		jsOption(jsOpts, "grid", js.TypeBoolean, func(v js.Value) { effects.Grid = v.Bool() }),
+		jsOption(jsOpts, "scanlines", js.TypeBoolean, func(v js.Value) { effects.Scanlines = v.Bool() }),
+		jsOption(jsOpts, "present", js.TypeString, func(v js.Value) { present = v.String() }),
+		jsOption(jsOpts, "fadeFrames", js.TypeNumber, func(v js.Value) { fadeFrames = v.Int() }),
+	)
+	if err != nil {
+		return err.Error()
+	}
+
+	opts, err := newDisplayOptions(palette, effects.Persistence, effects.Grid, effects.Scanlines, present, fadeFrames)
+	if err != nil {
+		return err.Error()
+	}
+
+	session.setPresentMode(opts.presentMode, opts.fadeFrames)
+	session.renderer.setOptions(opts)
+	return nil
+}
+
+// Passes the option name to set if it has type t, undefined and null leave
+// it unset
+func jsOption(opts js.Value, name string, t js.Type, set func(v js.Value)) error {
+	v := opts.Get(name)
+	switch v.Type() {
+	case js.TypeUndefined, js.TypeNull:
+		return nil
+	case t:
+		set(v)
+		return nil
+	}
+	return fmt.Errorf("%s must be a %s, got %s", name, t, v.Type())
 }
 
 func setupKeyboardHandlers() {
@@ -143,23 +211,9 @@ func setupKeyboardHandlers() {
 	js.Global().Get("document").Call("addEventListener", "keyup", keyupHandler)
 }
 
-func updateKeyboardState(emulator *chip8.Chip8) {
-	// Update the emulator's keyboard state based on our keyStates map
-	for chip8Key, isPressed := range keyStates {
-		emulator.UpdateKeyboardState(chip8Key, isPressed)
-	}
-}
-
-func loop(emulator *chip8.Chip8, modifier int32) {
-	emulator.Initialize()
+func loop(session *session) {
 	isRunning = true
 
-	// Rendering loop runs at 60 FPS because of requestAnimationFrame.
-	// This means you have 60 "slots" per second to both run the CPU and update the display.
-	// This means for each of those 60 frames, you execute ~12 CHIP-8 instructions, so that by the end of 1 second:
-	// 12 instructions/frame * 60 frames/second = 720 instructions/second ~ nearly the correct number of instructions
-	instrPerFrame := emulator.Speed() / 60 // e.g. 700/60 ≈ 12
-
 	var renderFrame js.Func
 	renderFrame = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		// Check if we should stop
@@ -167,27 +221,13 @@ func loop(emulator *chip8.Chip8, modifier int32) {
 			return nil
 		}
 
-		// Update keyboard state
-		updateKeyboardState(emulator)
-
-		// 1. Run CPU
-		for i := 0; i < instrPerFrame; i++ {
-			instr := emulator.Fetch()
-			emulator.NextInstruction()
-			emulator.ExecuteInstruction(instr)
-		}
-
-		// 2. If the VRAM changed, paint it
-		if emulator.ShouldRedraw() {
-			emulator.ResetRedraw()
-			renderDisplay(emulator, modifier)
-		}
+		session.frame()
 
 		// Continue the loop only if still running
 		if isRunning {
-			js.Global().Call("requestAnimationFrame", renderFrame)
+			animationFrame = js.Global().Call("requestAnimationFrame", renderFrame)
 		}
 		return nil
 	})
-	js.Global().Call("requestAnimationFrame", renderFrame)
+	animationFrame = js.Global().Call("requestAnimationFrame", renderFrame)
 }
@@ -0,0 +1,348 @@
+// Package netplay runs two emulators of the same ROM in deterministic
+// lockstep over TCP, so two players can share one keypad from different
+// machines: PONG's left paddle is 1/4 and its right one C/D.
+//
+// Each player's machine runs every frame with the keys both players held in
+// it. A player's keys apply InputDelay frames after they were pressed, which
+// gives them that many frames to reach the other player, so the game only
+// waits when the connection is slower than that. Along with its keys each
+// player sends a hash of its machine's state, and a Session fails with
+// ErrDesync as soon as the machines differ.
+//
+// The host sends the settings, both players then reset their machines:
+//
+//	hello  "C8NP" version uint8, inputDelay uint8, speed uint32, quirks uint8
+//	       (shift 1, jump 2), seed int64, SHA-1 of the ROM [20]byte
+//	frame  frame uint32, keys uint16, hash uint64
+//
+// A frame message carries the keys for frame+inputDelay, bit k set while
+// key k is held, and the FNV-1a hash of the machine's save state before
+// frame. Everything is big endian.
+package netplay
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+const (
+	magic   = "C8NP"
+	version = 1
+
+	helloSize = len(magic) + 1 + 1 + 4 + 1 + 8 + sha1.Size
+	frameSize = 4 + 2 + 8
+
+	DefaultInputDelay = 2 // Frames, hides about 30 ms of latency
+	DefaultTimeout    = 10 * time.Second
+	MaxInputDelay     = 30
+)
+
+var (
+	// ErrDesync is returned once the machines have run differently
+	ErrDesync = errors.New("netplay: the machines are out of sync")
+
+	// ErrPeerLeft is returned when the other player closed the connection
+	ErrPeerLeft = errors.New("netplay: the other player left")
+)
+
+// Options are the host's, the joining player takes them over
+type Options struct {
+	InputDelay int           // Frames, 0 for strict lockstep, DefaultInputDelay if negative
+	Timeout    time.Duration // How long to wait for the other player's keys, DefaultTimeout if 0
+}
+
+// Session is one player's end of a game, its Input runs the player's machine
+type Session struct {
+	conn     net.Conn
+	machine  *chip8.Chip8
+	delay    int
+	timeout  time.Duration
+	frame    int // Next frame to run
+	received int // Next frame message expected from the other player
+
+	localKeys   map[int]uint16 // Keys by the frame they apply in
+	remoteKeys  map[int]uint16
+	localHashes map[int]uint64 // By frame, until the other player's hash arrives
+	buf         [frameSize]byte
+}
+
+// ------------------------------------------------
+// Host starts a game on conn as the first player: the other player gets
+// machine's speed and quirks and the input delay of opts. Both machines
+// are reset and have to run the same ROM.
+// ------------------------------------------------
+func Host(conn net.Conn, machine *chip8.Chip8, opts Options) (*Session, error) {
+	if opts.InputDelay < 0 {
+		opts.InputDelay = DefaultInputDelay
+	}
+	if opts.InputDelay > MaxInputDelay {
+		return nil, fmt.Errorf("netplay: the input delay must be from 0 to %d frames", MaxInputDelay)
+	}
+
+	ours := hello{
+		delay:  opts.InputDelay,
+		speed:  machine.Speed(),
+		quirks: machine.Quirks(),
+		seed:   rand.Int63(),
+		rom:    sha1.Sum(machine.ROM()),
+	}
+	theirs, err := exchangeHellos(conn, ours, opts)
+	if err != nil {
+		return nil, err
+	}
+	if theirs.rom != ours.rom {
+		return nil, errors.New("netplay: the other player runs a different ROM")
+	}
+	return newSession(conn, machine, ours, opts), nil
+}
+
+// Join joins the game of the host at the other end of conn as the second
+// player, taking over its settings. opts.InputDelay is ignored.
+func Join(conn net.Conn, machine *chip8.Chip8, opts Options) (*Session, error) {
+	ours := hello{speed: machine.Speed(), quirks: machine.Quirks(), rom: sha1.Sum(machine.ROM())}
+	theirs, err := exchangeHellos(conn, ours, opts)
+	if err != nil {
+		return nil, err
+	}
+	if theirs.rom != ours.rom {
+		return nil, errors.New("netplay: the host runs a different ROM")
+	}
+
+	machine.SetSpeed(theirs.speed)
+	machine.SetQuirks(theirs.quirks)
+	return newSession(conn, machine, theirs, opts), nil
+}
+
+func newSession(conn net.Conn, machine *chip8.Chip8, settings hello, opts Options) *Session {
+	machine.Reset()
+	machine.SetSeed(settings.seed)
+
+	s := &Session{
+		conn:        conn,
+		machine:     machine,
+		delay:       settings.delay,
+		timeout:     opts.Timeout,
+		localKeys:   make(map[int]uint16),
+		remoteKeys:  make(map[int]uint16),
+		localHashes: make(map[int]uint64),
+	}
+	if s.timeout == 0 {
+		s.timeout = DefaultTimeout
+	}
+	// Nobody pressed anything before the game
+	for frame := 0; frame < s.delay; frame++ {
+		s.localKeys[frame] = 0
+		s.remoteKeys[frame] = 0
+	}
+	return s
+}
+
+// ------------------------------------------------
+// Hellos
+// ------------------------------------------------
+
+type hello struct {
+	delay  int
+	speed  int
+	quirks chip8.Quirks
+	seed   int64
+	rom    [sha1.Size]byte
+}
+
+// Both players send their hello first, so neither waits on the other
+func exchangeHellos(conn net.Conn, ours hello, opts Options) (hello, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	data := make([]byte, 0, helloSize)
+	data = append(data, magic...)
+	data = append(data, version, byte(ours.delay))
+	data = binary.BigEndian.AppendUint32(data, uint32(ours.speed))
+	var quirks byte
+	if ours.quirks.Shift {
+		quirks |= 1
+	}
+	if ours.quirks.Jump {
+		quirks |= 2
+	}
+	data = append(data, quirks)
+	data = binary.BigEndian.AppendUint64(data, uint64(ours.seed))
+	data = append(data, ours.rom[:]...)
+	if _, err := conn.Write(data); err != nil {
+		return hello{}, err
+	}
+
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return hello{}, fmt.Errorf("netplay: reading the other player's hello: %w", err)
+	}
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return hello{}, errors.New("netplay: the other end is not a CHIP-8 netplay session")
+	}
+	if data[4] != version {
+		return hello{}, fmt.Errorf("netplay: unsupported version %d", data[4])
+	}
+	theirs := hello{
+		delay:  int(data[5]),
+		speed:  int(binary.BigEndian.Uint32(data[6:])),
+		quirks: chip8.Quirks{Shift: data[10]&1 != 0, Jump: data[10]&2 != 0},
+		seed:   int64(binary.BigEndian.Uint64(data[11:])),
+	}
+	copy(theirs.rom[:], data[19:])
+	if theirs.delay > MaxInputDelay {
+		return hello{}, fmt.Errorf("netplay: the input delay must be from 0 to %d frames", MaxInputDelay)
+	}
+	return theirs, nil
+}
+
+// ------------------------------------------------
+// Frames
+// ------------------------------------------------
+
+// Exchange sends the keys the player holds and returns the keys to run the
+// next frame with: both players' keys from InputDelay frames ago. It
+// waits for the other player's keys if they haven't arrived yet.
+func (s *Session) Exchange(local [16]bool) ([16]bool, error) {
+	frame := s.frame
+	hash := s.hash()
+	s.localKeys[frame+s.delay] = keyMask(local)
+	s.localHashes[frame] = hash
+
+	msg := s.buf[:]
+	binary.BigEndian.PutUint32(msg, uint32(frame))
+	binary.BigEndian.PutUint16(msg[4:], s.localKeys[frame+s.delay])
+	binary.BigEndian.PutUint64(msg[6:], hash)
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		return [16]bool{}, s.connError(err)
+	}
+
+	// Messages arrive in order, the one for this frame was sent InputDelay frames ago
+	for {
+		if _, ok := s.remoteKeys[frame]; ok {
+			break
+		}
+		if err := s.receive(); err != nil {
+			return [16]bool{}, err
+		}
+	}
+
+	keys := s.localKeys[frame] | s.remoteKeys[frame]
+	delete(s.localKeys, frame)
+	delete(s.remoteKeys, frame)
+	s.frame++
+	return keyStates(keys), nil
+}
+
+// Reads a frame message and checks the other player's hash against ours
+func (s *Session) receive() error {
+	msg := s.buf[:]
+	s.conn.SetReadDeadline(time.Now().Add(s.timeout))
+	if _, err := io.ReadFull(s.conn, msg); err != nil {
+		return s.connError(err)
+	}
+	frame := int(binary.BigEndian.Uint32(msg))
+	if frame != s.received {
+		return fmt.Errorf("netplay: the other player sent frame %d instead of %d", frame, s.received)
+	}
+	s.received++
+	s.remoteKeys[frame+s.delay] = binary.BigEndian.Uint16(msg[4:])
+
+	hash, ok := s.localHashes[frame]
+	delete(s.localHashes, frame)
+	if ok && hash != binary.BigEndian.Uint64(msg[6:]) {
+		return fmt.Errorf("%w from frame %d", ErrDesync, frame)
+	}
+	return nil
+}
+
+func (s *Session) connError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return ErrPeerLeft
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("netplay: the other player hasn't answered for %v", s.timeout)
+	}
+	return fmt.Errorf("netplay: %w", err)
+}
+
+// FNV-1a of the save state
+func (s *Session) hash() uint64 {
+	state, _ := s.machine.MarshalBinary()
+	h := fnv.New64a()
+	h.Write(state)
+	return h.Sum64()
+}
+
+// Frame returns the number of frames run
+func (s *Session) Frame() int {
+	return s.frame
+}
+
+// InputDelay returns the frames keys take to apply
+func (s *Session) InputDelay() int {
+	return s.delay
+}
+
+// Close ends the game, the other player gets ErrPeerLeft
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+func keyMask(keys [16]bool) uint16 {
+	var mask uint16
+	for key, held := range keys {
+		if held {
+			mask |= 1 << key
+		}
+	}
+	return mask
+}
+
+func keyStates(mask uint16) [16]bool {
+	var keys [16]bool
+	for key := range keys {
+		keys[key] = mask&(1<<key) != 0
+	}
+	return keys
+}
+
+// ------------------------------------------------
+// Input returns the host.Input the player's Runner runs the session's
+// machine with, local reads the player's own keys. Local errors, e.g.
+// host.ErrQuit, end the game.
+// ------------------------------------------------
+func (s *Session) Input(local host.Input) host.Input {
+	return &sessionInput{session: s, local: local}
+}
+
+type sessionInput struct {
+	session *Session
+	local   host.Input
+}
+
+func (i *sessionInput) Poll() ([16]bool, error) {
+	keys, err := i.local.Poll()
+	if err != nil {
+		i.session.Close()
+		return keys, err
+	}
+	return i.session.Exchange(keys)
+}
@@ -0,0 +1,234 @@
+package netplay
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Checks the keys once per frame, counting the frames player 1 holds key 1
+// in V4 and the frames player 2 holds key C in V5, and draws random numbers
+// so unseeded machines would drift apart:
+//
+//	0x200: 6101  V1 = 0x1
+//	0x202: 620C  V2 = 0xC
+//	0x204: C3FF  V3 = random byte
+//	0x206: E1A1  skip the next instruction unless key V1 is held
+//	0x208: 7401  V4 += 1
+//	0x20A: E2A1  skip the next instruction unless key V2 is held
+//	0x20C: 7501  V5 += 1
+//	0x20E: 6001  V0 = 1
+//	0x210: F015  DT = V0
+//	0x212: F007  V0 = DT     wait for the next frame
+//	0x214: 3000  skip the next instruction if V0 == 0
+//	0x216: 1212  jump to 0x212
+//	0x218: 1204  jump to 0x204
+var countROM = []byte{
+	0x61, 0x01, 0x62, 0x0C, 0xC3, 0xFF, 0xE1, 0xA1, 0x74, 0x01, 0xE2, 0xA1, 0x75, 0x01,
+	0x60, 0x01, 0xF0, 0x15, 0xF0, 0x07, 0x30, 0x00, 0x12, 0x12, 0x12, 0x04,
+}
+
+// Holds keys during frames [from, to)
+type scriptedInput struct {
+	key      chip8.Key
+	from, to int
+	frame    int
+}
+
+func (i *scriptedInput) Poll() ([16]bool, error) {
+	var keys [16]bool
+	keys[i.key] = i.frame >= i.from && i.frame < i.to
+	i.frame++
+	return keys, nil
+}
+
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
+
+func newMachine(t *testing.T, rom []byte, speed int) *chip8.Chip8 {
+	t.Helper()
+	machine, err := host.NewMachine(rom, chip8.Options{Speed: speed})
+	require.NoError(t, err)
+	return machine
+}
+
+// Connects the players over loopback and starts the game
+func connect(t *testing.T, hostMachine, joinMachine *chip8.Chip8, opts Options) (*Session, *Session, error, error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type result struct {
+		session *Session
+		err     error
+	}
+	hosted := make(chan result, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			hosted <- result{nil, err}
+			return
+		}
+		session, err := Host(conn, hostMachine, opts)
+		if err != nil {
+			conn.Close()
+		}
+		hosted <- result{session, err}
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	joined, joinErr := Join(conn, joinMachine, opts)
+	if joinErr != nil {
+		conn.Close()
+	}
+	h := <-hosted
+	return h.session, joined, h.err, joinErr
+}
+
+// Runs frames on both sides at once and returns their errors
+func play(sessions [2]*Session, machines [2]*chip8.Chip8, inputs [2]host.Input, frames int) [2]error {
+	var errs [2]error
+	done := make(chan struct{})
+	for player := range sessions {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			runner := host.NewRunner(machines[player], discardDisplay{}, sessions[player].Input(inputs[player]), nil)
+			for frame := 0; frame < frames; frame++ {
+				if err := runner.Frame(); err != nil {
+					errs[player] = err
+					sessions[player].Close()
+					return
+				}
+			}
+		}()
+	}
+	<-done
+	<-done
+	return errs
+}
+
+func TestSession_Lockstep(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for _, delay := range []int{0, 1, 4} {
+		// The joining player runs at the host's speed
+		machines := [2]*chip8.Chip8{newMachine(t, countROM, 1200), newMachine(t, countROM, 600)}
+		hosting, joining, hostErr, joinErr := connect(t, machines[0], machines[1], Options{InputDelay: delay})
+		require.NoError(t, hostErr)
+		require.NoError(t, joinErr)
+		require.Equal(t, 1200, machines[1].Speed())
+		require.Equal(t, delay, joining.InputDelay())
+
+		inputs := [2]host.Input{
+			&scriptedInput{key: chip8.KEY_1, from: 10, to: 20},
+			&scriptedInput{key: chip8.KEY_C, from: 30, to: 35},
+		}
+		errs := play([2]*Session{hosting, joining}, machines, inputs, 60)
+		require.NoError(t, errs[0])
+		require.NoError(t, errs[1])
+
+		// Both machines saw both players' keys and ran alike
+		require.Equal(t, 60, hosting.Frame())
+		hostState, _ := machines[0].MarshalBinary()
+		joinState, _ := machines[1].MarshalBinary()
+		require.Equal(t, hostState, joinState)
+		require.Equal(t, uint8(10), machines[0].Registers()[4])
+		require.Equal(t, uint8(5), machines[0].Registers()[5])
+
+		hosting.Close()
+		joining.Close()
+	}
+}
+
+func TestSession_InputDelay(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	machines := [2]*chip8.Chip8{newMachine(t, countROM, 600), newMachine(t, countROM, 600)}
+	hosting, joining, _, _ := connect(t, machines[0], machines[1], Options{InputDelay: 3})
+	defer hosting.Close()
+	defer joining.Close()
+
+	// Keys pressed in frame 0 apply in frame 3
+	keys := [16]bool{chip8.KEY_1: true}
+	for frame := 0; frame < 4; frame++ {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := joining.Exchange([16]bool{})
+			errs <- err
+		}()
+		got, err := hosting.Exchange(keys)
+		require.NoError(t, err)
+		require.NoError(t, <-errs)
+		require.Equal(t, frame == 3, got[chip8.KEY_1], "frame %d", frame)
+		keys = [16]bool{}
+	}
+}
+
+func TestSession_Desync(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	machines := [2]*chip8.Chip8{newMachine(t, countROM, 600), newMachine(t, countROM, 600)}
+	hosting, joining, _, _ := connect(t, machines[0], machines[1], Options{})
+
+	// A different seed draws other random numbers
+	machines[1].SetSeed(1)
+	errs := play([2]*Session{hosting, joining}, machines, [2]host.Input{&scriptedInput{}, &scriptedInput{}}, 60)
+
+	desynced := 0
+	for _, err := range errs {
+		if errors.Is(err, ErrDesync) {
+			desynced++
+			require.Contains(t, err.Error(), "from frame 1")
+		} else {
+			require.ErrorIs(t, err, ErrPeerLeft)
+		}
+	}
+	require.NotZero(t, desynced)
+}
+
+func TestSession_DifferentROM(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	_, _, hostErr, joinErr := connect(t, newMachine(t, countROM, 600), newMachine(t, []byte{0x12, 0x00}, 600), Options{})
+	require.EqualError(t, hostErr, "netplay: the other player runs a different ROM")
+	require.EqualError(t, joinErr, "netplay: the host runs a different ROM")
+}
+
+func TestSession_PeerLeft(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	machines := [2]*chip8.Chip8{newMachine(t, countROM, 600), newMachine(t, countROM, 600)}
+	hosting, joining, _, _ := connect(t, machines[0], machines[1], Options{InputDelay: -1, Timeout: time.Second})
+	defer hosting.Close()
+	require.Equal(t, DefaultInputDelay, hosting.InputDelay())
+
+	// Quitting ends the game for both, once the keys sent before run out
+	joining.Close()
+	var err error
+	for frame := 0; err == nil && frame <= DefaultInputDelay; frame++ {
+		_, err = hosting.Exchange([16]bool{})
+	}
+	require.ErrorIs(t, err, ErrPeerLeft)
+}
+
+func TestSession_Timeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	machines := [2]*chip8.Chip8{newMachine(t, countROM, 600), newMachine(t, countROM, 600)}
+	hosting, joining, _, _ := connect(t, machines[0], machines[1], Options{InputDelay: 1, Timeout: 50 * time.Millisecond})
+	defer hosting.Close()
+	defer joining.Close()
+
+	// The first frame's keys are known, the second one's never arrive
+	_, err := hosting.Exchange([16]bool{})
+	require.NoError(t, err)
+	_, err = hosting.Exchange([16]bool{})
+	require.ErrorContains(t, err, "hasn't answered")
+}
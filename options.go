@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+)
+
+// ------------------------------------------------
+// Command line options shared by the SDL and WASM builds.
+// In the browser they are passed through go.argv.
+// ------------------------------------------------
+type options struct {
+	romName     string
+	palette     render.Palette
+	paletteSet  bool // -palette was given, so the ROM database's colours don't apply
+	effects     render.Effects
+	presentMode chip8.PresentMode
+	fadeFrames  int
+	scaleMode   render.ScaleMode // SDL window only
+	windowScale int              // SDL window only
+	fullscreen  bool             // SDL window only
+	spectate    string           // SDL build only, address to stream the session on, see package spectate
+}
+
+// Screen options for drawing the buffer the present mode is drawn from
+func (opts options) screenOptions(scale int) render.Options {
+	screenOpts := render.Options{Palette: opts.palette, Scale: scale}
+	if opts.presentMode != chip8.PRESENT_LIVE {
+		screenOpts.Levels = chip8.FRAME_MAX
+	}
+	return screenOpts
+}
+
+func parseOptions(args []string) (options, error) {
+	flags := flag.NewFlagSet("chip8", flag.ContinueOnError)
+	palette := flags.String("palette", "classic",
+		fmt.Sprintf("display colours, one of %s or BACKGROUND,FOREGROUND hex colours", strings.Join(render.PresetNames(), ", ")))
+	persistence := flags.Float64("persistence", 0, "phosphor persistence 0..1, ghosts fading pixels to reduce flicker")
+	grid := flags.Bool("grid", false, "draw a gap around every pixel")
+	scanlines := flags.Bool("scanlines", false, "draw a gap below every row of pixels")
+	present := flags.String("present", "live", "anti-flicker mode: live draws every change, blend ORs the last two frames, fade fades pixels out")
+	fadeFrames := flags.Int("fade-frames", 4, "number of frames a pixel takes to fade out with -present fade")
+	scaleMode := flags.String("scale-mode", "fit", "how the display fills the window: fit, integer or stretch")
+	windowScale := flags.Int("window-scale", 10, "initial window size in window pixels per CHIP-8 pixel")
+	fullscreen := flags.Bool("fullscreen", false, "start in fullscreen, Alt+Enter toggles it")
+	spectate := flags.String("spectate", "", "stream the session to spectate.html viewers on this address, e.g. :8081")
+	if err := flags.Parse(args); err != nil {
+		return options{}, err
+	}
+
+	// Report invalid values the same way the flag package reports parse errors
+	fail := func(err error) (options, error) {
+		fmt.Fprintln(flags.Output(), err)
+		flags.Usage()
+		return options{}, err
+	}
+
+	opts, err := newDisplayOptions(*palette, *persistence, *grid, *scanlines, *present, *fadeFrames)
+	if err != nil {
+		return fail(err)
+	}
+
+	if opts.scaleMode, err = render.ParseScaleMode(*scaleMode); err != nil {
+		return fail(err)
+	}
+	if *windowScale < 1 {
+		return fail(fmt.Errorf("window-scale must be at least 1, got %d", *windowScale))
+	}
+	opts.windowScale = *windowScale
+	opts.fullscreen = *fullscreen
+	opts.spectate = *spectate
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "palette" {
+			opts.paletteSet = true
+		}
+	})
+
+	opts.romName = DefaultROM
+
+	// If a filename is passed as an argument, use it
+	if flags.NArg() > 0 {
+		opts.romName = flags.Arg(0)
+	}
+	return opts, nil
+}
+
+// Validates display options given as flags, or from JavaScript in the browser
+func newDisplayOptions(palette string, persistence float64, grid, scanlines bool, present string, fadeFrames int) (options, error) {
+	opts := options{
+		effects: render.Effects{
+			Persistence: persistence,
+			Grid:        grid,
+			Scanlines:   scanlines,
+		},
+		fadeFrames: fadeFrames,
+	}
+
+	var err error
+	if opts.palette, err = render.ParsePalette(palette); err != nil {
+		return options{}, err
+	}
+	if persistence < 0 || persistence >= 1 {
+		return options{}, fmt.Errorf("persistence must be in [0, 1), got %v", persistence)
+	}
+
+	if opts.presentMode, err = chip8.ParsePresentMode(present); err != nil {
+		return options{}, err
+	}
+	if fadeFrames < 1 {
+		return options{}, fmt.Errorf("fade-frames must be at least 1, got %d", fadeFrames)
+	}
+	return opts, nil
+}
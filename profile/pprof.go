@@ -0,0 +1,226 @@
+package profile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// pprof export.
+// The profile is a gzipped profile.proto message, encoded by hand to keep
+// protobuf out of the dependencies. Each sample is an address with the call
+// sites that led to it and the number of times it was executed there.
+// ------------------------------------------------
+
+// Field numbers of profile.proto
+const (
+	profileSampleType  = 1
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+	profileDuration    = 10
+	profilePeriodType  = 11
+	profilePeriod      = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID      = 1
+	locationAddress = 3
+	locationLine    = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+// WritePprof writes the profile in pprof's format, see go tool pprof
+func (p *Profiler) WritePprof(w io.Writer) error {
+	e := &pprofEncoder{strings: map[string]int{"": 0}, stringTable: []string{""}, functions: make(map[uint16]uint64), locations: make(map[[2]uint16]uint64)}
+	var msg protoBuffer
+
+	valueType := func(b *protoBuffer) {
+		b.uint64(valueTypeType, uint64(e.string("instructions")))
+		b.uint64(valueTypeUnit, uint64(e.string("count")))
+	}
+	msg.message(profileSampleType, valueType)
+	p.root.walk(func(n *node) {
+		for _, pc := range sortedKeys(n.counts) {
+			ids := []uint64{e.location(n.entry, pc)}
+			for caller := n; caller.parent != nil; caller = caller.parent {
+				ids = append(ids, e.location(caller.parent.entry, caller.site))
+			}
+			msg.message(profileSample, func(b *protoBuffer) {
+				b.packed(sampleLocationID, ids)
+				b.packed(sampleValue, []uint64{n.counts[pc]})
+			})
+		}
+	})
+	for _, loc := range e.locationList {
+		msg.message(profileLocation, func(b *protoBuffer) {
+			b.uint64(locationID, loc.id)
+			b.uint64(locationAddress, uint64(loc.addr))
+			b.message(locationLine, func(b *protoBuffer) {
+				b.uint64(lineFunctionID, e.function(loc.entry))
+			})
+		})
+	}
+	for _, entry := range e.functionList {
+		name := uint64(e.string(fmt.Sprintf("0x%03X", entry)))
+		msg.message(profileFunction, func(b *protoBuffer) {
+			b.uint64(functionID, e.functions[entry])
+			b.uint64(functionName, name)
+			b.uint64(functionSystemName, name)
+		})
+	}
+	msg.message(profilePeriodType, valueType)
+	msg.uint64(profilePeriod, 1)
+	msg.uint64(profileDuration, uint64(time.Duration(len(p.frames))*time.Second/chip8.TIMER_HZ))
+	// Strings last, the fields above added them
+	for _, s := range e.stringTable {
+		msg.bytes(profileStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(msg.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Interns the strings, functions and locations of a profile, IDs start at 1
+type pprofEncoder struct {
+	strings      map[string]int
+	stringTable  []string
+	functions    map[uint16]uint64 // By entry
+	functionList []uint16
+	locations    map[[2]uint16]uint64 // By function entry and address
+	locationList []pprofLocation
+}
+
+type pprofLocation struct {
+	id          uint64
+	entry, addr uint16
+}
+
+func (e *pprofEncoder) string(s string) int {
+	i, ok := e.strings[s]
+	if !ok {
+		i = len(e.stringTable)
+		e.strings[s] = i
+		e.stringTable = append(e.stringTable, s)
+	}
+	return i
+}
+
+func (e *pprofEncoder) function(entry uint16) uint64 {
+	id, ok := e.functions[entry]
+	if !ok {
+		id = uint64(len(e.functionList) + 1)
+		e.functions[entry] = id
+		e.functionList = append(e.functionList, entry)
+	}
+	return id
+}
+
+// An address in a function, code shared by several functions has a
+// location in each
+func (e *pprofEncoder) location(entry, addr uint16) uint64 {
+	key := [2]uint16{entry, addr}
+	id, ok := e.locations[key]
+	if !ok {
+		id = uint64(len(e.locationList) + 1)
+		e.locations[key] = id
+		e.locationList = append(e.locationList, pprofLocation{id, entry, addr})
+		e.function(entry)
+	}
+	return id
+}
+
+// Visits the node and its descendants, in a stable order
+func (n *node) walk(visit func(*node)) {
+	visit(n)
+	keys := make([][2]uint16, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]uint16) int {
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		return int(a[1]) - int(b[1])
+	})
+	for _, key := range keys {
+		n.children[key].walk(visit)
+	}
+}
+
+func sortedKeys(counts map[uint16]uint64) []uint16 {
+	keys := make([]uint16, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ------------------------------------------------
+// protoBuffer appends the fields of a protobuf message. Zero numbers are
+// left out, as proto3 does.
+// ------------------------------------------------
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// Field key with wire type 0 (varint) or 2 (length delimited)
+func (b *protoBuffer) key(field, wireType int) {
+	b.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+// Strings of the string table are written even when empty, their position counts
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protoBuffer) packed(field int, xs []uint64) {
+	var inner protoBuffer
+	for _, x := range xs {
+		inner.varint(x)
+	}
+	b.bytes(field, inner.data)
+}
+
+func (b *protoBuffer) message(field int, encode func(*protoBuffer)) {
+	var inner protoBuffer
+	encode(&inner)
+	b.bytes(field, inner.data)
+}
@@ -0,0 +1,223 @@
+// Package profile finds where ROMs spend their cycles. A Profiler hooks
+// into a Chip8 and counts the instructions it executes by address and by
+// opcode class, the instructions of every frame and the calls made with
+// 2NNN. It writes a text report with a heatmap of memory, and profiles in
+// pprof's format for go tool pprof:
+//
+//	chip8 profile -o pong.pb.gz PONG
+//	go tool pprof -top pong.pb.gz
+//
+// Functions in the pprof profile are named after their first instruction,
+// code reached without a call belongs to the one at 0x200.
+package profile
+
+import (
+	"fmt"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Call is an edge of the call graph: the function starting at Caller called
+// Callee with the 2NNN at Site
+type Call struct {
+	Caller, Site, Callee uint16
+}
+
+// Profiler counts what a machine executes, from Start until Stop
+type Profiler struct {
+	machine      *chip8.Chip8
+	counts       [chip8.RAM]uint64
+	opcodes      [chip8.RAM]chip8.Opcode // Last executed at each address
+	classes      map[string]uint64
+	calls        map[Call]uint64
+	frames       []int // Instructions per frame
+	instructions uint64
+	frame        int // Instructions so far this frame
+
+	root    *node
+	current *node // The function running, as reached through the calls
+}
+
+// ------------------------------------------------
+// A node of the call tree: a function as called from a call site of its
+// caller's node, with the instructions run in it by address
+// ------------------------------------------------
+type node struct {
+	parent   *node
+	site     uint16 // The 2NNN in parent that called entry
+	entry    uint16
+	children map[[2]uint16]*node // By site and callee
+	counts   map[uint16]uint64
+}
+
+func newNode(parent *node, site, entry uint16) *node {
+	return &node{parent: parent, site: site, entry: entry, children: make(map[[2]uint16]*node), counts: make(map[uint16]uint64)}
+}
+
+func (n *node) child(site, callee uint16) *node {
+	key := [2]uint16{site, callee}
+	child, ok := n.children[key]
+	if !ok {
+		child = newNode(n, site, callee)
+		n.children[key] = child
+	}
+	return child
+}
+
+// Start profiles machine until Stop, replacing its hooks. Frames are
+// counted by the Display of the Profiler.
+func Start(machine *chip8.Chip8) *Profiler {
+	p := &Profiler{
+		machine: machine,
+		classes: make(map[string]uint64),
+		calls:   make(map[Call]uint64),
+		root:    newNode(nil, 0, chip8.PROGRAM_START),
+	}
+	p.current = p.root
+	machine.SetHooks(chip8.Hooks{Execute: p.execute})
+	return p
+}
+
+// Stop removes the machine's hooks, the counts are kept
+func (p *Profiler) Stop() {
+	p.machine.SetHooks(chip8.Hooks{})
+}
+
+func (p *Profiler) execute(pc uint16, op chip8.Opcode) {
+	p.counts[pc]++
+	p.opcodes[pc] = op
+	p.classes[Class(op)]++
+	p.instructions++
+	p.frame++
+	p.current.counts[pc]++
+
+	switch {
+	case op&0xF000 == 0x2000:
+		callee := op.NNN()
+		p.calls[Call{p.current.entry, pc, callee}]++
+		p.current = p.current.child(pc, callee)
+	case op == 0x00EE && p.current.parent != nil:
+		p.current = p.current.parent
+	}
+}
+
+// Display wraps the host.Display of a Runner to count the instructions of
+// every frame
+func (p *Profiler) Display(display host.Display) host.Display {
+	return &countingDisplay{profiler: p, display: display}
+}
+
+type countingDisplay struct {
+	profiler *Profiler
+	display  host.Display
+}
+
+func (d *countingDisplay) Present(frame [][]int, changed bool) error {
+	d.profiler.frames = append(d.profiler.frames, d.profiler.frame)
+	d.profiler.frame = 0
+	return d.display.Present(frame, changed)
+}
+
+// Instructions returns the number of instructions executed
+func (p *Profiler) Instructions() uint64 {
+	return p.instructions
+}
+
+// Count returns how often the instruction at addr was executed
+func (p *Profiler) Count(addr uint16) uint64 {
+	if int(addr) >= chip8.RAM {
+		return 0
+	}
+	return p.counts[addr]
+}
+
+// Classes returns the instructions executed by Class
+func (p *Profiler) Classes() map[string]uint64 {
+	classes := make(map[string]uint64, len(p.classes))
+	for class, count := range p.classes {
+		classes[class] = count
+	}
+	return classes
+}
+
+// Calls returns how often each call was made
+func (p *Profiler) Calls() map[Call]uint64 {
+	calls := make(map[Call]uint64, len(p.calls))
+	for call, count := range p.calls {
+		calls[call] = count
+	}
+	return calls
+}
+
+// FrameCycles returns the instructions executed in each frame presented
+func (p *Profiler) FrameCycles() []int {
+	return append([]int(nil), p.frames...)
+}
+
+// ------------------------------------------------
+// Class returns the opcode pattern op belongs to, e.g. 8XY4 for 8124, as
+// CHIP-8 references list them. Undefined opcodes get a pattern too.
+// ------------------------------------------------
+func Class(op chip8.Opcode) string {
+	// Opcode's String would be printed in hex
+	kind := uint16(op >> 12)
+	switch kind {
+	case 0x0:
+		if op == 0x00E0 || op == 0x00EE {
+			return op.String()
+		}
+		return "0NNN"
+	case 0x1, 0x2, 0xA, 0xB:
+		return fmt.Sprintf("%XNNN", kind)
+	case 0x3, 0x4, 0x6, 0x7, 0xC:
+		return fmt.Sprintf("%XXNN", kind)
+	case 0x5, 0x9:
+		return fmt.Sprintf("%XXY%X", kind, op.N())
+	case 0x8:
+		return fmt.Sprintf("8XY%X", op.N())
+	case 0xD:
+		return "DXYN"
+	default:
+		return fmt.Sprintf("%XX%02X", kind, op.NN())
+	}
+}
+
+// What the classes do, for the report
+var classNames = map[string]string{
+	"00E0": "clear the display",
+	"00EE": "return",
+	"0NNN": "machine code routine",
+	"1NNN": "jump",
+	"2NNN": "call",
+	"3XNN": "skip if VX == NN",
+	"4XNN": "skip if VX != NN",
+	"5XY0": "skip if VX == VY",
+	"6XNN": "VX = NN",
+	"7XNN": "VX += NN",
+	"8XY0": "VX = VY",
+	"8XY1": "VX |= VY",
+	"8XY2": "VX &= VY",
+	"8XY3": "VX ^= VY",
+	"8XY4": "VX += VY",
+	"8XY5": "VX -= VY",
+	"8XY6": "VX >>= 1",
+	"8XY7": "VX = VY - VX",
+	"8XYE": "VX <<= 1",
+	"9XY0": "skip if VX != VY",
+	"ANNN": "I = NNN",
+	"BNNN": "jump with offset",
+	"CXNN": "VX = random & NN",
+	"DXYN": "draw",
+	"EX9E": "skip if key VX is held",
+	"EXA1": "skip unless key VX is held",
+	"FX07": "VX = delay timer",
+	"FX0A": "wait for a key",
+	"FX15": "delay timer = VX",
+	"FX18": "sound timer = VX",
+	"FX1E": "I += VX",
+	"FX29": "I = font sprite of VX",
+	"FX33": "store BCD of VX",
+	"FX55": "store V0-VX",
+	"FX65": "load V0-VX",
+}
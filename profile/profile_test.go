@@ -0,0 +1,148 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Calls a subroutine once per frame, which calls another one:
+//
+//	0x200: 2206  call 0x206
+//	0x202: F00A  wait for a key, ends the frame's work
+//	0x204: 1200  jump to 0x200
+//	0x206: 220C  call 0x20C
+//	0x208: 7001  V0 += 1
+//	0x20A: 00EE  return
+//	0x20C: 8014  V0 += V1
+//	0x20E: 00EE  return
+var callROM = []byte{0x22, 0x06, 0xF0, 0x0A, 0x12, 0x00, 0x22, 0x0C, 0x70, 0x01, 0x00, 0xEE, 0x80, 0x14, 0x00, 0xEE}
+
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
+
+// Runs frames with a key pressed and released in turn, so FX0A lets the
+// program through every other frame
+func profileFrames(t *testing.T, frames int) (*Profiler, *chip8.Chip8) {
+	t.Helper()
+	machine, err := host.NewMachine(callROM, chip8.Options{Speed: 600})
+	require.NoError(t, err)
+	p := Start(machine)
+	runner := host.NewRunner(machine, p.Display(discardDisplay{}), nil, nil)
+	for frame := 0; frame < frames; frame++ {
+		machine.UpdateKeyboardState(chip8.KEY_0, frame%2 == 0)
+		require.NoError(t, runner.Frame())
+	}
+	p.Stop()
+	return p, machine
+}
+
+func TestProfiler_Counts(t *testing.T) {
+	p, machine := profileFrames(t, 4)
+
+	require.Len(t, p.FrameCycles(), 4)
+	require.Equal(t, uint64(40), p.Instructions())
+	var total int
+	for _, cycles := range p.FrameCycles() {
+		total += cycles
+	}
+	require.Equal(t, 40, total)
+
+	// Every address ran, the call tree balances out
+	calls := p.Calls()
+	outer := calls[Call{Caller: 0x200, Site: 0x200, Callee: 0x206}]
+	require.NotZero(t, outer)
+	require.Equal(t, outer, calls[Call{Caller: 0x206, Site: 0x206, Callee: 0x20C}])
+	require.Equal(t, outer, p.Count(0x20C))
+	require.Equal(t, 2*outer, p.Classes()["00EE"])
+	require.Equal(t, p.Count(0x202), p.Classes()["FX0A"])
+	require.Zero(t, p.Count(0x210))
+	require.Zero(t, p.Count(0xFFFF))
+
+	// Stopped profilers count nothing more
+	machine.Step()
+	require.Equal(t, uint64(40), p.Instructions())
+}
+
+func TestClass(t *testing.T) {
+	for op, class := range map[chip8.Opcode]string{
+		0x00E0: "00E0", 0x00EE: "00EE", 0x0123: "0NNN", 0x1ABC: "1NNN", 0x2ABC: "2NNN",
+		0x3A12: "3XNN", 0x5AB0: "5XY0", 0x8AB4: "8XY4", 0x8ABE: "8XYE", 0x9AB0: "9XY0",
+		0xB123: "BNNN", 0xCA0F: "CXNN", 0xDAB5: "DXYN", 0xE19E: "EX9E", 0xF165: "FX65",
+	} {
+		require.Equal(t, class, Class(op), "%04X", uint16(op))
+	}
+	for class := range classNames {
+		require.Len(t, class, 4)
+	}
+}
+
+func TestProfiler_Report(t *testing.T) {
+	p, _ := profileFrames(t, 4)
+	var out bytes.Buffer
+	require.NoError(t, p.WriteReport(&out, 3))
+	report := out.String()
+
+	require.Contains(t, report, "40 instructions in 4 frames, 10.0 per frame")
+	hotSpots := report[strings.Index(report, "Hot spots"):strings.Index(report, "Opcode classes")]
+	require.Equal(t, 3+3, strings.Count(hotSpots, "\n"), "a title, a header and 3 rows:\n%s", hotSpots)
+	require.Contains(t, hotSpots, "0x202    F00A")
+	require.Regexp(t, `00EE\s+\d+\s+[\d.]+%\s+return`, report)
+	require.Regexp(t, `0x206\s+0x206\s+0x20C`, report)
+
+	// The heatmap has the program's row, and elides the empty ones around it
+	require.Regexp(t, `\n  \.\.\.\n  0x200 \|[^ ]{8} {24}\|\n  \.\.\.\n$`, report)
+}
+
+func TestProfiler_Pprof(t *testing.T) {
+	p, _ := profileFrames(t, 4)
+	var out bytes.Buffer
+	require.NoError(t, p.WritePprof(&out))
+
+	gz, err := gzip.NewReader(&out)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	// Decodes the top level fields
+	fields := make(map[uint64][][]byte)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			_, n = binary.Uvarint(data)
+			fields[key>>3] = append(fields[key>>3], nil)
+			data = data[n:]
+		case 2:
+			length, n := binary.Uvarint(data)
+			fields[key>>3] = append(fields[key>>3], data[n:n+int(length)])
+			data = data[n+int(length):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+
+	var strs []string
+	for _, s := range fields[profileStringTable] {
+		strs = append(strs, string(s))
+	}
+	require.Equal(t, "", strs[0])
+	require.Subset(t, strs, []string{"instructions", "count", "0x200", "0x206", "0x20C"})
+	require.Len(t, fields[profileFunction], 3)
+	// An address per function, and the call sites: 8 instructions and 2 sites
+	require.Len(t, fields[profileLocation], 8)
+	require.Len(t, fields[profileSample], 8)
+	require.Len(t, fields[profileDuration], 1)
+}
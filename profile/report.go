@@ -0,0 +1,140 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+const (
+	heatmapRow  = 64 // Bytes per heatmap row, a cell is an instruction's 2 bytes
+	heatmapRamp = " .:-=+*#%@"
+)
+
+// ------------------------------------------------
+// WriteReport writes the profile as text: the instructions per frame, the
+// top hot spots, the opcode classes, the calls and a heatmap of the memory
+// executed
+// ------------------------------------------------
+func (p *Profiler) WriteReport(w io.Writer, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "%d instructions in %d frames", p.instructions, len(p.frames))
+	if len(p.frames) > 0 {
+		fmt.Fprintf(tw, ", %.1f per frame (min %d, max %d)",
+			float64(p.instructions)/float64(len(p.frames)), slices.Min(p.frames), slices.Max(p.frames))
+	}
+	fmt.Fprintln(tw)
+
+	var addrs []uint16
+	for addr, count := range p.counts {
+		if count > 0 {
+			addrs = append(addrs, uint16(addr))
+		}
+	}
+	slices.SortStableFunc(addrs, func(a, b uint16) int { return compareCounts(p.counts[a], p.counts[b]) })
+	fmt.Fprintln(tw, "\nHot spots\nADDRESS\tOPCODE\tCOUNT\tSHARE\tCLASS")
+	for _, addr := range addrs[:min(top, len(addrs))] {
+		op := p.opcodes[addr]
+		fmt.Fprintf(tw, "0x%03X\t%s\t%d\t%s\t%s\n", addr, op, p.counts[addr], p.share(p.counts[addr]), Class(op))
+	}
+
+	classes := make([]string, 0, len(p.classes))
+	for class := range p.classes {
+		classes = append(classes, class)
+	}
+	slices.Sort(classes)
+	slices.SortStableFunc(classes, func(a, b string) int { return compareCounts(p.classes[a], p.classes[b]) })
+	fmt.Fprintln(tw, "\nOpcode classes\nCLASS\tCOUNT\tSHARE\tDESCRIPTION")
+	for _, class := range classes {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", class, p.classes[class], p.share(p.classes[class]), classNames[class])
+	}
+
+	if len(p.calls) > 0 {
+		calls := make([]Call, 0, len(p.calls))
+		for call := range p.calls {
+			calls = append(calls, call)
+		}
+		slices.SortFunc(calls, func(a, b Call) int {
+			if c := compareCounts(p.calls[a], p.calls[b]); c != 0 {
+				return c
+			}
+			return int(a.Site) - int(b.Site)
+		})
+		fmt.Fprintln(tw, "\nCalls\nCALLER\tSITE\tCALLEE\tCOUNT")
+		for _, call := range calls {
+			fmt.Fprintf(tw, "0x%03X\t0x%03X\t0x%03X\t%d\n", call.Caller, call.Site, call.Callee, p.calls[call])
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return p.writeHeatmap(w)
+}
+
+// Larger counts first
+func compareCounts(a, b uint64) int {
+	switch {
+	case a > b:
+		return -1
+	case a < b:
+		return 1
+	}
+	return 0
+}
+
+func (p *Profiler) share(count uint64) string {
+	if p.instructions == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(count)/float64(p.instructions))
+}
+
+// ------------------------------------------------
+// Rows of memory with executed instructions, a cell per 2 bytes from blank
+// to @ on a log scale, so a loop that runs a million times doesn't hide
+// code that ran a hundred. Runs of rows with nothing executed are elided.
+// ------------------------------------------------
+func (p *Profiler) writeHeatmap(w io.Writer) error {
+	var hottest uint64
+	for _, count := range p.counts {
+		hottest = max(hottest, count)
+	}
+	if hottest == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nMemory heatmap, %d bytes per row, %q from never to %d executions\n", heatmapRow, heatmapRamp, hottest)
+	elided := false
+	for row := 0; row < chip8.RAM; row += heatmapRow {
+		cells := make([]byte, heatmapRow/2)
+		executed := false
+		for i := range cells {
+			// Instructions may start at odd addresses
+			count := p.counts[row+2*i] + p.counts[row+2*i+1]
+			level := 0
+			if count > 0 {
+				executed = true
+				level = 1 + int(math.Log(float64(count))/math.Log(float64(2*hottest))*float64(len(heatmapRamp)-2)+0.5)
+			}
+			cells[i] = heatmapRamp[min(level, len(heatmapRamp)-1)]
+		}
+		if !executed {
+			if !elided {
+				b.WriteString("  ...\n")
+				elided = true
+			}
+			continue
+		}
+		elided = false
+		fmt.Fprintf(&b, "  0x%03X |%s|\n", row, cells)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
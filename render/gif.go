@@ -0,0 +1,80 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/gif"
+	"io"
+)
+
+// ------------------------------------------------
+// Animated GIF recording of a play session.
+// Frames are captured once per 60 Hz frame, consecutive identical frames are
+// merged into one GIF frame with a longer delay to keep the file small.
+// ------------------------------------------------
+
+const FrameRate = 60 // Frames per second the recorder assumes between AddFrame calls
+
+type Recorder struct {
+	opts   Options
+	anim   gif.GIF
+	frames int   // Total number of 60 Hz frames captured so far
+	starts []int // 60 Hz frame at which each GIF frame starts
+}
+
+func NewRecorder(opts Options) *Recorder {
+	return &Recorder{opts: opts}
+}
+
+// AddFrame captures the display as the next 60 Hz frame of the recording
+func (r *Recorder) AddFrame(display [][]int) {
+	frame := NewImage(display, r.opts).Paletted()
+
+	last := len(r.anim.Image) - 1
+	if last < 0 || !samePixels(r.anim.Image[last], frame) {
+		r.anim.Image = append(r.anim.Image, frame)
+		r.anim.Delay = append(r.anim.Delay, 0)
+		r.starts = append(r.starts, r.frames)
+	}
+	r.frames++
+}
+
+// Frames returns the number of 60 Hz frames captured so far
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+// Encode writes the recording as an animated GIF
+func (r *Recorder) Encode(w io.Writer) error {
+	if len(r.anim.Image) == 0 {
+		return errors.New("render: no frames recorded")
+	}
+
+	// GIF delays are in 1/100 s, round the start of every frame rather than
+	// each frame's length so the error does not accumulate over a long session
+	for i := range r.anim.Image {
+		end := r.frames
+		if i+1 < len(r.starts) {
+			end = r.starts[i+1]
+		}
+		r.anim.Delay[i] = centiseconds(end) - centiseconds(r.starts[i])
+	}
+
+	return gif.EncodeAll(w, &r.anim)
+}
+
+func centiseconds(frame int) int {
+	return (frame*100 + FrameRate/2) / FrameRate
+}
+
+func samePixels(a, b *image.Paletted) bool {
+	if len(a.Pix) != len(b.Pix) {
+		return false
+	}
+	for i := range a.Pix {
+		if a.Pix[i] != b.Pix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package render
+
+import (
+	"bytes"
+	"image/gif"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func TestRecorder_MergesIdenticalFrames(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	recorder := NewRecorder(Options{Scale: 1})
+
+	// 30 blank frames, then 60 frames with a lit pixel
+	for i := 0; i < 30; i++ {
+		recorder.AddFrame(display)
+	}
+	display[5][5] = 1
+	for i := 0; i < 60; i++ {
+		recorder.AddFrame(display)
+	}
+	require.Equal(t, 90, recorder.Frames())
+
+	var buf bytes.Buffer
+	require.NoError(t, recorder.Encode(&buf))
+
+	anim, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, anim.Image, 2)
+	require.Equal(t, []int{50, 100}, anim.Delay)
+	require.Equal(t, uint8(1), anim.Image[1].ColorIndexAt(5, 5))
+}
+
+func TestRecorder_EncodeWithoutFrames(t *testing.T) {
+	recorder := NewRecorder(Options{})
+	require.Error(t, recorder.Encode(&bytes.Buffer{}))
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Renderer independent view of the CHIP-8 display.
+// The SDL and WASM front-ends blit the display themselves, this package
+// turns the same [][]int buffer returned by GetDisplay() into an
+// image.Image so it can be saved or encoded without a window.
+// ------------------------------------------------
+
+const DefaultScale = 10
+
+// Palette holds the two colours a monochrome CHIP-8 display is drawn with
+type Palette struct {
+	Background color.RGBA
+	Foreground color.RGBA
+}
+
+// DefaultPalette matches the red/yellow colours used by both front-ends
+var DefaultPalette = Palette{
+	Background: color.RGBA{R: 255, G: 0, B: 0, A: 255},
+	Foreground: color.RGBA{R: 255, G: 255, B: 0, A: 255},
+}
+
+// Colors returns the palette as a color.Palette with the background at index 0
+func (p Palette) Colors() color.Palette {
+	return color.Palette{p.Background, p.Foreground}
+}
+
+// Options control how a display buffer is turned into an image
+type Options struct {
+	Palette Palette // Defaults to DefaultPalette
+	Scale   int     // Size of one CHIP-8 pixel in image pixels, defaults to DefaultScale
+
+	// Value of a fully lit pixel in the buffers that are drawn. Defaults to 1
+	// as in GetDisplay(), use chip8.FRAME_MAX to draw the presentation buffer.
+	Levels int
+}
+
+func (opts Options) levels() int {
+	if opts.Levels <= 0 {
+		return 1
+	}
+	return opts.Levels
+}
+
+func (opts Options) palette() Palette {
+	if opts.Palette == (Palette{}) {
+		return DefaultPalette
+	}
+	return opts.Palette
+}
+
+func (opts Options) scale() int {
+	if opts.Scale <= 0 {
+		return DefaultScale
+	}
+	return opts.Scale
+}
+
+// Image is an image.Image backed directly by a display buffer.
+// It is a live view: changes to the display show up in the image.
+type Image struct {
+	display [][]int
+	palette color.Palette
+	scale   int
+}
+
+func NewImage(display [][]int, opts Options) *Image {
+	return &Image{
+		display: display,
+		palette: opts.palette().Colors(),
+		scale:   opts.scale(),
+	}
+}
+
+func (img *Image) ColorModel() color.Model {
+	return img.palette
+}
+
+func (img *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, chip8.DISPLAY_COLS*img.scale, chip8.DISPLAY_ROWS*img.scale)
+}
+
+func (img *Image) At(x, y int) color.Color {
+	return img.palette[img.ColorIndexAt(x, y)]
+}
+
+// ColorIndexAt implements image.PalettedImage
+func (img *Image) ColorIndexAt(x, y int) uint8 {
+	if !(image.Point{X: x, Y: y}.In(img.Bounds())) {
+		return 0
+	}
+	if img.display[y/img.scale][x/img.scale] != 0 {
+		return 1
+	}
+	return 0
+}
+
+// Paletted copies the current display into a new image.Paletted
+func (img *Image) Paletted() *image.Paletted {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, img.palette)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			paletted.SetColorIndex(x, y, img.ColorIndexAt(x, y))
+		}
+	}
+	return paletted
+}
+
+// WritePNG encodes a snapshot of the display as a PNG
+func WritePNG(w io.Writer, display [][]int, opts Options) error {
+	return png.Encode(w, NewImage(display, opts).Paletted())
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func TestImage_BoundsAndColors(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[1][2] = 1
+
+	img := NewImage(display, Options{Scale: 3})
+	require.Equal(t, chip8.DISPLAY_COLS*3, img.Bounds().Dx())
+	require.Equal(t, chip8.DISPLAY_ROWS*3, img.Bounds().Dy())
+
+	// Every image pixel covered by display[1][2] is foreground
+	for y := 3; y < 6; y++ {
+		for x := 6; x < 9; x++ {
+			require.Equal(t, DefaultPalette.Foreground, img.At(x, y))
+		}
+	}
+	require.Equal(t, DefaultPalette.Background, img.At(5, 3))
+	require.Equal(t, DefaultPalette.Background, img.At(9, 5))
+
+	// The image is a live view of the display
+	display[1][2] = 0
+	require.Equal(t, DefaultPalette.Background, img.At(6, 3))
+}
+
+func TestWritePNG(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[0][0] = 1
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePNG(&buf, display, Options{Scale: 1}))
+
+	decoded, err := png.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, chip8.DISPLAY_COLS, decoded.Bounds().Dx())
+	require.Equal(t, chip8.DISPLAY_ROWS, decoded.Bounds().Dy())
+
+	r, g, b, _ := decoded.At(0, 0).RGBA()
+	require.Equal(t, [3]uint32{0xFFFF, 0xFFFF, 0}, [3]uint32{r, g, b})
+	r, g, b, _ = decoded.At(1, 0).RGBA()
+	require.Equal(t, [3]uint32{0xFFFF, 0, 0}, [3]uint32{r, g, b})
+}
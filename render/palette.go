@@ -0,0 +1,90 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------
+// Built-in palettes and parsing of custom colours.
+// A palette is selected by preset name, or given as two hex colours
+// "BACKGROUND,FOREGROUND" e.g. "#000000,#33FF33".
+// ------------------------------------------------
+
+var Presets = map[string]Palette{
+	"classic": DefaultPalette,
+	"green": { // P1 green phosphor
+		Background: color.RGBA{R: 0x00, G: 0x14, B: 0x00, A: 255},
+		Foreground: color.RGBA{R: 0x33, G: 0xFF, B: 0x33, A: 255},
+	},
+	"amber": { // P3 amber phosphor
+		Background: color.RGBA{R: 0x1A, G: 0x0F, B: 0x00, A: 255},
+		Foreground: color.RGBA{R: 0xFF, G: 0xB0, B: 0x00, A: 255},
+	},
+	"lcd": { // Reflective green LCD
+		Background: color.RGBA{R: 0x9B, G: 0xBC, B: 0x0F, A: 255},
+		Foreground: color.RGBA{R: 0x0F, G: 0x38, B: 0x0F, A: 255},
+	},
+	"high-contrast": {
+		Background: color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 255},
+		Foreground: color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 255},
+	},
+}
+
+// PresetNames returns the names of the built-in palettes in sorted order
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePalette returns the preset with the given name, or parses a custom
+// "BACKGROUND,FOREGROUND" pair of hex colours
+func ParsePalette(spec string) (Palette, error) {
+	if palette, ok := Presets[spec]; ok {
+		return palette, nil
+	}
+
+	colors := strings.Split(spec, ",")
+	if len(colors) != 2 {
+		return Palette{}, fmt.Errorf("unknown palette %q, expected one of %v or BACKGROUND,FOREGROUND", spec, PresetNames())
+	}
+
+	background, err := ParseColor(colors[0])
+	if err != nil {
+		return Palette{}, err
+	}
+	foreground, err := ParseColor(colors[1])
+	if err != nil {
+		return Palette{}, err
+	}
+	return Palette{Background: background, Foreground: foreground}, nil
+}
+
+// ParseColor parses a "#RRGGBB" or "#RGB" hex colour, the # is optional
+func ParseColor(hex string) (color.RGBA, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", hex)
+	}
+
+	val, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", hex)
+	}
+	return color.RGBA{R: uint8(val >> 16), G: uint8(val >> 8), B: uint8(val), A: 255}, nil
+}
+
+// Hex formats a colour as "#RRGGBB", the format canvas fillStyle expects
+func Hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
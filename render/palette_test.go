@@ -0,0 +1,54 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		hex    string
+		expect color.RGBA
+		err    bool
+	}{
+		{"long form", "#33FF33", color.RGBA{R: 0x33, G: 0xFF, B: 0x33, A: 255}, false},
+		{"lower case without #", "ffb000", color.RGBA{R: 0xFF, G: 0xB0, B: 0x00, A: 255}, false},
+		{"short form", "#0F0", color.RGBA{R: 0x00, G: 0xFF, B: 0x00, A: 255}, false},
+		{"wrong length", "#12345", color.RGBA{}, true},
+		{"not hex", "#GGGGGG", color.RGBA{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseColor(tt.hex)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, c)
+		})
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	palette, err := ParsePalette("amber")
+	require.NoError(t, err)
+	require.Equal(t, Presets["amber"], palette)
+
+	palette, err = ParsePalette("#000000,#FFFFFF")
+	require.NoError(t, err)
+	require.Equal(t, Presets["high-contrast"], palette)
+
+	_, err = ParsePalette("purple")
+	require.Error(t, err)
+
+	_, err = ParsePalette("#000000,nope")
+	require.Error(t, err)
+}
+
+func TestHex(t *testing.T) {
+	require.Equal(t, "#FFB000", Hex(Presets["amber"].Foreground))
+}
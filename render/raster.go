@@ -0,0 +1,47 @@
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Software rasterisation of a Screen into an RGBA pixel buffer, for
+// front-ends that upload a whole frame at once (SDL streaming textures,
+// canvas ImageData) instead of filling rectangles one by one
+// ------------------------------------------------
+
+// NewRGBA returns an image the size of the screen at its scale
+func (s *Screen) NewRGBA() *image.RGBA {
+	scale := s.opts.scale()
+	return image.NewRGBA(image.Rect(0, 0, chip8.DISPLAY_COLS*scale, chip8.DISPLAY_ROWS*scale))
+}
+
+// Rasterize draws the buffer into dst, which should come from NewRGBA.
+// Fills are clipped to the bounds of dst.
+func (s *Screen) Rasterize(buffer [][]int, dst *image.RGBA) {
+	s.Draw(buffer, func(x, y, w, h int, c color.RGBA) {
+		fillRGBA(dst, image.Rect(x, y, x+w, y+h), c)
+	})
+}
+
+func fillRGBA(dst *image.RGBA, rect image.Rectangle, c color.RGBA) {
+	rect = rect.Intersect(dst.Bounds())
+	if rect.Empty() {
+		return
+	}
+
+	// Fill the first row, then copy it to the rows below
+	first := dst.PixOffset(rect.Min.X, rect.Min.Y)
+	width := rect.Dx() * 4
+	row := dst.Pix[first : first+width]
+	for i := 0; i < width; i += 4 {
+		row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+	}
+	for y := rect.Min.Y + 1; y < rect.Max.Y; y++ {
+		offset := dst.PixOffset(rect.Min.X, y)
+		copy(dst.Pix[offset:offset+width], row)
+	}
+}
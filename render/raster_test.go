@@ -0,0 +1,35 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func TestScreen_Rasterize(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[1][2] = 1
+	palette := Presets["amber"]
+
+	screen := NewScreen(Options{Palette: palette, Scale: 2}, Effects{})
+	img := screen.NewRGBA()
+	require.Equal(t, chip8.DISPLAY_COLS*2, img.Bounds().Dx())
+	require.Equal(t, chip8.DISPLAY_ROWS*2, img.Bounds().Dy())
+
+	screen.Rasterize(display, img)
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			expect := palette.Background
+			if x/2 == 2 && y/2 == 1 {
+				expect = palette.Foreground
+			}
+			require.Equal(t, expect, img.RGBAAt(x, y), "pixel (%d, %d)", x, y)
+		}
+	}
+
+	// Rasterising again redraws the background over old pixels
+	display[1][2] = 0
+	screen.Rasterize(display, img)
+	require.Equal(t, palette.Background, img.RGBAAt(4, 2))
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"image/color"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Shared rendering for the live front-ends.
+// Screen decides the colour and size of every rectangle that is drawn, so
+// the SDL renderer and the canvas renderer only need a fill function and
+// always look the same.
+// ------------------------------------------------
+
+type Effects struct {
+	// Fraction of a pixel's brightness kept per 60 Hz frame after it is
+	// turned off, 0 disables. Ghosting hides the flicker of sprites that are
+	// erased and redrawn with XOR.
+	Persistence float64
+	Grid        bool // Dark gap around every pixel
+	Scanlines   bool // Dark gap below every row of pixels
+}
+
+// FillFunc fills an axis aligned rectangle, in output pixels, with a colour
+type FillFunc func(x, y, w, h int, c color.RGBA)
+
+type Screen struct {
+	opts    Options
+	effects Effects
+	glow    [chip8.DISPLAY_ROWS][chip8.DISPLAY_COLS]float64 // Brightness of unlit pixels, 0..1
+}
+
+func NewScreen(opts Options, effects Effects) *Screen {
+	return &Screen{opts: opts, effects: effects}
+}
+
+func (s *Screen) Options() Options {
+	return s.opts
+}
+
+func (s *Screen) SetOptions(opts Options) {
+	s.opts = opts
+}
+
+func (s *Screen) Effects() Effects {
+	return s.effects
+}
+
+func (s *Screen) SetEffects(effects Effects) {
+	s.effects = effects
+}
+
+// Fade advances phosphor persistence by one 60 Hz frame.
+// Returns true while some pixel is still fading and the screen should be redrawn.
+func (s *Screen) Fade(display [][]int) bool {
+	if s.effects.Persistence <= 0 {
+		return false
+	}
+
+	fading := false
+	for y := range s.glow {
+		for x := range s.glow[y] {
+			level := s.level(display[y][x])
+			switch {
+			case level >= s.glow[y][x]:
+				s.glow[y][x] = level
+			case s.glow[y][x] > 0:
+				s.glow[y][x] *= s.effects.Persistence
+				if s.glow[y][x] < level {
+					s.glow[y][x] = level
+				}
+				if s.glow[y][x] < 1.0/255 {
+					s.glow[y][x] = 0
+				}
+				fading = true
+			}
+		}
+	}
+	return fading
+}
+
+// Color returns the colour the pixel at row y, column x is drawn with
+func (s *Screen) Color(display [][]int, x, y int) color.RGBA {
+	palette := s.opts.palette()
+	level := s.level(display[y][x])
+	if s.effects.Persistence > 0 && s.glow[y][x] > level {
+		level = s.glow[y][x]
+	}
+
+	switch {
+	case level >= 1:
+		return palette.Foreground
+	case level <= 0:
+		return palette.Background
+	}
+	return blend(palette.Background, palette.Foreground, level)
+}
+
+// Brightness of a pixel value from 0 to 1
+func (s *Screen) level(val int) float64 {
+	level := float64(val) / float64(s.opts.levels())
+	if level > 1 {
+		return 1
+	}
+	return level
+}
+
+// Draw renders the display through fill. The background is filled first, then
+// only pixels that differ from it, which keeps the number of fills small.
+func (s *Screen) Draw(display [][]int, fill FillFunc) {
+	palette := s.opts.palette()
+	scale := s.opts.scale()
+
+	gapX, gapY := 0, 0
+	if s.effects.Grid && scale > 2 {
+		gapX, gapY = 1, 1
+	}
+	if s.effects.Scanlines && scale > 1 {
+		gapY = 1
+	}
+
+	gaps := gapX > 0 || gapY > 0
+	background := palette.Background
+	if gaps {
+		background = blend(color.RGBA{A: 255}, palette.Background, 0.5)
+	}
+	fill(0, 0, chip8.DISPLAY_COLS*scale, chip8.DISPLAY_ROWS*scale, background)
+
+	for y := 0; y < chip8.DISPLAY_ROWS; y++ {
+		for x := 0; x < chip8.DISPLAY_COLS; x++ {
+			c := s.Color(display, x, y)
+			if c == background {
+				continue
+			}
+			fill(x*scale, y*scale, scale-gapX, scale-gapY, c)
+		}
+	}
+}
+
+// Linear interpolation from a to b, t in 0..1
+func blend(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(from, to uint8) uint8 {
+		return uint8(float64(from) + (float64(to)-float64(from))*t + 0.5)
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: 255}
+}
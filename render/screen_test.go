@@ -0,0 +1,90 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+type fillCall struct {
+	x, y, w, h int
+	c          color.RGBA
+}
+
+func drawCalls(screen *Screen, display [][]int) []fillCall {
+	var calls []fillCall
+	screen.Draw(display, func(x, y, w, h int, c color.RGBA) {
+		calls = append(calls, fillCall{x, y, w, h, c})
+	})
+	return calls
+}
+
+func TestScreen_DrawOnlyLitPixels(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[2][3] = 1
+	palette := Presets["green"]
+
+	calls := drawCalls(NewScreen(Options{Palette: palette, Scale: 4}, Effects{}), display)
+	require.Equal(t, []fillCall{
+		{0, 0, chip8.DISPLAY_COLS * 4, chip8.DISPLAY_ROWS * 4, palette.Background},
+		{12, 8, 4, 4, palette.Foreground},
+	}, calls)
+}
+
+func TestScreen_DrawGridAndScanlines(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+
+	// With gaps every pixel is drawn over the darker gap colour
+	calls := drawCalls(NewScreen(Options{Scale: 4}, Effects{Grid: true}), display)
+	require.Len(t, calls, 1+chip8.DISPLAY_ROWS*chip8.DISPLAY_COLS)
+	require.Equal(t, fillCall{0, 0, 3, 3, DefaultPalette.Background}, calls[1])
+
+	calls = drawCalls(NewScreen(Options{Scale: 4}, Effects{Scanlines: true}), display)
+	require.Equal(t, fillCall{4, 0, 4, 3, DefaultPalette.Background}, calls[2])
+}
+
+func TestScreen_Persistence(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	palette := Presets["high-contrast"]
+	screen := NewScreen(Options{Palette: palette}, Effects{Persistence: 0.5})
+
+	display[0][0] = 1
+	require.False(t, screen.Fade(display))
+	require.Equal(t, palette.Foreground, screen.Color(display, 0, 0))
+
+	// Turned off pixels fade out over several frames instead of vanishing
+	display[0][0] = 0
+	require.True(t, screen.Fade(display))
+	require.Equal(t, color.RGBA{R: 128, G: 128, B: 128, A: 255}, screen.Color(display, 0, 0))
+
+	for i := 0; i < 10; i++ {
+		screen.Fade(display)
+	}
+	require.False(t, screen.Fade(display))
+	require.Equal(t, palette.Background, screen.Color(display, 0, 0))
+}
+
+func TestScreen_NoPersistence(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	screen := NewScreen(Options{}, Effects{})
+
+	display[0][0] = 1
+	screen.Fade(display)
+	display[0][0] = 0
+	require.False(t, screen.Fade(display))
+	require.Equal(t, DefaultPalette.Background, screen.Color(display, 0, 0))
+}
+
+func TestScreen_Levels(t *testing.T) {
+	frame := chip8.NewChip8(false, false, 700).Frame()
+	palette := Presets["high-contrast"]
+	screen := NewScreen(Options{Palette: palette, Levels: chip8.FRAME_MAX}, Effects{})
+
+	frame[0][0] = chip8.FRAME_MAX
+	frame[0][1] = chip8.FRAME_MAX / 2
+	require.Equal(t, palette.Foreground, screen.Color(frame, 0, 0))
+	require.Equal(t, color.RGBA{R: 127, G: 127, B: 127, A: 255}, screen.Color(frame, 1, 0))
+	require.Equal(t, palette.Background, screen.Color(frame, 2, 0))
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Placement of the display inside a window of arbitrary size
+// ------------------------------------------------
+
+type ScaleMode int
+
+const (
+	ScaleFit     ScaleMode = iota // Largest size that keeps the 2:1 aspect ratio
+	ScaleInteger                  // Largest whole multiple of the display size, pixels stay square and even
+	ScaleStretch                  // Fill the whole window
+)
+
+var scaleModeNames = []string{
+	ScaleFit:     "fit",
+	ScaleInteger: "integer",
+	ScaleStretch: "stretch",
+}
+
+func (mode ScaleMode) String() string {
+	if int(mode) < len(scaleModeNames) {
+		return scaleModeNames[mode]
+	}
+	return "unknown"
+}
+
+func ParseScaleMode(name string) (ScaleMode, error) {
+	for mode, modeName := range scaleModeNames {
+		if modeName == name {
+			return ScaleMode(mode), nil
+		}
+	}
+	return ScaleFit, fmt.Errorf("unknown scale mode %q, expected one of %v", name, scaleModeNames)
+}
+
+// Viewport returns the rectangle the display is drawn in, centered in an
+// output of outW x outH pixels
+func Viewport(mode ScaleMode, outW, outH int) image.Rectangle {
+	cols, rows := chip8.DISPLAY_COLS, chip8.DISPLAY_ROWS
+
+	w, h := outW, outH
+	switch mode {
+	case ScaleInteger:
+		factor := min(outW/cols, outH/rows)
+		if factor >= 1 {
+			w, h = cols*factor, rows*factor
+			break
+		}
+		// Window smaller than the display, fall back to fit
+		fallthrough
+
+	case ScaleFit:
+		if outW*rows > outH*cols {
+			w = outH * cols / rows
+		} else {
+			h = outW * rows / cols
+		}
+	}
+
+	x := (outW - w) / 2
+	y := (outH - h) / 2
+	return image.Rect(x, y, x+w, y+h)
+}
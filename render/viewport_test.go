@@ -0,0 +1,39 @@
+package render
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewport(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       ScaleMode
+		outW, outH int
+		expect     image.Rectangle
+	}{
+		{"fit exact", ScaleFit, 640, 320, image.Rect(0, 0, 640, 320)},
+		{"fit wide window", ScaleFit, 1000, 300, image.Rect(200, 0, 800, 300)},
+		{"fit tall window", ScaleFit, 300, 1000, image.Rect(0, 425, 300, 575)},
+		{"integer", ScaleInteger, 1000, 300, image.Rect(212, 6, 788, 294)},
+		{"integer smaller than display", ScaleInteger, 50, 50, image.Rect(0, 12, 50, 37)},
+		{"stretch", ScaleStretch, 1000, 300, image.Rect(0, 0, 1000, 300)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, Viewport(tt.mode, tt.outW, tt.outH))
+		})
+	}
+}
+
+func TestParseScaleMode(t *testing.T) {
+	for _, mode := range []ScaleMode{ScaleFit, ScaleInteger, ScaleStretch} {
+		parsed, err := ParseScaleMode(mode.String())
+		require.NoError(t, err)
+		require.Equal(t, mode, parsed)
+	}
+	_, err := ParseScaleMode("zoom")
+	require.Error(t, err)
+}
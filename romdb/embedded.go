@@ -0,0 +1,101 @@
+// Code generated by gen.go from programs.json and platforms.json. DO NOT EDIT.
+
+package romdb
+
+var embeddedPlatforms = []Platform{
+	{ID: "modernChip8", Name: "Modern CHIP-8", Quirks: map[string]bool{"jump": false, "logic": false, "memoryIncrementByX": false, "memoryLeaveIUnchanged": false, "shift": false, "vblank": false, "wrap": false}},
+	{ID: "originalChip8", Name: "Cosmac VIP CHIP-8", Quirks: map[string]bool{"jump": false, "logic": true, "memoryIncrementByX": false, "memoryLeaveIUnchanged": false, "shift": false, "vblank": true, "wrap": false}},
+	{ID: "superchip", Name: "Modern SUPER-CHIP", Quirks: map[string]bool{"jump": true, "logic": false, "memoryIncrementByX": false, "memoryLeaveIUnchanged": true, "shift": true, "vblank": false, "wrap": false}},
+}
+
+var embeddedPrograms = []Program{
+	{
+		Title:       "BC_test",
+		Description: "Tests the arithmetic and flow control opcodes, showing BON on success or an error code.",
+		Authors:     []string{"BestCoder"},
+		ROMs: map[string]ROM{
+			"9df1689015a0d1d95144f141903296f9f1c35fc5": {
+				File:      "BC_test.ch8",
+				Platforms: []string{"superchip"},
+				Tickrate:  15,
+			},
+		},
+	},
+	{
+		Title:       "Cave",
+		Description: "Steer through a winding cave without touching the walls.",
+		ROMs: map[string]ROM{
+			"5c82520906073287a3ef781746c67207ca084d93": {
+				File:      "CAVE",
+				Platforms: []string{"superchip"},
+				Tickrate:  15,
+				Keys:      map[string]int{"a": 15, "down": 8, "left": 4, "right": 6, "up": 2},
+				Colors:    &Colors{Pixels: []string{"#1A1008", "#E0B060"}, Buzzer: "", Silence: ""},
+			},
+		},
+	},
+	{
+		Title:       "IBM Logo",
+		Description: "Draws the IBM logo, the usual first test of a new interpreter.",
+		ROMs: map[string]ROM{
+			"1ba58656810b67fd131eb9af3e3987863bf26c90": {
+				File:      "IBM_Logo.ch8",
+				Platforms: []string{"originalChip8"},
+				Tickrate:  15,
+				Colors:    &Colors{Pixels: []string{"#000000", "#1F70C1"}, Buzzer: "", Silence: ""},
+			},
+		},
+	},
+	{
+		Title:       "Opcode test",
+		Description: "Checks the opcodes one by one and shows OK or NO next to each.",
+		Authors:     []string{"corax89"},
+		ROMs: map[string]ROM{
+			"f1cfcffe1937ed6dd6eeed1a7f85dfc777bda700": {
+				File:      "test_opcode.ch8",
+				Platforms: []string{"superchip"},
+				Tickrate:  15,
+			},
+		},
+	},
+	{
+		Title:       "Pong (1 player)",
+		Description: "Single player Pong against the computer, move the paddle with 1 and 4.",
+		Release:     "1990",
+		Authors:     []string{"Paul Vervalin"},
+		ROMs: map[string]ROM{
+			"1830eb401ba8789a477dfcf294873a5479ebcfe8": {
+				File:      "PONG",
+				Platforms: []string{"superchip"},
+				Tickrate:  12,
+				Keys:      map[string]int{"down": 4, "player2Down": 13, "player2Up": 12, "up": 1},
+			},
+		},
+	},
+	{
+		Title:       "Tank",
+		Description: "Drive a tank around the screen and shoot the moving target.",
+		ROMs: map[string]ROM{
+			"18b9d15f4c159e1f0ed58c2d8ec1d89325d3a3b6": {
+				File:      "TANK",
+				Platforms: []string{"superchip"},
+				Tickrate:  12,
+				Keys:      map[string]int{"a": 5, "down": 8, "left": 4, "right": 6, "up": 2},
+			},
+		},
+	},
+	{
+		Title:       "Tetris",
+		Description: "Falling blocks: 5 and 6 move the piece, 4 rotates it.",
+		Release:     "1991",
+		Authors:     []string{"Fran Dachille"},
+		ROMs: map[string]ROM{
+			"5f518084744bf3cb8733f6e5454dfd1634320563": {
+				File:      "TETRIS",
+				Platforms: []string{"superchip"},
+				Tickrate:  10,
+				Keys:      map[string]int{"a": 4, "left": 5, "right": 6},
+			},
+		},
+	},
+}
@@ -0,0 +1,89 @@
+//go:build ignore
+
+// Compiles programs.json and platforms.json into embedded.go, run with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+)
+
+func main() {
+	programs, err := os.ReadFile("programs.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	platforms, err := os.ReadFile("platforms.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := romdb.Parse(programs, platforms)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go from programs.json and platforms.json. DO NOT EDIT.\n\n")
+	buf.WriteString("package romdb\n\n")
+
+	buf.WriteString("var embeddedPlatforms = []Platform{\n")
+	for _, platform := range db.Platforms() {
+		fmt.Fprintf(&buf, "{ID: %q, Name: %q, Quirks: %s},\n", platform.ID, platform.Name, literal(platform.Quirks))
+	}
+	buf.WriteString("}\n\n")
+
+	buf.WriteString("var embeddedPrograms = []Program{\n")
+	for _, program := range db.Programs() {
+		buf.WriteString("{\n")
+		fmt.Fprintf(&buf, "Title: %q,\n", program.Title)
+		if program.Description != "" {
+			fmt.Fprintf(&buf, "Description: %q,\n", program.Description)
+		}
+		if program.Release != "" {
+			fmt.Fprintf(&buf, "Release: %q,\n", program.Release)
+		}
+		if program.Authors != nil {
+			fmt.Fprintf(&buf, "Authors: %s,\n", literal(program.Authors))
+		}
+		buf.WriteString("ROMs: map[string]ROM{\n")
+		for hash, rom := range program.ROMs {
+			fmt.Fprintf(&buf, "%q: {\n", hash)
+			fmt.Fprintf(&buf, "File: %q,\n", rom.File)
+			fmt.Fprintf(&buf, "Platforms: %s,\n", literal(rom.Platforms))
+			if rom.QuirkyPlatforms != nil {
+				fmt.Fprintf(&buf, "QuirkyPlatforms: %s,\n", literal(rom.QuirkyPlatforms))
+			}
+			if rom.Tickrate != 0 {
+				fmt.Fprintf(&buf, "Tickrate: %d,\n", rom.Tickrate)
+			}
+			if rom.Keys != nil {
+				fmt.Fprintf(&buf, "Keys: %s,\n", literal(rom.Keys))
+			}
+			if rom.Colors != nil {
+				fmt.Fprintf(&buf, "Colors: &%s,\n", literal(*rom.Colors))
+			}
+			buf.WriteString("},\n")
+		}
+		buf.WriteString("},\n},\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("embedded.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Go syntax for maps, slices and structs without pointers, maps come out sorted
+func literal(val interface{}) string {
+	return strings.ReplaceAll(fmt.Sprintf("%#v", val), "romdb.", "")
+}
@@ -0,0 +1,33 @@
+package romdb
+
+import (
+	"fmt"
+
+	"github.com/yuvrajchettri/chip-8-emulator/wasmjson"
+)
+
+// ------------------------------------------------
+// Reading the database from JSON, with wasmjson so the WASM build can too
+// ------------------------------------------------
+
+// Parse reads a database from the contents of programs.json and platforms.json
+func Parse(programs, platforms []byte) (*Database, error) {
+	var programList []Program
+	if err := wasmjson.Unmarshal(programs, &programList); err != nil {
+		return nil, fmt.Errorf("programs: %w", err)
+	}
+	var platformList []Platform
+	if err := wasmjson.Unmarshal(platforms, &platformList); err != nil {
+		return nil, fmt.Errorf("platforms: %w", err)
+	}
+	return New(programList, platformList), nil
+}
+
+// WithOverrides is WithPrograms with the programs in programs.json format
+func (db *Database) WithOverrides(programs []byte) (*Database, error) {
+	var programList []Program
+	if err := wasmjson.Unmarshal(programs, &programList); err != nil {
+		return nil, fmt.Errorf("programs: %w", err)
+	}
+	return db.WithPrograms(programList), nil
+}
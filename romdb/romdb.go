@@ -0,0 +1,336 @@
+package romdb
+
+import (
+	"cmp"
+	"crypto/sha1"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// ROM metadata database.
+// ROMs are identified by the SHA-1 of their bytes. The files use the schema
+// of the community chip-8-database (programs.json and platforms.json), so
+// entries can be copied from it, or the whole database dropped in as
+// overrides. Only the fields below are read, the rest are ignored.
+//
+// The embedded database is compiled in from programs.json and
+// platforms.json by go generate rather than parsed at run time. The WASM
+// build links no encoding/json at all, importing it adds megabytes to the
+// binary: this package, cart and bundle decode their JSON with wasmjson,
+// which is encoding/json natively and JSON.parse in the browser, and their
+// encoders and the command-line-only code are tagged out of the WASM build.
+// ------------------------------------------------
+
+//go:generate go run gen.go
+
+// Program is an entry of programs.json
+type Program struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Release     string         `json:"release,omitempty"`
+	Authors     []string       `json:"authors,omitempty"`
+	ROMs        map[string]ROM `json:"roms"` // By SHA-1
+}
+
+// ROM is one version of a program
+type ROM struct {
+	File            string                     `json:"file,omitempty"`
+	Platforms       []string                   `json:"platforms,omitempty"` // Platform IDs, most suitable first
+	QuirkyPlatforms map[string]map[string]bool `json:"quirkyPlatforms,omitempty"`
+	Tickrate        int                        `json:"tickrate,omitempty"` // Instructions per 60 Hz frame
+	Keys            map[string]int             `json:"keys,omitempty"`     // e.g. "up", "a", "player2Down"
+	Colors          *Colors                    `json:"colors,omitempty"`
+}
+
+// Colors are hex colours such as "#FF0000"
+type Colors struct {
+	Pixels  []string `json:"pixels,omitempty"` // Background, then foreground
+	Buzzer  string   `json:"buzzer,omitempty"`
+	Silence string   `json:"silence,omitempty"`
+}
+
+// Platform is an entry of platforms.json. Only the shift and jump quirks
+// have an equivalent in chip8.Quirks.
+type Platform struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Quirks map[string]bool `json:"quirks"`
+}
+
+// ------------------------------------------------
+// Entry is what the database knows about a ROM
+// ------------------------------------------------
+type Entry struct {
+	Hash        string
+	Title       string
+	Description string
+	Authors     []string
+	Release     string
+	ROM         ROM
+	Platform    Platform // The first of ROM.Platforms the database knows, zero if none
+}
+
+// Database maps ROM hashes to their program and platform
+type Database struct {
+	platforms map[string]Platform
+	programs  map[string]Program // By ROM SHA-1
+}
+
+// New creates a database from the entries of programs.json and platforms.json
+func New(programs []Program, platforms []Platform) *Database {
+	db := &Database{platforms: make(map[string]Platform), programs: make(map[string]Program)}
+	for _, platform := range platforms {
+		db.platforms[platform.ID] = platform
+	}
+	db.addPrograms(programs, false)
+	return db
+}
+
+func (db *Database) addPrograms(programs []Program, merge bool) {
+	for _, program := range programs {
+		for hash, rom := range program.ROMs {
+			hash = strings.ToLower(hash)
+			entry := program
+			if old, ok := db.programs[hash]; ok && merge {
+				entry = mergeProgram(old, program)
+				rom = mergeROM(old.ROMs[hash], rom)
+			}
+			// Each hash gets a copy of the program with just its own ROM
+			entry.ROMs = map[string]ROM{hash: rom}
+			db.programs[hash] = entry
+		}
+	}
+}
+
+// Fields set in the override replace the original's
+func mergeProgram(old, override Program) Program {
+	if override.Title == "" {
+		override.Title = old.Title
+	}
+	if override.Description == "" {
+		override.Description = old.Description
+	}
+	if override.Release == "" {
+		override.Release = old.Release
+	}
+	if override.Authors == nil {
+		override.Authors = old.Authors
+	}
+	return override
+}
+
+func mergeROM(old, override ROM) ROM {
+	if override.File == "" {
+		override.File = old.File
+	}
+	if override.Platforms == nil {
+		override.Platforms = old.Platforms
+	}
+	if override.QuirkyPlatforms == nil {
+		override.QuirkyPlatforms = old.QuirkyPlatforms
+	}
+	if override.Tickrate == 0 {
+		override.Tickrate = old.Tickrate
+	}
+	if override.Keys == nil {
+		override.Keys = old.Keys
+	}
+	if override.Colors == nil {
+		override.Colors = old.Colors
+	}
+	return override
+}
+
+// ------------------------------------------------
+// WithPrograms returns a copy of the database with the programs laid over
+// it. Fields a program leaves out keep their value, ROMs the database
+// doesn't know are added.
+// ------------------------------------------------
+func (db *Database) WithPrograms(programs []Program) *Database {
+	merged := &Database{platforms: db.platforms, programs: maps.Clone(db.programs)}
+	merged.addPrograms(programs, true)
+	return merged
+}
+
+var defaultDB = sync.OnceValue(func() *Database {
+	return New(embeddedPrograms, embeddedPlatforms)
+})
+
+// Programs returns one program per ROM, each with only that ROM, sorted by
+// title and then hash
+func (db *Database) Programs() []Program {
+	programs := slices.Collect(maps.Values(db.programs))
+	slices.SortFunc(programs, func(a, b Program) int {
+		return cmp.Or(strings.Compare(a.Title, b.Title), strings.Compare(a.hash(), b.hash()))
+	})
+	return programs
+}
+
+// The hash of a program with a single ROM
+func (program Program) hash() string {
+	for hash := range program.ROMs {
+		return hash
+	}
+	return ""
+}
+
+// Platforms returns the platforms sorted by ID
+func (db *Database) Platforms() []Platform {
+	platforms := slices.Collect(maps.Values(db.platforms))
+	slices.SortFunc(platforms, func(a, b Platform) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return platforms
+}
+
+// Default returns the database embedded in the binary
+func Default() *Database {
+	return defaultDB()
+}
+
+// Hash returns the lowercase hex SHA-1 ROMs are identified by
+func Hash(rom []byte) string {
+	sum := sha1.Sum(rom)
+	return hex.EncodeToString(sum[:])
+}
+
+func (db *Database) Lookup(rom []byte) (Entry, bool) {
+	return db.LookupHash(Hash(rom))
+}
+
+func (db *Database) LookupHash(hash string) (Entry, bool) {
+	hash = strings.ToLower(hash)
+	program, ok := db.programs[hash]
+	if !ok {
+		return Entry{}, false
+	}
+
+	entry := Entry{
+		Hash:        hash,
+		Title:       program.Title,
+		Description: program.Description,
+		Authors:     program.Authors,
+		Release:     program.Release,
+		ROM:         program.ROMs[hash],
+	}
+	for _, id := range entry.ROM.Platforms {
+		if platform, ok := db.platforms[id]; ok {
+			entry.Platform = platform
+			break
+		}
+	}
+	return entry, true
+}
+
+// ------------------------------------------------
+// Options lays the settings the database recommends for the ROM over opts,
+// opts is returned unchanged for unknown ROMs
+// ------------------------------------------------
+func (db *Database) Options(rom []byte, opts chip8.Options) chip8.Options {
+	if entry, ok := db.Lookup(rom); ok {
+		return entry.Options(opts)
+	}
+	return opts
+}
+
+// Options lays the entry's quirks and speed over opts
+func (e Entry) Options(opts chip8.Options) chip8.Options {
+	if e.Platform.ID != "" {
+		opts.Quirks = e.Quirks()
+	}
+	if speed := e.Speed(); speed != 0 {
+		opts.Speed = speed
+	}
+	return opts
+}
+
+// ------------------------------------------------
+// NewMachine loads rom, a plain ROM or any registered container format,
+// into a new machine with opts and the settings the database recommends.
+// The program is looked up by its own bytes, so ROMs in bundles and
+// cartridges find their entries too, and the database's settings, the
+// user's overrides included, win over the ones the container carries. The
+// entry is zero for programs the database doesn't know.
+// ------------------------------------------------
+func (db *Database) NewMachine(rom []byte, opts chip8.Options) (*chip8.Chip8, Entry, error) {
+	machine := chip8.New(opts)
+	if err := machine.LoadROM(rom); err != nil {
+		return nil, Entry{}, err
+	}
+	entry, ok := db.Lookup(machine.ROM())
+	if ok {
+		recommended := entry.Options(chip8.Options{Speed: machine.Speed(), Quirks: machine.Quirks()})
+		machine.SetSpeed(recommended.Speed)
+		machine.SetQuirks(recommended.Quirks)
+	}
+	return machine, entry, nil
+}
+
+// ------------------------------------------------
+// Settings lays the entry's title, speed, quirks, keys and colours over the
+// ones of the cartridge the program came in, the zero Cartridge for a plain
+// ROM. Fields the database leaves out keep the cartridge's, so in order of
+// precedence a front-end's settings are its own flags, the database's and
+// the cartridge's.
+// ------------------------------------------------
+func (e Entry) Settings(cart chip8.Cartridge) chip8.Cartridge {
+	cart.Title = cmp.Or(e.Title, cart.Title)
+	cart.Speed = cmp.Or(e.Speed(), cart.Speed)
+	cart.Palette = cmp.Or(e.Palette(), cart.Palette)
+	if e.Platform.ID != "" {
+		quirks := e.Quirks()
+		cart.Quirks = &quirks
+	}
+	if e.ROM.Keys != nil {
+		cart.Keys = e.Keys()
+	}
+	return cart
+}
+
+// ------------------------------------------------
+// Quirks returns the platform's quirks with the ROM's own on top. The
+// database's shift and jump quirks describe the SUPER-CHIP behaviour,
+// chip8.Quirks the COSMAC VIP one, so they are inverted.
+// ------------------------------------------------
+func (e Entry) Quirks() chip8.Quirks {
+	quirk := func(name string) bool {
+		if val, ok := e.ROM.QuirkyPlatforms[e.Platform.ID][name]; ok {
+			return val
+		}
+		return e.Platform.Quirks[name]
+	}
+	return chip8.Quirks{Shift: !quirk("shift"), Jump: !quirk("jump")}
+}
+
+// Speed returns the recommended instructions per second, 0 if unknown
+func (e Entry) Speed() int {
+	return e.ROM.Tickrate * chip8.TIMER_HZ
+}
+
+// Keys returns the keys the ROM uses by their role, e.g. "up" or "a"
+func (e Entry) Keys() map[string]chip8.Key {
+	keys := make(map[string]chip8.Key, len(e.ROM.Keys))
+	for role, key := range e.ROM.Keys {
+		if key >= 0 && key <= 0xF {
+			keys[role] = chip8.Key(key)
+		}
+	}
+	return keys
+}
+
+// ------------------------------------------------
+// Palette returns the recommended colours as a "BACKGROUND,FOREGROUND"
+// spec for render.ParsePalette, or "" if the ROM has none
+// ------------------------------------------------
+func (e Entry) Palette() string {
+	if e.ROM.Colors == nil || len(e.ROM.Colors.Pixels) < 2 {
+		return ""
+	}
+	return e.ROM.Colors.Pixels[0] + "," + e.ROM.Colors.Pixels[1]
+}
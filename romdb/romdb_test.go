@@ -0,0 +1,199 @@
+//go:build !js && !wasm
+
+package romdb
+
+import (
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/bundle"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func readROM(t *testing.T, name string) []byte {
+	rom, err := os.ReadFile(filepath.Join("..", "roms", name))
+	require.NoError(t, err)
+	return rom
+}
+
+func TestDefault_BundledROMs(t *testing.T) {
+	for _, name := range []string{"PONG", "TANK", "TETRIS", "CAVE", "IBM_Logo.ch8", "BC_test.ch8", "test_opcode.ch8"} {
+		entry, ok := Default().Lookup(readROM(t, name))
+		require.True(t, ok, name)
+		require.Equal(t, name, entry.ROM.File)
+		require.NotEmpty(t, entry.Platform.ID, name)
+		require.NotZero(t, entry.Speed(), name)
+	}
+
+	_, ok := Default().Lookup([]byte{0x12, 0x00})
+	require.False(t, ok)
+}
+
+func TestHash(t *testing.T) {
+	require.Equal(t, "1830eb401ba8789a477dfcf294873a5479ebcfe8", Hash(readROM(t, "PONG")))
+}
+
+func TestDefault_MatchesJSON(t *testing.T) {
+	programs, err := os.ReadFile("programs.json")
+	require.NoError(t, err)
+	platforms, err := os.ReadFile("platforms.json")
+	require.NoError(t, err)
+
+	db, err := Parse(programs, platforms)
+	require.NoError(t, err)
+	require.Equal(t, db.Programs(), Default().Programs(), "embedded.go is out of date, run go generate ./romdb")
+	require.Equal(t, db.Platforms(), Default().Platforms(), "embedded.go is out of date, run go generate ./romdb")
+}
+
+func TestEntry_Settings(t *testing.T) {
+	entry, ok := Default().Lookup(readROM(t, "TANK"))
+	require.True(t, ok)
+	require.Equal(t, "Tank", entry.Title)
+	require.Equal(t, 720, entry.Speed())
+	require.Equal(t, chip8.Quirks{}, entry.Quirks())
+	require.Equal(t, chip8.KEY_5, entry.Keys()["a"])
+	require.Equal(t, "", entry.Palette())
+
+	opts := entry.Options(chip8.Options{Speed: 700, FadeFrames: 3})
+	require.Equal(t, chip8.Options{Speed: 720, FadeFrames: 3}, opts)
+
+	entry, ok = Default().Lookup(readROM(t, "IBM_Logo.ch8"))
+	require.True(t, ok)
+	require.Equal(t, chip8.Quirks{Shift: true, Jump: true}, entry.Quirks())
+	require.Equal(t, "#000000,#1F70C1", entry.Palette())
+}
+
+func TestParse_CommunitySchema(t *testing.T) {
+	programs := `[{
+		"title": "Game",
+		"authors": ["Someone"],
+		"images": ["game.png"],
+		"roms": {
+			"ABCDEF": {
+				"file": "game.ch8",
+				"platforms": ["unknownPlatform", "vip"],
+				"quirkyPlatforms": {"vip": {"jump": true}},
+				"tickrate": 30,
+				"keys": {"up": 2, "down": 99},
+				"screenRotation": 0
+			}
+		}
+	}]`
+	platforms := `[{"id": "vip", "name": "VIP", "quirks": {"shift": true, "jump": false, "vblank": true}}]`
+
+	db, err := Parse([]byte(programs), []byte(platforms))
+	require.NoError(t, err)
+
+	entry, ok := db.LookupHash("abcdef")
+	require.True(t, ok)
+	require.Equal(t, "vip", entry.Platform.ID)
+	require.Equal(t, chip8.Quirks{Shift: false, Jump: false}, entry.Quirks())
+	require.Equal(t, 1800, entry.Speed())
+	require.Equal(t, map[string]chip8.Key{"up": chip8.KEY_2}, entry.Keys())
+
+	_, err = Parse([]byte(`{}`), []byte(platforms))
+	require.Error(t, err)
+}
+
+func TestWithOverrides(t *testing.T) {
+	rom := readROM(t, "PONG")
+	hash := Hash(rom)
+
+	overrides := `[
+		{"roms": {"` + hash + `": {"tickrate": 20}}},
+		{"title": "New", "roms": {"0123": {"platforms": ["superchip"]}}}
+	]`
+	db, err := Default().WithOverrides([]byte(overrides))
+	require.NoError(t, err)
+
+	// Only the fields set in the override change
+	entry, ok := db.Lookup(rom)
+	require.True(t, ok)
+	require.Equal(t, 1200, entry.Speed())
+	require.Equal(t, "Pong (1 player)", entry.Title)
+	require.Equal(t, chip8.KEY_1, entry.Keys()["up"])
+
+	entry, ok = db.LookupHash("0123")
+	require.True(t, ok)
+	require.Equal(t, "New", entry.Title)
+
+	// The default database is left alone
+	entry, _ = Default().Lookup(rom)
+	require.Equal(t, 720, entry.Speed())
+	_, ok = Default().LookupHash("0123")
+	require.False(t, ok)
+
+	require.Equal(t, chip8.Options{Speed: 1200}, db.Options(rom, chip8.Options{Speed: 700}))
+	require.Equal(t, chip8.Options{Speed: 700}, db.Options([]byte{0}, chip8.Options{Speed: 700}))
+}
+
+// Bundles are looked up by the ROM inside, and the database wins over the
+// settings they carry
+func TestNewMachine_Bundle(t *testing.T) {
+	pong := readROM(t, "PONG.bundle.png")
+	machine, entry, err := Default().NewMachine(pong, chip8.Options{Speed: 700})
+	require.NoError(t, err)
+	require.Equal(t, Hash(readROM(t, "PONG")), entry.Hash)
+	require.Equal(t, 720, machine.Speed())
+
+	db, err := Default().WithOverrides([]byte(`[{"roms": {"` + entry.Hash + `": {
+		"tickrate": 20, "keys": {"a": 7}, "colors": {"pixels": ["#111111", "#EEEEEE"]}
+	}}}]`))
+	require.NoError(t, err)
+	machine, entry, err = db.NewMachine(pong, chip8.Options{Speed: 700})
+	require.NoError(t, err)
+	require.Equal(t, 1200, machine.Speed())
+
+	cart, ok := machine.Cartridge()
+	require.True(t, ok)
+	settings := entry.Settings(cart)
+	require.Equal(t, 1200, settings.Speed)
+	require.Equal(t, "#111111,#EEEEEE", settings.Palette)
+	require.Equal(t, map[string]chip8.Key{"a": 7}, settings.Keys)
+	require.Equal(t, cart.Title, settings.Title)
+
+	// ROMs the database doesn't know keep the bundle's settings
+	var buf bytes.Buffer
+	require.NoError(t, bundle.Encode(&buf, bundle.Bundle{Title: "Loop", Speed: 900, Palette: "#000000,#FFFFFF", ROM: []byte{0x12, 0x00}}, image.NewGray(image.Rect(0, 0, 1, 1))))
+	machine, entry, err = db.NewMachine(buf.Bytes(), chip8.Options{Speed: 700})
+	require.NoError(t, err)
+	require.Empty(t, entry.Hash)
+	require.Equal(t, 900, machine.Speed())
+	cart, _ = machine.Cartridge()
+	require.Equal(t, cart, entry.Settings(cart))
+
+	_, _, err = db.NewMachine(make([]byte, chip8.RAM), chip8.Options{})
+	require.Error(t, err)
+}
+
+func TestLoadUser(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+
+	db, err := LoadUser()
+	require.NoError(t, err)
+	require.Same(t, Default(), db)
+
+	path, err := UserOverridesPath()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(`[{"roms": {"0123": {"tickrate": 5}}}]`), 0o644))
+
+	db, err = LoadUser()
+	require.NoError(t, err)
+	entry, ok := db.LookupHash("0123")
+	require.True(t, ok)
+	require.Equal(t, 300, entry.Speed())
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o644))
+	db, err = LoadUser()
+	require.Error(t, err)
+	require.Same(t, Default(), db)
+}
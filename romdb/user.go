@@ -0,0 +1,43 @@
+//go:build !js && !wasm
+
+package romdb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// UserOverridesPath is where LoadUser looks for the user's overrides
+func UserOverridesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "chip8", "romdb.json"), nil
+}
+
+// ------------------------------------------------
+// LoadUser returns the embedded database with the user's overrides from
+// UserOverridesPath laid over it, or just the embedded one if there are none
+// ------------------------------------------------
+func LoadUser() (*Database, error) {
+	path, err := UserOverridesPath()
+	if err != nil {
+		return Default(), nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	} else if err != nil {
+		return Default(), err
+	}
+
+	db, err := Default().WithOverrides(data)
+	if err != nil {
+		return Default(), fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
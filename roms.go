@@ -1,17 +1,19 @@
 package main
 
-import "embed"
+import (
+	"fmt"
 
-//go:embed roms/PONG roms/TANK roms/TETRIS
-var embeddedROMs embed.FS
-
-// GetROMBytes returns the bytes of the specified ROM file
-func GetROMBytes(romName string) ([]byte, error) {
-	return embeddedROMs.ReadFile("roms/" + romName)
-}
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+)
 
 // DefaultROM is the ROM that will be loaded if no argument is provided
 const DefaultROM = "PONG"
 
-// ValidROMs contains the list of available ROMs
-var ValidROMs = []string{"PONG", "TANK", "TETRIS"}
+// namedROM returns the bytes of one of the ROMs in the catalogue, see
+// package roms
+func namedROM(romName string) ([]byte, error) {
+	if _, ok := roms.Lookup(romName); !ok {
+		return nil, fmt.Errorf("Invalid ROM name. Available ROMs: %v", roms.Names())
+	}
+	return roms.Load(romName)
+}
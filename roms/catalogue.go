@@ -0,0 +1,87 @@
+// Code generated by gen.go from the ROMs in this directory. DO NOT EDIT.
+
+package roms
+
+import (
+	"embed"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+//go:embed BC_test.ch8 CAVE IBM_Logo.ch8 PONG PONG.bundle.png TANK TANK.bundle.png TETRIS TETRIS.bundle.png test_opcode.ch8
+var files embed.FS
+
+var catalogue = []ROM{
+	{
+		Name:        "BC_test.ch8",
+		File:        "BC_test.ch8",
+		Size:        470,
+		Hash:        "9df1689015a0d1d95144f141903296f9f1c35fc5",
+		Title:       "BC_test",
+		Description: "Tests the arithmetic and flow control opcodes, showing BON on success or an error code.",
+		Speed:       900,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+	},
+	{
+		Name:        "CAVE",
+		File:        "CAVE",
+		Size:        882,
+		Hash:        "5c82520906073287a3ef781746c67207ca084d93",
+		Title:       "Cave",
+		Description: "Steer through a winding cave without touching the walls.",
+		Speed:       900,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+		Palette:     "#1A1008,#E0B060",
+	},
+	{
+		Name:        "IBM_Logo.ch8",
+		File:        "IBM_Logo.ch8",
+		Size:        132,
+		Hash:        "1ba58656810b67fd131eb9af3e3987863bf26c90",
+		Title:       "IBM Logo",
+		Description: "Draws the IBM logo, the usual first test of a new interpreter.",
+		Speed:       900,
+		Quirks:      chip8.Quirks{Shift: true, Jump: true},
+		Palette:     "#000000,#1F70C1",
+	},
+	{
+		Name:        "PONG",
+		File:        "PONG.bundle.png",
+		Size:        294,
+		Hash:        "1830eb401ba8789a477dfcf294873a5479ebcfe8",
+		Title:       "Pong (1 player)",
+		Description: "Single player Pong against the computer, move the paddle with 1 and 4.",
+		Speed:       720,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+	},
+	{
+		Name:        "TANK",
+		File:        "TANK.bundle.png",
+		Size:        560,
+		Hash:        "18b9d15f4c159e1f0ed58c2d8ec1d89325d3a3b6",
+		Title:       "Tank",
+		Description: "Drive a tank around the screen and shoot the moving target.",
+		Speed:       720,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+	},
+	{
+		Name:        "TETRIS",
+		File:        "TETRIS.bundle.png",
+		Size:        494,
+		Hash:        "5f518084744bf3cb8733f6e5454dfd1634320563",
+		Title:       "Tetris",
+		Description: "Falling blocks: 5 and 6 move the piece, 4 rotates it.",
+		Speed:       600,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+	},
+	{
+		Name:        "test_opcode.ch8",
+		File:        "test_opcode.ch8",
+		Size:        478,
+		Hash:        "f1cfcffe1937ed6dd6eeed1a7f85dfc777bda700",
+		Title:       "Opcode test",
+		Description: "Checks the opcodes one by one and shows OK or NO next to each.",
+		Speed:       900,
+		Quirks:      chip8.Quirks{Shift: false, Jump: false},
+	},
+}
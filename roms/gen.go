@@ -0,0 +1,63 @@
+//go:build ignore
+
+// Catalogues the ROMs in this directory into catalogue.go, run with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+)
+
+func main() {
+	list, err := roms.Scan(os.DirFS("."), romdb.Default())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var files []string
+	for _, rom := range list {
+		files = append(files, rom.Name)
+		if rom.File != rom.Name {
+			files = append(files, rom.File)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go from the ROMs in this directory. DO NOT EDIT.\n\n")
+	buf.WriteString("package roms\n\n")
+	buf.WriteString("import (\n\"embed\"\n\n\"github.com/yuvrajchettri/chip-8-emulator/chip8\"\n)\n\n")
+	fmt.Fprintf(&buf, "//go:embed %s\n", strings.Join(files, " "))
+	buf.WriteString("var files embed.FS\n\n")
+
+	buf.WriteString("var catalogue = []ROM{\n")
+	for _, rom := range list {
+		fmt.Fprintf(&buf, "{\nName: %q,\nFile: %q,\nSize: %d,\nHash: %q,\nTitle: %q,\n", rom.Name, rom.File, rom.Size, rom.Hash, rom.Title)
+		if rom.Description != "" {
+			fmt.Fprintf(&buf, "Description: %q,\n", rom.Description)
+		}
+		if rom.Speed != 0 {
+			fmt.Fprintf(&buf, "Speed: %d,\n", rom.Speed)
+		}
+		fmt.Fprintf(&buf, "Quirks: chip8.Quirks{Shift: %t, Jump: %t},\n", rom.Quirks.Shift, rom.Quirks.Jump)
+		if rom.Palette != "" {
+			fmt.Fprintf(&buf, "Palette: %q,\n", rom.Palette)
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("catalogue.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package roms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/yuvrajchettri/chip-8-emulator/bundle"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// Loading bundles needs package bundle, which registers their format
+func TestLoad(t *testing.T) {
+	for _, rom := range List() {
+		data, err := Load(rom.Name)
+		require.NoError(t, err, rom.Name)
+
+		// Bundles run at their recommended settings too
+		machine := chip8.New(chip8.Options{Speed: 1})
+		require.NoError(t, machine.LoadROM(data), rom.Name)
+		require.Len(t, machine.ROM(), rom.Size, rom.Name)
+		if rom.File != rom.Name {
+			require.Equal(t, rom.Speed, machine.Speed(), rom.Name)
+			require.Equal(t, rom.Quirks, machine.Quirks(), rom.Name)
+		}
+	}
+
+	_, err := Load("MISSING")
+	require.EqualError(t, err, `unknown ROM "MISSING"`)
+}
@@ -0,0 +1,107 @@
+// Package roms embeds the ROMs in this directory along with a catalogue of
+// them, generated with their recommended settings from the ROM database.
+// Drop a ROM in the directory and run go generate ./roms to add it.
+package roms
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+)
+
+//go:generate go run gen.go
+
+const bundleSuffix = ".bundle.png"
+
+// ROM is a catalogue entry
+type ROM struct {
+	Name        string // File name of the ROM
+	File        string // File to load: the ROM's bundle if it has one, else the ROM itself
+	Size        int    // Of the ROM, in bytes
+	Hash        string // SHA-1, see romdb.Hash
+	Title       string // The ROM database's, Name if unknown
+	Description string
+
+	// Recommended settings, zero if the ROM database doesn't know the ROM
+	Speed   int // Instructions per second
+	Quirks  chip8.Quirks
+	Palette string // "BACKGROUND,FOREGROUND" hex colours, "" if the ROM has none
+}
+
+// List returns the catalogue sorted by name
+func List() []ROM {
+	return slices.Clone(catalogue)
+}
+
+// Names returns the names of the catalogued ROMs
+func Names() []string {
+	names := make([]string, len(catalogue))
+	for i, rom := range catalogue {
+		names[i] = rom.Name
+	}
+	return names
+}
+
+// Lookup returns the catalogue entry of the ROM called name
+func Lookup(name string) (ROM, bool) {
+	i := slices.IndexFunc(catalogue, func(rom ROM) bool { return rom.Name == name })
+	if i < 0 {
+		return ROM{}, false
+	}
+	return catalogue[i], true
+}
+
+// Load returns the File of a catalogued ROM, chip8.LoadROM loads bundles
+// like ROMs
+func Load(name string) ([]byte, error) {
+	rom, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown ROM %q", name)
+	}
+	return files.ReadFile(rom.File)
+}
+
+// ------------------------------------------------
+// Scan catalogues the ROMs in a directory, recommending the settings db
+// knows for them. Go files are skipped and NAME.bundle.png files are the
+// bundles of the ROM NAME, see package bundle.
+// ------------------------------------------------
+func Scan(dir fs.FS, db *romdb.Database) ([]ROM, error) {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ROM
+	for _, file := range entries {
+		name := file.Name()
+		if file.IsDir() || path.Ext(name) == ".go" || strings.HasSuffix(name, bundleSuffix) {
+			continue
+		}
+		data, err := fs.ReadFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+
+		rom := ROM{Name: name, File: name, Size: len(data), Hash: romdb.Hash(data), Title: name}
+		if _, err := fs.Stat(dir, name+bundleSuffix); err == nil {
+			rom.File = name + bundleSuffix
+		}
+		if entry, ok := db.LookupHash(rom.Hash); ok {
+			rom.Title = entry.Title
+			rom.Description = entry.Description
+			rom.Speed = entry.Speed()
+			if entry.Platform.ID != "" {
+				rom.Quirks = entry.Quirks()
+			}
+			rom.Palette = entry.Palette()
+		}
+		list = append(list, rom)
+	}
+	return list, nil
+}
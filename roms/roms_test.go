@@ -0,0 +1,32 @@
+package roms
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+)
+
+func TestCatalogue_UpToDate(t *testing.T) {
+	scanned, err := Scan(os.DirFS("."), romdb.Default())
+	require.NoError(t, err)
+	require.Equal(t, scanned, List(), "catalogue.go is out of date, run go generate ./roms")
+}
+
+func TestLookup(t *testing.T) {
+	rom, ok := Lookup("PONG")
+	require.True(t, ok)
+	require.Equal(t, "Pong (1 player)", rom.Title)
+	require.Equal(t, "PONG.bundle.png", rom.File)
+	require.Equal(t, 720, rom.Speed)
+	require.Contains(t, Names(), "CAVE")
+
+	// ROMs the database doesn't know are listed under their file name
+	cave, ok := Lookup("CAVE")
+	require.True(t, ok)
+	list, err := Scan(os.DirFS("."), romdb.New(nil, nil))
+	require.NoError(t, err)
+	require.Contains(t, list, ROM{Name: "CAVE", File: "CAVE", Size: cave.Size, Hash: cave.Hash, Title: "CAVE"})
+}
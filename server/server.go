@@ -0,0 +1,540 @@
+// Package server hosts Chip8 machines behind a local HTTP API, so scripts
+// in any language can drive the emulator without linking Go:
+//
+//	GET    /machines                     list the machines
+//	POST   /machines                     create one, see below for the ROM
+//	GET    /machines/{id}                the machine's ROM and frame count
+//	DELETE /machines/{id}                remove it
+//	PUT    /machines/{id}/rom            load another ROM, see below
+//	POST   /machines/{id}/reset          restart the loaded ROM
+//	POST   /machines/{id}/step?n=N       execute N instructions, 1 if omitted
+//	POST   /machines/{id}/run?frames=N   run N 60 Hz frames, 1 if omitted
+//	PUT    /machines/{id}/keys/{key}     press key 0-F
+//	DELETE /machines/{id}/keys/{key}     release it
+//	GET    /machines/{id}/state          registers, stack, timers and keys
+//	GET    /machines/{id}/memory         raw memory, ?addr=A&len=N selects a range
+//	GET    /machines/{id}/display        the display as JSON rows of 0s and 1s
+//	GET    /machines/{id}/display.png    as a PNG, ?scale=N&palette=P as in chip8 record
+//	GET    /machines/{id}/spectate       WebSocket stream of the frames run, see package spectate
+//
+// ROMs are sent as the request body, or named with ?rom=NAME to use one of
+// the built-in ROMs, see package roms. They run with the settings the ROM
+// database recommends unless ?speed=HZ is given, formats registered with
+// chip8.RegisterFormat load too. Machines only run when asked to, so runs
+// are deterministic.
+//
+// Errors are returned as {"error": "message"} with a 4xx or 5xx status.
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/roms"
+	"github.com/yuvrajchettri/chip-8-emulator/spectate"
+)
+
+const (
+	maxROMSize = 1 << 20 // Bundles and cartridges are images, raw ROMs fit in memory
+	maxFrames  = 60 * 60 // Per run request, a minute of emulated time
+	maxSteps   = 1 << 20
+)
+
+// Server is an http.Handler serving the API
+type Server struct {
+	db  *romdb.Database
+	mux *http.ServeMux
+
+	mu       sync.Mutex
+	machines map[string]*machine
+	nextID   int
+}
+
+// A machine is run one frame at a time with the keys the API pressed, it
+// has no input of its own
+type machine struct {
+	mu     sync.Mutex
+	runner *host.Runner
+	hub    *spectate.Hub // Set once, it is safe to use without mu
+	entry  romdb.Entry   // Zero for ROMs the database doesn't know
+	frames int           // Run since the ROM was loaded or reset
+}
+
+// New creates a Server with no machines, ROMs get the settings db has for them
+func New(db *romdb.Database) *Server {
+	s := &Server{db: db, mux: http.NewServeMux(), machines: make(map[string]*machine)}
+
+	s.mux.HandleFunc("GET /machines", s.list)
+	s.mux.HandleFunc("POST /machines", s.create)
+	s.handle("GET /machines/{id}", s.info)
+	s.mux.HandleFunc("DELETE /machines/{id}", s.remove)
+	s.handle("PUT /machines/{id}/rom", s.load)
+	s.handle("POST /machines/{id}/reset", s.reset)
+	s.handle("POST /machines/{id}/step", s.step)
+	s.handle("POST /machines/{id}/run", s.run)
+	s.handle("PUT /machines/{id}/keys/{key}", s.key)
+	s.handle("DELETE /machines/{id}/keys/{key}", s.key)
+	s.handle("GET /machines/{id}/state", s.state)
+	s.handle("GET /machines/{id}/memory", s.memory)
+	s.handle("GET /machines/{id}/display", s.display)
+	s.handle("GET /machines/{id}/display.png", s.displayPNG)
+	s.mux.HandleFunc("GET /machines/{id}/spectate", s.spectate)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ------------------------------------------------
+// Add creates a machine running rom, speedHz overrides the recommended
+// speed unless it is 0. It returns the machine's ID.
+// ------------------------------------------------
+func (s *Server) Add(rom []byte, speedHz int) (string, error) {
+	m := &machine{}
+	if err := s.loadROM(m, rom, speedHz); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	s.machines[id] = m
+	return id, nil
+}
+
+func (s *Server) lookup(id string) (*machine, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.machines[id]
+	return m, ok
+}
+
+// Starts a fresh emulator with rom, the machine is left untouched on errors
+func (s *Server) loadROM(m *machine, rom []byte, speedHz int) error {
+	emulator, entry, err := s.db.NewMachine(rom, chip8.Options{Speed: chip8.DEFAULT_SPEED})
+	if err != nil {
+		return err
+	}
+	if speedHz != 0 {
+		emulator.SetSpeed(speedHz)
+	}
+
+	if m.runner == nil {
+		m.hub = spectate.NewHub(spectate.Info{})
+		m.runner = host.NewRunner(emulator, m.hub.Display(discardDisplay{}, func() *chip8.Chip8 {
+			return m.runner.Machine()
+		}), nil, nil)
+	} else {
+		m.runner.SetMachine(emulator)
+	}
+	m.entry = entry
+	m.frames = 0
+	m.hub.SetInfo(m.spectateInfo())
+	return nil
+}
+
+// Headless host.Display, the API reads the display when asked
+type discardDisplay struct{}
+
+func (discardDisplay) Present(frame [][]int, changed bool) error {
+	return nil
+}
+
+// ------------------------------------------------
+// Request handling
+// ------------------------------------------------
+
+// httpError is an error with the status it is returned with
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+// handle registers a handler for a machine's routes, it runs with the
+// machine locked and its errors are written as JSON
+func (s *Server) handle(pattern string, fn func(w http.ResponseWriter, r *http.Request, m *machine) error) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		m, ok := s.lookup(r.PathValue("id"))
+		if !ok {
+			writeError(w, &httpError{http.StatusNotFound, fmt.Sprintf("no machine %q", r.PathValue("id"))})
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if err := fn(w, r, m); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, val interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(val)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		status = httpErr.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// Reads the ROM of a request: ?rom=NAME or the body
+func readROM(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if name := r.URL.Query().Get("rom"); name != "" {
+		if _, ok := roms.Lookup(name); !ok {
+			return nil, badRequest("unknown ROM %q, available ROMs: %v", name, roms.Names())
+		}
+		return roms.Load(name)
+	}
+
+	rom, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxROMSize))
+	if err != nil {
+		return nil, badRequest("reading the ROM: %v", err)
+	}
+	if len(rom) == 0 {
+		return nil, badRequest("expected a ROM in the request body or ?rom=NAME")
+	}
+	return rom, nil
+}
+
+// Parses the query parameter name as an integer from lo to hi, fallback if absent
+func queryInt(r *http.Request, name string, fallback, lo, hi int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return fallback, nil
+	}
+	val, err := strconv.ParseInt(s, 0, 0)
+	if err != nil || int(val) < lo || int(val) > hi {
+		return 0, badRequest("%s must be a number from %d to %d", name, lo, hi)
+	}
+	return int(val), nil
+}
+
+// ------------------------------------------------
+// Machines
+// ------------------------------------------------
+
+// Summary of a machine, as listed
+type machineInfo struct {
+	ID     string `json:"id"`
+	Title  string `json:"title,omitempty"` // From the cartridge or ROM database
+	Hash   string `json:"hash"`            // SHA-1 of the loaded ROM, see romdb.Hash
+	Format string `json:"format,omitempty"`
+	Frames int    `json:"frames"`
+}
+
+func (m *machine) info(id string) machineInfo {
+	emulator := m.runner.Machine()
+	info := machineInfo{ID: id, Title: m.title(), Hash: romdb.Hash(emulator.ROM()), Frames: m.frames}
+	if cart, ok := emulator.Cartridge(); ok {
+		info.Format = cart.Format
+	}
+	return info
+}
+
+// The ROM database's title, or else the cartridge's
+func (m *machine) title() string {
+	cart, _ := m.runner.Machine().Cartridge()
+	return m.entry.Settings(cart).Title
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.machines))
+	for id := range s.machines {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(ids, cmpIDs)
+
+	list := make([]machineInfo, 0, len(ids))
+	for _, id := range ids {
+		if m, ok := s.lookup(id); ok {
+			m.mu.Lock()
+			list = append(list, m.info(id))
+			m.mu.Unlock()
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// IDs are counted up, shorter ones are older
+func cmpIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	speed, err := queryInt(r, "speed", 0, 0, 1_000_000)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rom, err := readROM(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := s.Add(rom, speed)
+	if err != nil {
+		writeError(w, badRequest("%v", err))
+		return
+	}
+
+	m, _ := s.lookup(id)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Location", "/machines/"+id)
+	writeJSON(w, http.StatusCreated, m.info(id))
+}
+
+func (s *Server) info(w http.ResponseWriter, r *http.Request, m *machine) error {
+	writeJSON(w, http.StatusOK, m.info(r.PathValue("id")))
+	return nil
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	m, ok := s.machines[r.PathValue("id")]
+	delete(s.machines, r.PathValue("id"))
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, &httpError{http.StatusNotFound, fmt.Sprintf("no machine %q", r.PathValue("id"))})
+		return
+	}
+	m.hub.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) load(w http.ResponseWriter, r *http.Request, m *machine) error {
+	speed, err := queryInt(r, "speed", 0, 0, 1_000_000)
+	if err != nil {
+		return err
+	}
+	rom, err := readROM(w, r)
+	if err != nil {
+		return err
+	}
+	if err := s.loadROM(m, rom, speed); err != nil {
+		return badRequest("%v", err)
+	}
+	writeJSON(w, http.StatusOK, m.info(r.PathValue("id")))
+	return nil
+}
+
+// ------------------------------------------------
+// Running
+// ------------------------------------------------
+
+func (s *Server) reset(w http.ResponseWriter, r *http.Request, m *machine) error {
+	m.runner.Machine().Reset()
+	m.frames = 0
+	writeJSON(w, http.StatusOK, m.state())
+	return nil
+}
+
+// Steps run without the timers, as in a debugger
+func (s *Server) step(w http.ResponseWriter, r *http.Request, m *machine) error {
+	n, err := queryInt(r, "n", 1, 0, maxSteps)
+	if err != nil {
+		return err
+	}
+	emulator := m.runner.Machine()
+	for i := 0; i < n; i++ {
+		if emulator.ProgramCounter() > chip8.RAM-2 {
+			return &httpError{http.StatusConflict, host.ErrHalted.Error()}
+		}
+		emulator.Step()
+	}
+	writeJSON(w, http.StatusOK, m.state())
+	return nil
+}
+
+func (s *Server) run(w http.ResponseWriter, r *http.Request, m *machine) error {
+	frames, err := queryInt(r, "frames", 1, 0, maxFrames)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < frames; i++ {
+		if err := m.runner.Frame(); errors.Is(err, host.ErrHalted) {
+			return &httpError{http.StatusConflict, err.Error()}
+		} else if err != nil {
+			return err
+		}
+		m.frames++
+	}
+	writeJSON(w, http.StatusOK, m.state())
+	return nil
+}
+
+// Pressed keys stay down until released
+func (s *Server) key(w http.ResponseWriter, r *http.Request, m *machine) error {
+	key, err := strconv.ParseUint(r.PathValue("key"), 16, 8)
+	if err != nil || key > 0xF {
+		return badRequest("key must be a hex digit from 0 to F")
+	}
+	m.runner.Machine().UpdateKeyboardState(chip8.Key(key), r.Method == http.MethodPut)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ------------------------------------------------
+// Inspection
+// ------------------------------------------------
+
+// Machine state, with the names of chip8.getState() in the browser
+type machineState struct {
+	PC         uint16   `json:"pc"`
+	I          uint16   `json:"i"`
+	V          []int    `json:"v"`
+	Stack      []uint16 `json:"stack"`
+	DelayTimer byte     `json:"delayTimer"`
+	SoundTimer byte     `json:"soundTimer"`
+	Speed      int      `json:"speed"`
+	Quirks     quirks   `json:"quirks"`
+	Keys       []bool   `json:"keys"` // Indexed by key
+	Frames     int      `json:"frames"`
+}
+
+type quirks struct {
+	Shift bool `json:"shift"`
+	Jump  bool `json:"jump"`
+}
+
+func (m *machine) state() machineState {
+	emulator := m.runner.Machine()
+	state := emulator.State()
+	v := make([]int, len(state.V))
+	for x, val := range state.V {
+		v[x] = int(val)
+	}
+	return machineState{
+		PC:         state.PC,
+		I:          state.I,
+		V:          v,
+		Stack:      append([]uint16{}, state.Stack...),
+		DelayTimer: state.DelayTimer,
+		SoundTimer: state.SoundTimer,
+		Speed:      emulator.Speed(),
+		Quirks:     quirks{Shift: emulator.Quirks().Shift, Jump: emulator.Quirks().Jump},
+		Keys:       state.Keys[:],
+		Frames:     m.frames,
+	}
+}
+
+func (s *Server) state(w http.ResponseWriter, r *http.Request, m *machine) error {
+	writeJSON(w, http.StatusOK, m.state())
+	return nil
+}
+
+func (s *Server) memory(w http.ResponseWriter, r *http.Request, m *machine) error {
+	addr, err := queryInt(r, "addr", 0, 0, chip8.RAM)
+	if err != nil {
+		return err
+	}
+	length, err := queryInt(r, "len", chip8.RAM-addr, 0, chip8.RAM-addr)
+	if err != nil {
+		return err
+	}
+	memory := m.runner.Machine().State().Memory
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, err = w.Write(memory[addr : addr+length])
+	return err
+}
+
+// The display as rows of "0" and "1" characters, top to bottom
+func (s *Server) display(w http.ResponseWriter, r *http.Request, m *machine) error {
+	display := m.runner.Machine().GetDisplay()
+	rows := make([]string, len(display))
+	for y, row := range display {
+		var line strings.Builder
+		for _, pixel := range row {
+			line.WriteByte('0' + byte(min(pixel, 1)))
+		}
+		rows[y] = line.String()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"width":  chip8.DISPLAY_COLS,
+		"height": chip8.DISPLAY_ROWS,
+		"rows":   rows,
+	})
+	return nil
+}
+
+func (s *Server) displayPNG(w http.ResponseWriter, r *http.Request, m *machine) error {
+	scale, err := queryInt(r, "scale", render.DefaultScale, 1, 64)
+	if err != nil {
+		return err
+	}
+	palette, err := m.palette(r.URL.Query().Get("palette"))
+	if err != nil {
+		return badRequest("%v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.WritePNG(&buf, m.runner.Machine().GetDisplay(), render.Options{Palette: palette, Scale: scale}); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// The palette spec, or else the ROM database's colours, the cartridge's or classic
+func (m *machine) palette(spec string) (render.Palette, error) {
+	cart, _ := m.runner.Machine().Cartridge()
+	return render.ParsePalette(cmp.Or(spec, m.entry.Settings(cart).Palette, "classic"))
+}
+
+// ------------------------------------------------
+// Spectating: every machine streams the frames it runs, see package
+// spectate. The stream outlives ROM loads and ends when the machine is
+// removed.
+// ------------------------------------------------
+func (s *Server) spectate(w http.ResponseWriter, r *http.Request) {
+	m, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, &httpError{http.StatusNotFound, fmt.Sprintf("no machine %q", r.PathValue("id"))})
+		return
+	}
+	m.hub.ServeHTTP(w, r)
+}
+
+// Tells the spectators what the machine runs now
+func (m *machine) spectateInfo() spectate.Info {
+	info := spectate.Info{Title: m.title()}
+	if palette, err := m.palette(""); err == nil {
+		info.Palette = render.Hex(palette.Background) + "," + render.Hex(palette.Foreground)
+	}
+	return info
+}
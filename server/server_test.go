@@ -0,0 +1,184 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/yuvrajchettri/chip-8-emulator/bundle"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+)
+
+// Counts V0 up while key 5 is held, draws the 0 font sprite:
+//
+//	0x200: 6005  V0 = 5
+//	0x202: 00E0  clear the screen
+//	0x204: A000  I = 0
+//	0x206: D015  draw 5 rows at V0, V1 (5, 0)
+//	0x208: 6105  V1 = 5
+//	0x20A: E1A1  skip the next instruction unless key V1 is held
+//	0x20C: 7001  V0 += 1
+//	0x20E: 120A  jump to 0x20A
+var testROM = []byte{0x60, 0x05, 0x00, 0xE0, 0xA0, 0x00, 0xD0, 0x15, 0x61, 0x05, 0xE1, 0xA1, 0x70, 0x01, 0x12, 0x0A}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(New(romdb.Default()))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Sends a request and returns the response status and body
+func do(t *testing.T, method, url string, body []byte) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, data
+}
+
+func doJSON(t *testing.T, method, url string, body []byte, wantStatus int) map[string]interface{} {
+	t.Helper()
+	status, data := do(t, method, url, body)
+	require.Equal(t, wantStatus, status, string(data))
+	var val map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &val))
+	return val
+}
+
+func TestServer_Machine(t *testing.T) {
+	server := newTestServer(t)
+
+	created := doJSON(t, "POST", server.URL+"/machines?speed=600", testROM, http.StatusCreated)
+	require.Equal(t, "1", created["id"])
+	require.Equal(t, romdb.Hash(testROM), created["hash"])
+	machine := server.URL + "/machines/1"
+
+	// 10 instructions per frame: 4 to draw and then the loop, V0 only counts with key 5 held
+	state := doJSON(t, "POST", machine+"/run?frames=2", nil, http.StatusOK)
+	require.EqualValues(t, 5, state["v"].([]interface{})[0])
+	require.EqualValues(t, 2, state["frames"])
+	require.EqualValues(t, 600, state["speed"])
+
+	status, _ := do(t, "PUT", machine+"/keys/5", nil)
+	require.Equal(t, http.StatusNoContent, status)
+	state = doJSON(t, "POST", machine+"/step?n=3", nil, http.StatusOK)
+	require.EqualValues(t, 6, state["v"].([]interface{})[0])
+	require.Equal(t, true, state["keys"].([]interface{})[5])
+
+	status, _ = do(t, "DELETE", machine+"/keys/5", nil)
+	require.Equal(t, http.StatusNoContent, status)
+	state = doJSON(t, "GET", machine+"/state", nil, http.StatusOK)
+	require.Equal(t, false, state["keys"].([]interface{})[5])
+
+	// The 0 sprite is 0xF0 0x90 0x90 0x90 0xF0, drawn at 5, 0
+	display := doJSON(t, "GET", machine+"/display", nil, http.StatusOK)
+	rows := display["rows"].([]interface{})
+	require.Len(t, rows, 32)
+	require.Equal(t, "0000011110", rows[0].(string)[:10])
+	require.Equal(t, "0000010010", rows[1].(string)[:10])
+
+	status, data := do(t, "GET", machine+"/display.png?scale=2", nil)
+	require.Equal(t, http.StatusOK, status)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 128, img.Bounds().Dx())
+
+	status, data = do(t, "GET", machine+"/memory?addr=0x200&len=4", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, testROM[:4], data)
+
+	state = doJSON(t, "POST", machine+"/reset", nil, http.StatusOK)
+	require.EqualValues(t, 0x200, state["pc"])
+	require.EqualValues(t, 0, state["frames"])
+}
+
+func TestServer_Machines(t *testing.T) {
+	server := newTestServer(t)
+
+	// Built-in ROMs get their bundle's settings
+	pong := doJSON(t, "POST", server.URL+"/machines?rom=PONG", nil, http.StatusCreated)
+	require.Equal(t, "Pong (1 player)", pong["title"])
+	require.Equal(t, "bundle", pong["format"])
+	state := doJSON(t, "GET", server.URL+"/machines/1/state", nil, http.StatusOK)
+	require.EqualValues(t, 720, state["speed"])
+
+	doJSON(t, "POST", server.URL+"/machines", testROM, http.StatusCreated)
+	status, data := do(t, "GET", server.URL+"/machines", nil)
+	require.Equal(t, http.StatusOK, status)
+	var list []machineInfo
+	require.NoError(t, json.Unmarshal(data, &list))
+	require.Len(t, list, 2)
+	require.Equal(t, "1", list[0].ID)
+	require.Equal(t, "2", list[1].ID)
+
+	// Loading another ROM keeps the ID
+	loaded := doJSON(t, "PUT", server.URL+"/machines/1/rom", testROM, http.StatusOK)
+	require.Equal(t, "1", loaded["id"])
+	require.Equal(t, romdb.Hash(testROM), loaded["hash"])
+
+	status, _ = do(t, "DELETE", server.URL+"/machines/2", nil)
+	require.Equal(t, http.StatusNoContent, status)
+	doJSON(t, "GET", server.URL+"/machines/2", nil, http.StatusNotFound)
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := newTestServer(t)
+	doJSON(t, "POST", server.URL+"/machines", testROM, http.StatusCreated)
+
+	tests := []struct {
+		method, path string
+		body         []byte
+		status       int
+		err          string
+	}{
+		{"POST", "/machines", nil, http.StatusBadRequest, "expected a ROM"},
+		{"POST", "/machines?rom=MISSING", nil, http.StatusBadRequest, `unknown ROM "MISSING"`},
+		{"POST", "/machines", make([]byte, 4096), http.StatusBadRequest, "only 3584 fit"},
+		{"GET", "/machines/9/state", nil, http.StatusNotFound, `no machine "9"`},
+		{"DELETE", "/machines/9", nil, http.StatusNotFound, `no machine "9"`},
+		{"PUT", "/machines/1/keys/G", nil, http.StatusBadRequest, "hex digit"},
+		{"POST", "/machines/1/run?frames=-1", nil, http.StatusBadRequest, "frames must be"},
+		{"GET", "/machines/1/memory?addr=4000&len=100", nil, http.StatusBadRequest, "len must be"},
+		{"GET", "/machines/1/display.png?palette=nope", nil, http.StatusBadRequest, "nope"},
+	}
+	for _, test := range tests {
+		body := doJSON(t, test.method, server.URL+test.path, test.body, test.status)
+		require.Contains(t, body["error"], test.err, "%s %s: %s", test.method, test.path, body["error"])
+	}
+}
+
+func TestServer_Halted(t *testing.T) {
+	server := newTestServer(t)
+
+	// Jumps to the last byte of memory
+	doJSON(t, "POST", server.URL+"/machines", []byte{0x1F, 0xFF}, http.StatusCreated)
+	body := doJSON(t, "POST", server.URL+"/machines/1/run", nil, http.StatusConflict)
+	require.Contains(t, body["error"], "outside memory")
+}
+
+func TestServer_Spectate(t *testing.T) {
+	server := newTestServer(t)
+	doJSON(t, "POST", server.URL+"/machines?rom=PONG", nil, http.StatusCreated)
+
+	// Plain requests are turned away, the stream itself is tested in package spectate
+	status, _ := do(t, "GET", server.URL+"/machines/1/spectate", nil)
+	require.Equal(t, http.StatusBadRequest, status)
+	doJSON(t, "GET", server.URL+"/machines/9/spectate", nil, http.StatusNotFound)
+
+	// Removed machines end their stream
+	status, _ = do(t, "DELETE", server.URL+"/machines/1", nil)
+	require.Equal(t, http.StatusNoContent, status)
+	status, _ = do(t, "GET", server.URL+"/machines/1/spectate", nil)
+	require.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,284 @@
+// Package spectate streams a running emulator to spectators over
+// WebSockets. A Hub is fed every frame and sends its clients only what
+// changed, so a 64x32 display that barely moves costs next to nothing.
+//
+// A client first gets the hub's Info as a JSON text message:
+//
+//	{"title": "Pong", "palette": "#000000,#FFFFFF", "width": 64, "height": 32}
+//
+// and then binary messages, one per frame in which the display or the keys
+// changed, big endian:
+//
+//	frame    uint32  frames published so far
+//	keys     uint16  bit k is set while key k is held
+//	count    uint8   number of rows that follow
+//	rows     count times: y uint8, pixels uint64, leftmost pixel in the high bit
+//
+// The first binary message has every row. Info is sent again whenever it
+// changes, e.g. when another ROM is loaded. spectate.html is a viewer.
+package spectate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// Messages queued per client, a client that falls further behind skips
+// frames and is sent the whole display once it catches up
+const sendQueue = 32
+
+// Info describes the stream to the viewer
+type Info struct {
+	Title   string `json:"title,omitempty"`
+	Palette string `json:"palette,omitempty"` // "BACKGROUND,FOREGROUND" hex colours
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
+
+// Hub publishes the frames of one emulator to any number of clients, it is
+// the http.Handler of the WebSocket endpoint
+type Hub struct {
+	mu      sync.Mutex
+	info    []byte // Info as JSON
+	rows    [chip8.DISPLAY_ROWS]uint64
+	keys    uint16
+	frame   uint32
+	clients map[*client]struct{}
+	closed  bool
+}
+
+type client struct {
+	send   chan message
+	stale  bool          // Frames were skipped, the next one has to be sent whole
+	closed chan struct{} // Closed by Hub.Close
+}
+
+type message struct {
+	opcode byte
+	data   []byte
+}
+
+// NewHub creates a Hub with a blank display, Width and Height of info are
+// filled in
+func NewHub(info Info) *Hub {
+	h := &Hub{clients: make(map[*client]struct{})}
+	h.SetInfo(info)
+	return h
+}
+
+// SetInfo changes the Info and sends it to the clients
+func (h *Hub) SetInfo(info Info) {
+	info.Width, info.Height = chip8.DISPLAY_COLS, chip8.DISPLAY_ROWS
+	data, _ := json.Marshal(info)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.info = data
+	for c := range h.clients {
+		c.queue(message{opText, data})
+	}
+}
+
+// ------------------------------------------------
+// Publish sends the clients the rows of display and the keys that changed
+// since the last frame. It never blocks on slow clients.
+// ------------------------------------------------
+func (h *Hub) Publish(display [][]int, keys [16]bool) {
+	var rows [chip8.DISPLAY_ROWS]uint64
+	for y, row := range display {
+		for x, pixel := range row {
+			if pixel != 0 {
+				rows[y] |= 1 << (63 - x)
+			}
+		}
+	}
+	var mask uint16
+	for key, held := range keys {
+		if held {
+			mask |= 1 << key
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var changed []int
+	for y := range rows {
+		if rows[y] != h.rows[y] {
+			changed = append(changed, y)
+		}
+	}
+	keysChanged := mask != h.keys
+	h.rows, h.keys = rows, mask
+	h.frame++
+
+	var diff []byte
+	if len(changed) > 0 || keysChanged {
+		diff = h.encode(changed)
+	}
+	for c := range h.clients {
+		switch {
+		case c.stale:
+			c.stale = !c.queue(message{opBinary, h.snapshot()})
+		case diff != nil:
+			c.stale = !c.queue(message{opBinary, diff})
+		}
+	}
+}
+
+// Encodes a frame message with the rows ys
+func (h *Hub) encode(ys []int) []byte {
+	data := make([]byte, 0, 7+len(ys)*9)
+	data = binary.BigEndian.AppendUint32(data, h.frame)
+	data = binary.BigEndian.AppendUint16(data, h.keys)
+	data = append(data, byte(len(ys)))
+	for _, y := range ys {
+		data = append(data, byte(y))
+		data = binary.BigEndian.AppendUint64(data, h.rows[y])
+	}
+	return data
+}
+
+// A frame message with every row
+func (h *Hub) snapshot() []byte {
+	ys := make([]int, chip8.DISPLAY_ROWS)
+	for y := range ys {
+		ys[y] = y
+	}
+	return h.encode(ys)
+}
+
+// Queues a message unless the client's queue is full
+func (c *client) queue(msg message) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// ------------------------------------------------
+// ServeHTTP upgrades the request to a WebSocket and streams to it until the
+// client goes away or the hub is closed
+// ------------------------------------------------
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	closed := h.closed
+	h.mu.Unlock()
+	if closed {
+		http.Error(w, "the stream has ended", http.StatusGone)
+		return
+	}
+
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	c := &client{send: make(chan message, sendQueue), closed: make(chan struct{})}
+	h.mu.Lock()
+	// Close may have run during the handshake
+	if h.closed {
+		h.mu.Unlock()
+		conn.writeFrame(opClose, nil)
+		return
+	}
+	c.queue(message{opText, h.info})
+	c.queue(message{opBinary, h.snapshot()})
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		h.mu.Unlock()
+	}()
+
+	// Closing conn on return stops the reader
+	readDone := make(chan error, 1)
+	go func() {
+		readDone <- conn.readLoop(func(payload []byte) {
+			c.queue(message{opPong, payload})
+		})
+	}()
+	for {
+		select {
+		case msg := <-c.send:
+			if err := conn.writeFrame(msg.opcode, msg.data); err != nil {
+				return
+			}
+		case <-readDone:
+			conn.writeFrame(opClose, nil)
+			return
+		case <-c.closed:
+			conn.writeFrame(opClose, nil)
+			return
+		}
+	}
+}
+
+// ------------------------------------------------
+// Serve streams hub to spectators on addr/spectate. It returns once it
+// listens, so a busy address is reported right away, and stop closes the
+// hub and the listener.
+// ------------------------------------------------
+func Serve(addr string, hub *Hub) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/spectate", hub)
+	go http.Serve(listener, mux)
+
+	return func() {
+		hub.Close()
+		listener.Close()
+	}, nil
+}
+
+// Close disconnects the clients and turns new ones away
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for c := range h.clients {
+		close(c.closed)
+		delete(h.clients, c)
+	}
+}
+
+// ------------------------------------------------
+// Display wraps the host.Display of a front-end to publish every frame of
+// the machine it runs, machine returns the Runner's current machine
+// ------------------------------------------------
+func (h *Hub) Display(display host.Display, machine func() *chip8.Chip8) host.Display {
+	return &publishingDisplay{hub: h, display: display, machine: machine}
+}
+
+type publishingDisplay struct {
+	hub     *Hub
+	display host.Display
+	machine func() *chip8.Chip8
+}
+
+// Publishes the live display whatever the present mode, spectators get the
+// raw pixels
+func (d *publishingDisplay) Present(frame [][]int, changed bool) error {
+	machine := d.machine()
+	var keys [16]bool
+	for key := range keys {
+		keys[key] = machine.IsKeyPressed(chip8.Key(key))
+	}
+	d.hub.Publish(machine.GetDisplay(), keys)
+	return d.display.Present(frame, changed)
+}
@@ -0,0 +1,272 @@
+package spectate
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/host"
+)
+
+// A WebSocket client that reads the hub's messages
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, server *httptest.Server) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	require.NoError(t, err)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	// The example handshake of RFC 6455
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	return &testClient{t: t, conn: conn, r: r}
+}
+
+// Reads a frame the server sent
+func (c *testClient) read() (byte, []byte) {
+	c.t.Helper()
+	var header [2]byte
+	_, err := io.ReadFull(c.r, header[:])
+	require.NoError(c.t, err)
+	require.NotZero(c.t, header[0]&0x80, "fragmented frame")
+	require.Zero(c.t, header[1]&0x80, "masked frame")
+
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		_, err := io.ReadFull(c.r, ext[:])
+		require.NoError(c.t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.r, payload)
+	require.NoError(c.t, err)
+	return header[0] & 0x0F, payload
+}
+
+// Sends a masked frame
+func (c *testClient) write(opcode byte, payload []byte) {
+	c.t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x80 | opcode, 0x80 | byte(len(payload))}, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := c.conn.Write(frame)
+	require.NoError(c.t, err)
+}
+
+type frameMessage struct {
+	frame uint32
+	keys  uint16
+	rows  map[int]uint64
+}
+
+func (c *testClient) readFrame() frameMessage {
+	c.t.Helper()
+	opcode, data := c.read()
+	require.Equal(c.t, byte(opBinary), opcode)
+	msg := frameMessage{frame: binary.BigEndian.Uint32(data), keys: binary.BigEndian.Uint16(data[4:]), rows: make(map[int]uint64)}
+	count := int(data[6])
+	require.Len(c.t, data, 7+count*9)
+	for i := 0; i < count; i++ {
+		row := data[7+i*9:]
+		msg.rows[int(row[0])] = binary.BigEndian.Uint64(row[1:])
+	}
+	return msg
+}
+
+func newDisplay() [][]int {
+	display := make([][]int, chip8.DISPLAY_ROWS)
+	for y := range display {
+		display[y] = make([]int, chip8.DISPLAY_COLS)
+	}
+	return display
+}
+
+// Waits for the hub to register its clients, they connect asynchronously
+func waitForClients(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.clients) == n
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestHub_Stream(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	hub := NewHub(Info{Title: "Test", Palette: "#000000,#FFFFFF"})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	display := newDisplay()
+	display[3][0] = 1
+	hub.Publish(display, [16]bool{})
+
+	client := dial(t, server)
+	opcode, data := client.read()
+	require.Equal(t, byte(opText), opcode)
+	var info Info
+	require.NoError(t, json.Unmarshal(data, &info))
+	require.Equal(t, Info{Title: "Test", Palette: "#000000,#FFFFFF", Width: 64, Height: 32}, info)
+
+	// The whole display first
+	msg := client.readFrame()
+	require.Len(t, msg.rows, chip8.DISPLAY_ROWS)
+	require.Equal(t, uint64(1)<<63, msg.rows[3])
+	require.Equal(t, uint32(1), msg.frame)
+	waitForClients(t, hub, 1)
+
+	// Unchanged frames send nothing, then only the changed rows
+	hub.Publish(display, [16]bool{})
+	display[10][63] = 1
+	hub.Publish(display, [16]bool{})
+	msg = client.readFrame()
+	require.Equal(t, uint32(3), msg.frame)
+	require.Equal(t, map[int]uint64{10: 1}, msg.rows)
+
+	// Key changes alone
+	hub.Publish(display, [16]bool{0x5: true, 0xF: true})
+	msg = client.readFrame()
+	require.Equal(t, uint16(1<<0x5|1<<0xF), msg.keys)
+	require.Empty(t, msg.rows)
+
+	hub.SetInfo(Info{Title: "Other"})
+	opcode, data = client.read()
+	require.Equal(t, byte(opText), opcode)
+	require.Contains(t, string(data), `"title":"Other"`)
+
+	// Pings are answered and a close is echoed
+	client.write(opPing, []byte("hi"))
+	opcode, data = client.read()
+	require.Equal(t, byte(opPong), opcode)
+	require.Equal(t, "hi", string(data))
+	client.write(opClose, nil)
+	opcode, _ = client.read()
+	require.Equal(t, byte(opClose), opcode)
+	waitForClients(t, hub, 0)
+}
+
+func TestHub_SlowClient(t *testing.T) {
+	hub := NewHub(Info{})
+	c := &client{send: make(chan message, sendQueue), closed: make(chan struct{})}
+	hub.clients[c] = struct{}{}
+
+	// Publishing never blocks, whatever doesn't fit in the queue is skipped
+	display := newDisplay()
+	for frame := 0; frame < 2*sendQueue; frame++ {
+		display[frame%chip8.DISPLAY_ROWS][0] ^= 1
+		hub.Publish(display, [16]bool{})
+	}
+	require.True(t, c.stale)
+	for len(c.send) > 0 {
+		<-c.send
+	}
+
+	// and the client catches up with the whole display
+	hub.Publish(display, [16]bool{})
+	require.False(t, c.stale)
+	msg := <-c.send
+	require.Len(t, msg.data, 7+chip8.DISPLAY_ROWS*9)
+	require.Equal(t, hub.snapshot(), msg.data)
+}
+
+func TestHub_Close(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	hub := NewHub(Info{})
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	client := dial(t, server)
+	client.read()
+	client.readFrame()
+	waitForClients(t, hub, 1)
+
+	hub.Close()
+	opcode, _ := client.read()
+	require.Equal(t, byte(opClose), opcode)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+}
+
+func TestServe(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	_, err = Serve(busy.Addr().String(), NewHub(Info{}))
+	require.Error(t, err)
+
+	// Stopping closes the hub too
+	hub := NewHub(Info{})
+	stop, err := Serve("127.0.0.1:0", hub)
+	require.NoError(t, err)
+	stop()
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	require.True(t, hub.closed)
+}
+
+func TestUpgrade_NotWebSocket(t *testing.T) {
+	server := httptest.NewServer(NewHub(Info{}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+type fakeDisplay struct {
+	frames int
+}
+
+func (d *fakeDisplay) Present(frame [][]int, changed bool) error {
+	d.frames++
+	return nil
+}
+
+func TestHub_Display(t *testing.T) {
+	// Clears the screen and draws the 0 sprite at the origin
+	machine, err := host.NewMachine([]byte{0x00, 0xE0, 0xD0, 0x05, 0x12, 0x04}, chip8.Options{Speed: 600})
+	require.NoError(t, err)
+	machine.UpdateKeyboardState(chip8.KEY_7, true)
+
+	hub := NewHub(Info{})
+	inner := &fakeDisplay{}
+	runner := host.NewRunner(machine, hub.Display(inner, func() *chip8.Chip8 { return machine }), nil, nil)
+	require.NoError(t, runner.Frame())
+
+	require.Equal(t, 1, inner.frames)
+	require.Equal(t, uint32(1), hub.frame)
+	require.Equal(t, uint16(1<<7), hub.keys)
+	require.Equal(t, uint64(0xF0)<<56, hub.rows[0])
+}
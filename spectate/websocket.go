@@ -0,0 +1,152 @@
+package spectate
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------
+// The server side of the WebSocket protocol (RFC 6455), as much as
+// spectating needs: the server only sends, clients may ping and close.
+// Fragmented and oversized client messages close the connection.
+// ------------------------------------------------
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11" // Hashed with the client's key to accept it
+
+	opText   = 0x1
+	opBinary = 0x2
+	opClose  = 0x8
+	opPing   = 0x9
+	opPong   = 0xA
+
+	maxClientFrame = 125 // Control frames are all clients send
+	writeTimeout   = 5 * time.Second
+)
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// Upgrades an HTTP request to a WebSocket, replying with an error if it isn't one
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, errors.New("not a WebSocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("unsupported WebSocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "WebSockets are not supported", http.StatusInternalServerError)
+		return nil, errors.New("the connection can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// Reports whether a comma separated header has token, ignoring case
+func headerContains(header http.Header, name, token string) bool {
+	for _, val := range header.Values(name) {
+		for _, part := range strings.Split(val, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Writes a single unfragmented, unmasked frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(len(payload)))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// ------------------------------------------------
+// Reads client frames until the client closes the connection or breaks the
+// protocol, answering pings. Messages from the client are ignored. Returns
+// the error that ended the connection, io.EOF for a clean close.
+// ------------------------------------------------
+func (c *wsConn) readLoop(pong func(payload []byte)) error {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+			return err
+		}
+		fin, opcode := header[0]&0x80 != 0, header[0]&0x0F
+		masked, length := header[1]&0x80 != 0, int(header[1]&0x7F)
+		if !fin || !masked || length > maxClientFrame {
+			return errors.New("unexpected WebSocket frame from the client")
+		}
+
+		var mask [4]byte
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(c.rw, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+
+		switch opcode {
+		case opClose:
+			return io.EOF
+		case opPing:
+			pong(payload)
+		}
+	}
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"time"
+)
+
+// ------------------------------------------------
+// Keyboard input from a terminal in raw mode.
+// Terminals only report key presses (and auto-repeats), never releases, so a
+// key is treated as held for HoldTime after the last byte received for it.
+// ------------------------------------------------
+
+const HoldTime = 150 * time.Millisecond
+
+const (
+	CtrlC = 0x03
+	Esc   = 0x1B
+)
+
+// Same layout as the SDL and WASM front-ends
+var keyMap = map[byte]uint8{
+	'1': 0x1, '2': 0x2, '3': 0x3, '4': 0xC,
+	'q': 0x4, 'w': 0x5, 'e': 0x6, 'r': 0xD,
+	'a': 0x7, 's': 0x8, 'd': 0x9, 'f': 0xE,
+	'z': 0xA, 'x': 0x0, 'c': 0xB, 'v': 0xF,
+}
+
+// KeyFor maps a byte read from the terminal to a CHIP-8 key
+func KeyFor(b byte) (uint8, bool) {
+	if b >= 'A' && b <= 'Z' {
+		b += 'a' - 'A'
+	}
+	key, ok := keyMap[b]
+	return key, ok
+}
+
+type Keyboard struct {
+	releaseAt map[uint8]time.Time // Held keys and when they are released
+}
+
+func NewKeyboard() *Keyboard {
+	return &Keyboard{releaseAt: make(map[uint8]time.Time)}
+}
+
+// Feed handles bytes read from the terminal. Escape sequences, such as the
+// ESC [ A of the up arrow, are skipped rather than read as keys.
+func (k *Keyboard) Feed(input []byte, now time.Time) {
+	for i := 0; i < len(input); i++ {
+		if input[i] == Esc {
+			i += escapeLen(input[i:]) - 1
+			continue
+		}
+		if key, ok := KeyFor(input[i]); ok {
+			k.releaseAt[key] = now.Add(HoldTime)
+		}
+	}
+}
+
+// Returns the length of the escape sequence input starts with: ESC [, then
+// parameters up to a final byte in 0x40-0x7E (CSI), ESC O and one byte
+// (SS3), or ESC and the key pressed with Alt. A sequence ends with the read
+// it came in, a lone ESC is the Escape key.
+func escapeLen(input []byte) int {
+	if len(input) < 2 {
+		return len(input)
+	}
+	switch input[1] {
+	case '[':
+		for i := 2; i < len(input); i++ {
+			if input[i] >= 0x40 && input[i] <= 0x7E {
+				return i + 1
+			}
+		}
+		return len(input)
+	case 'O':
+		return min(3, len(input))
+	}
+	return 2
+}
+
+// Keys returns the keys held at now, indexed by CHIP-8 key, releasing keys whose hold expired
+func (k *Keyboard) Keys(now time.Time) [16]bool {
+	var keys [16]bool
+	for key, releaseAt := range k.releaseAt {
+		if !now.Before(releaseAt) {
+			delete(k.releaseAt, key)
+			continue
+		}
+		keys[key] = true
+	}
+	return keys
+}
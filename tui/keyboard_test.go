@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  byte
+		expect uint8
+		ok     bool
+	}{
+		{"digit", '1', 0x1, true},
+		{"lower case", 'v', 0xF, true},
+		{"upper case", 'X', 0x0, true},
+		{"unmapped", 'p', 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, ok := KeyFor(tt.input)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expect, key)
+		})
+	}
+}
+
+func TestKeyboard_HoldAndRelease(t *testing.T) {
+	keyboard := NewKeyboard()
+	start := time.Now()
+
+	keyboard.Feed([]byte("q"), start)
+	keys := keyboard.Keys(start.Add(HoldTime / 2))
+	require.True(t, keys[0x4])
+	require.False(t, keys[0x5])
+
+	// Auto-repeat extends the hold
+	keyboard.Feed([]byte("q"), start.Add(HoldTime/2))
+	require.True(t, keyboard.Keys(start.Add(HoldTime))[0x4])
+
+	require.False(t, keyboard.Keys(start.Add(2 * HoldTime))[0x4])
+}
+
+func TestKeyboard_EscapeSequences(t *testing.T) {
+	keyboard := NewKeyboard()
+	now := time.Now()
+
+	// Arrows, Ctrl+Right, F1 and Alt+a press nothing
+	keyboard.Feed([]byte("\x1b[A\x1b[B\x1b[C\x1b[D\x1b[1;5C\x1bOP\x1ba"), now)
+	require.Equal(t, [16]bool{}, keyboard.Keys(now))
+
+	// Keys after a sequence and after a lone ESC still count
+	keyboard.Feed([]byte("\x1b[Aw"), now)
+	keyboard.Feed([]byte("\x1b"), now)
+	keyboard.Feed([]byte("e"), now)
+	keys := keyboard.Keys(now)
+	require.True(t, keys[0x5])
+	require.True(t, keys[0x6])
+	require.False(t, keys[0x7], "the A of the up arrow is not a")
+}
@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+// ------------------------------------------------
+// Terminal renderer for the CHIP-8 display.
+// Two display rows share one terminal cell using Unicode half blocks, so the
+// 64x32 display takes 64x16 cells. The last frame is kept so each Draw only
+// writes the cells that changed.
+// ------------------------------------------------
+
+const (
+	Rows       = chip8.DISPLAY_ROWS / 2 // Terminal rows used by the display
+	StatusRows = 3                      // Terminal rows used by the status lines below it
+)
+
+const (
+	escHideCursor  = "\x1b[?25l"
+	escShowCursor  = "\x1b[?25h"
+	escAltScreen   = "\x1b[?1049h"
+	escMainScreen  = "\x1b[?1049l"
+	escClearScreen = "\x1b[2J"
+	escClearLine   = "\x1b[2K"
+)
+
+var halfBlocks = [4]rune{
+	' ', // neither pixel on
+	'▀', // upper pixel on
+	'▄', // lower pixel on
+	'█', // both pixels on
+}
+
+type Screen struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	cells  [Rows][chip8.DISPLAY_COLS]rune // What is currently on the terminal
+	status [StatusRows]string
+	drawn  bool // false until the first full frame is written
+}
+
+func NewScreen(w io.Writer) *Screen {
+	return &Screen{w: w}
+}
+
+// Start switches to the alternate screen and hides the cursor
+func (s *Screen) Start() error {
+	s.drawn = false
+	_, err := io.WriteString(s.w, escAltScreen+escHideCursor+escClearScreen)
+	return err
+}
+
+// Stop restores the terminal to the state it was in before Start
+func (s *Screen) Stop() error {
+	_, err := io.WriteString(s.w, escShowCursor+escMainScreen)
+	return err
+}
+
+// Draw writes the cells and status lines that changed since the last Draw
+func (s *Screen) Draw(display [][]int, status [StatusRows]string) error {
+	s.buf.Reset()
+
+	for row := 0; row < Rows; row++ {
+		col := 0
+		for col < chip8.DISPLAY_COLS {
+			if s.drawn && s.cells[row][col] == cellAt(display, row, col) {
+				col++
+				continue
+			}
+
+			// Write the whole run of changed cells after a single cursor move
+			moveTo(&s.buf, row, col)
+			for col < chip8.DISPLAY_COLS {
+				cell := cellAt(display, row, col)
+				if s.drawn && s.cells[row][col] == cell {
+					break
+				}
+				s.cells[row][col] = cell
+				s.buf.WriteRune(cell)
+				col++
+			}
+		}
+	}
+
+	for i, line := range status {
+		if s.drawn && s.status[i] == line {
+			continue
+		}
+		s.status[i] = line
+		moveTo(&s.buf, Rows+i, 0)
+		s.buf.WriteString(escClearLine)
+		s.buf.WriteString(line)
+	}
+
+	s.drawn = true
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	_, err := s.w.Write(s.buf.Bytes())
+	return err
+}
+
+func cellAt(display [][]int, row, col int) rune {
+	idx := 0
+	if display[row*2][col] != 0 {
+		idx |= 1
+	}
+	if display[row*2+1][col] != 0 {
+		idx |= 2
+	}
+	return halfBlocks[idx]
+}
+
+// Terminal rows and columns are 1-based
+func moveTo(buf *bytes.Buffer, row, col int) {
+	fmt.Fprintf(buf, "\x1b[%d;%dH", row+1, col+1)
+}
+
+// Status returns the status lines shown below the display.
+// Registers are split over two lines so every line fits in DISPLAY_COLS.
+func Status(emulator *chip8.Chip8) [StatusRows]string {
+	status := [StatusRows]string{
+		fmt.Sprintf("PC:%04X I:%04X DT:%02X ST:%02X  Ctrl-C quits",
+			emulator.ProgramCounter(), emulator.I, emulator.DelayTimer(), emulator.SoundTimer()),
+	}
+
+	var line bytes.Buffer
+	for i, val := range emulator.Registers() {
+		if i%8 != 0 {
+			line.WriteByte(' ')
+		}
+		fmt.Fprintf(&line, "V%X:%02X", i, val)
+		if i%8 == 7 {
+			status[1+i/8] = line.String()
+			line.Reset()
+		}
+	}
+	return status
+}
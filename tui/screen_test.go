@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+)
+
+func TestScreen_DrawHalfBlocks(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+	display[0][0] = 1 // upper half of cell (0,0)
+	display[1][1] = 1 // lower half of cell (0,1)
+	display[2][2] = 1 // both halves of cell (1,2)
+	display[3][2] = 1
+
+	var out bytes.Buffer
+	screen := NewScreen(&out)
+	require.NoError(t, screen.Draw(display, [StatusRows]string{"a", "b", "c"}))
+
+	require.Equal(t, '▀', screen.cells[0][0])
+	require.Equal(t, '▄', screen.cells[0][1])
+	require.Equal(t, '█', screen.cells[1][2])
+	require.Equal(t, ' ', screen.cells[1][3])
+	require.Contains(t, out.String(), "\x1b[1;1H▀▄ ")
+}
+
+func TestScreen_DrawOnlyWritesChanges(t *testing.T) {
+	display := chip8.NewChip8(false, false, 700).GetDisplay()
+
+	var out bytes.Buffer
+	screen := NewScreen(&out)
+	status := [StatusRows]string{"PC", "V0", "V8"}
+	require.NoError(t, screen.Draw(display, status))
+
+	// Nothing changed, nothing is written
+	out.Reset()
+	require.NoError(t, screen.Draw(display, status))
+	require.Empty(t, out.String())
+
+	// A single pixel change is one cursor move and one cell
+	display[31][63] = 1
+	require.NoError(t, screen.Draw(display, status))
+	require.Equal(t, "\x1b[16;64H▄", out.String())
+
+	// Only the changed status line is rewritten
+	out.Reset()
+	require.NoError(t, screen.Draw(display, [StatusRows]string{"PC", "V1", "V8"}))
+	require.Equal(t, "\x1b[18;1H"+escClearLine+"V1", out.String())
+}
+
+func TestStatus(t *testing.T) {
+	emulator := chip8.NewChip8(false, false, 700)
+	emulator.PC = 0x200
+	emulator.I = 0x123
+
+	status := Status(emulator)
+	require.True(t, strings.HasPrefix(status[0], "PC:0200 I:0123 DT:00 ST:00"))
+	require.Equal(t, "V0:00 V1:00 V2:00 V3:00 V4:00 V5:00 V6:00 V7:00", status[1])
+	require.Equal(t, "V8:00 V9:00 VA:00 VB:00 VC:00 VD:00 VE:00 VF:00", status[2])
+	for _, line := range status {
+		require.LessOrEqual(t, len(line), chip8.DISPLAY_COLS)
+	}
+}
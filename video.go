@@ -0,0 +1,115 @@
+//go:build !js && !wasm
+
+package main
+
+import (
+	"image"
+	"unsafe"
+
+	"github.com/yuvrajchettri/chip-8-emulator/chip8"
+	"github.com/yuvrajchettri/chip-8-emulator/render"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// ------------------------------------------------
+// SDL video output.
+// Each frame is rasterised in Go into an RGBA buffer, uploaded to a
+// streaming texture once and scaled to the window by the GPU, so the cost of
+// a frame does not depend on the window size.
+// ------------------------------------------------
+type video struct {
+	window    *sdl.Window
+	renderer  *sdl.Renderer
+	texture   *sdl.Texture
+	screen    *render.Screen
+	pixels    *image.RGBA
+	scaleMode render.ScaleMode
+	dirty     bool // Set when the window must be redrawn even if the frame did not change
+}
+
+func newVideo(opts options) (*video, error) {
+	// Nearest neighbour scaling keeps CHIP-8 pixels sharp
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "0")
+
+	var flags uint32 = sdl.WINDOW_SHOWN | sdl.WINDOW_RESIZABLE
+	if opts.fullscreen {
+		flags |= sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+
+	window, err := sdl.CreateWindow(
+		"Chip 8",
+		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		int32(chip8.DISPLAY_COLS*opts.windowScale), int32(chip8.DISPLAY_ROWS*opts.windowScale),
+		flags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := sdl.CreateRenderer(window, -1, 0)
+	if err != nil {
+		window.Destroy()
+		return nil, err
+	}
+
+	// The texture keeps the default scale so grid and scanline effects have room to show
+	screen := render.NewScreen(opts.screenOptions(render.DefaultScale), opts.effects)
+	pixels := screen.NewRGBA()
+	texture, err := renderer.CreateTexture(uint32(sdl.PIXELFORMAT_RGBA32), sdl.TEXTUREACCESS_STREAMING,
+		int32(pixels.Bounds().Dx()), int32(pixels.Bounds().Dy()))
+	if err != nil {
+		renderer.Destroy()
+		window.Destroy()
+		return nil, err
+	}
+
+	return &video{
+		window:    window,
+		renderer:  renderer,
+		texture:   texture,
+		screen:    screen,
+		pixels:    pixels,
+		scaleMode: opts.scaleMode,
+		dirty:     true,
+	}, nil
+}
+
+func (v *video) destroy() {
+	v.texture.Destroy()
+	v.renderer.Destroy()
+	v.window.Destroy()
+}
+
+// present draws a display or presentation buffer to the window
+func (v *video) present(buffer [][]int) {
+	v.screen.Rasterize(buffer, v.pixels)
+	v.texture.Update(nil, unsafe.Pointer(&v.pixels.Pix[0]), v.pixels.Stride)
+
+	// Letterbox around the viewport
+	v.renderer.SetDrawColor(0, 0, 0, 255)
+	v.renderer.Clear()
+
+	outW, outH, err := v.renderer.GetOutputSize()
+	if err == nil {
+		viewport := render.Viewport(v.scaleMode, int(outW), int(outH))
+		v.renderer.Copy(v.texture, nil, &sdl.Rect{
+			X: int32(viewport.Min.X),
+			Y: int32(viewport.Min.Y),
+			W: int32(viewport.Dx()),
+			H: int32(viewport.Dy()),
+		})
+	}
+
+	v.renderer.Present()
+	v.dirty = false
+}
+
+func (v *video) toggleFullscreen() {
+	var flags uint32 = sdl.WINDOW_FULLSCREEN_DESKTOP
+	if v.window.GetFlags()&flags == flags {
+		flags = 0
+	}
+	v.window.SetFullscreen(flags)
+	v.dirty = true
+}
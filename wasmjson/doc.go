@@ -0,0 +1,11 @@
+// Package wasmjson decodes JSON into Go values by their encoding/json struct
+// tags without linking encoding/json into the WASM build, see romdb for why.
+// Natively Unmarshal is json.Unmarshal. In the browser it parses with
+// JSON.parse and copies the result into the value by its struct tags, so
+// packages decode the same JSON into the same types on both builds.
+//
+// The browser decoder covers what the repo's JSON types use: strings, bools,
+// numbers, pointers, structs, slices, maps with string keys and []byte as
+// base64. Field names match their tag or Go name exactly, and embedded
+// structs aren't flattened.
+package wasmjson
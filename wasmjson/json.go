@@ -0,0 +1,10 @@
+//go:build !js && !wasm
+
+package wasmjson
+
+import "encoding/json"
+
+// Unmarshal is json.Unmarshal
+func Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,96 @@
+package wasmjson_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yuvrajchettri/chip-8-emulator/bundle"
+	"github.com/yuvrajchettri/chip-8-emulator/cart"
+	"github.com/yuvrajchettri/chip-8-emulator/romdb"
+	"github.com/yuvrajchettri/chip-8-emulator/wasmjson"
+)
+
+// The tests run natively, against encoding/json, and under Node, against
+// JSON.parse, so the same expectations hold for both decoders:
+//
+//	GOOS=js GOARCH=wasm go test -exec="$(go env GOROOT)/lib/wasm/go_js_wasm_exec" ./wasmjson
+
+func TestUnmarshal_Programs(t *testing.T) {
+	var programs []romdb.Program
+	require.NoError(t, wasmjson.Unmarshal([]byte(`[{
+		"title": "Pong", "release": "1990", "authors": ["Paul Vervalin"], "unknown": [1, {"a": null}],
+		"roms": {"abc": {
+			"file": "pong.ch8",
+			"platforms": ["originalChip8", "superchip"],
+			"quirkyPlatforms": {"superchip": {"shift": true, "jump": false}},
+			"tickrate": 20,
+			"keys": {"up": 1, "down": 4},
+			"colors": {"pixels": ["#000", "#fff"], "buzzer": "#f00"}
+		}}
+	}, {"title": "Empty", "description": null, "roms": {}}]`), &programs))
+
+	require.Equal(t, []romdb.Program{{
+		Title:   "Pong",
+		Release: "1990",
+		Authors: []string{"Paul Vervalin"},
+		ROMs: map[string]romdb.ROM{"abc": {
+			File:            "pong.ch8",
+			Platforms:       []string{"originalChip8", "superchip"},
+			QuirkyPlatforms: map[string]map[string]bool{"superchip": {"shift": true, "jump": false}},
+			Tickrate:        20,
+			Keys:            map[string]int{"up": 1, "down": 4},
+			Colors:          &romdb.Colors{Pixels: []string{"#000", "#fff"}, Buzzer: "#f00"},
+		}},
+	}, {Title: "Empty", ROMs: map[string]romdb.ROM{}}}, programs)
+}
+
+func TestUnmarshal_Bundle(t *testing.T) {
+	var b bundle.Bundle
+	require.NoError(t, wasmjson.Unmarshal([]byte(`{"title": "Count", "speed": 900,
+		"quirks": {"shift": true}, "keys": {"up": 2}, "palette": "#000000,#FFFFFF", "rom": "YAVwARIC"}`), &b))
+
+	require.Equal(t, bundle.Bundle{
+		Title:   "Count",
+		Speed:   900,
+		Quirks:  &bundle.Quirks{Shift: true},
+		Keys:    map[string]int{"up": 2},
+		Palette: "#000000,#FFFFFF",
+		ROM:     []byte{0x60, 0x05, 0x70, 0x01, 0x12, 0x02},
+	}, b)
+}
+
+func TestUnmarshal_CartOptions(t *testing.T) {
+	var opts cart.Options
+	require.NoError(t, wasmjson.Unmarshal([]byte(`{"tickrate": 7, "fillColor": "#FFAA00", "shiftQuirks": false, "jumpQuirks": null}`), &opts))
+
+	shift := false
+	require.Equal(t, cart.Options{Tickrate: 7, FillColor: "#FFAA00", ShiftQuirks: &shift}, opts)
+}
+
+// Decoding into a value keeps what the JSON doesn't mention and adds to maps
+func TestUnmarshal_Merge(t *testing.T) {
+	b := bundle.Bundle{Title: "Count", Keys: map[string]int{"up": 2}}
+	require.NoError(t, wasmjson.Unmarshal([]byte(`{"keys": {"down": 8}, "authors": null}`), &b))
+	require.Equal(t, bundle.Bundle{Title: "Count", Keys: map[string]int{"up": 2, "down": 8}}, b)
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	var b bundle.Bundle
+	for _, data := range []string{
+		`{"title": `,
+		`[]`,
+		`{"title": 1}`,
+		`{"speed": "fast"}`,
+		`{"speed": 1.5}`,
+		`{"authors": "Someone"}`,
+		`{"quirks": {"shift": 1}}`,
+		`{"keys": {"up": "1"}}`,
+		`{"rom": "not base64!"}`,
+	} {
+		require.Error(t, wasmjson.Unmarshal([]byte(data), &b), data)
+	}
+
+	require.Error(t, wasmjson.Unmarshal([]byte(`{}`), b))
+	require.Error(t, wasmjson.Unmarshal([]byte(`{}`), (*bundle.Bundle)(nil)))
+}
@@ -0,0 +1,188 @@
+//go:build js && wasm
+
+package wasmjson
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"syscall/js"
+)
+
+// Unmarshal parses data with JSON.parse and stores the result in the value
+// v points to, see Decode
+func Unmarshal(data []byte, v any) (err error) {
+	// JSON.parse throws on invalid JSON
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	bytes := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(bytes, data)
+	text := js.Global().Get("TextDecoder").New().Call("decode", bytes)
+	return Decode(js.Global().Get("JSON").Call("parse", text), v)
+}
+
+// Decode stores a value JSON.parse returned in the value v points to, as
+// json.Unmarshal would store its JSON
+func Decode(value js.Value, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("wasmjson: Decode(non-nil pointer expected, got %T)", v)
+	}
+	return decode(value, rv.Elem(), "")
+}
+
+// Stores value in v, path locates value in the JSON for errors
+func decode(value js.Value, v reflect.Value, path string) error {
+	if value.IsNull() {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			v.SetZero()
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decode(value, v.Elem(), path)
+
+	case reflect.String:
+		if value.Type() != js.TypeString {
+			return typeError(value, v, path)
+		}
+		v.SetString(value.String())
+
+	case reflect.Bool:
+		if value.Type() != js.TypeBoolean {
+			return typeError(value, v, path)
+		}
+		v.SetBool(value.Bool())
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type() != js.TypeNumber {
+			return typeError(value, v, path)
+		}
+		if n := value.Float(); n != math.Trunc(n) || v.OverflowInt(int64(n)) {
+			return typeError(value, v, path)
+		}
+		v.SetInt(int64(value.Float()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Type() != js.TypeNumber {
+			return typeError(value, v, path)
+		}
+		if n := value.Float(); n < 0 || n != math.Trunc(n) || v.OverflowUint(uint64(n)) {
+			return typeError(value, v, path)
+		}
+		v.SetUint(uint64(value.Float()))
+
+	case reflect.Float32, reflect.Float64:
+		if value.Type() != js.TypeNumber {
+			return typeError(value, v, path)
+		}
+		v.SetFloat(value.Float())
+
+	case reflect.Slice:
+		// encoding/json reads []byte as base64
+		if v.Type().Elem().Kind() == reflect.Uint8 && value.Type() == js.TypeString {
+			data, err := base64.StdEncoding.DecodeString(value.String())
+			if err != nil {
+				return fmt.Errorf("wasmjson: %s: %w", where(path), err)
+			}
+			v.SetBytes(data)
+			return nil
+		}
+		if !isArray(value) {
+			return typeError(value, v, path)
+		}
+		slice := reflect.MakeSlice(v.Type(), value.Length(), value.Length())
+		for i := range slice.Len() {
+			if err := decode(value.Index(i), slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+
+	case reflect.Map:
+		if value.Type() != js.TypeObject || isArray(value) || v.Type().Key().Kind() != reflect.String {
+			return typeError(value, v, path)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		keys := js.Global().Get("Object").Call("keys", value)
+		for i := range keys.Length() {
+			key := keys.Index(i).String()
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(value.Get(key), elem, join(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+
+	case reflect.Struct:
+		if value.Type() != js.TypeObject || isArray(value) {
+			return typeError(value, v, path)
+		}
+		for i := range v.NumField() {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			field := value.Get(name)
+			if field.IsUndefined() {
+				continue
+			}
+			if err := decode(field, v.Field(i), join(path, name)); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("wasmjson: %s: unsupported type %s", where(path), v.Type())
+	}
+	return nil
+}
+
+// Returns the JSON name of a struct field, false if JSON skips it
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if tag == "-" {
+		return "", false
+	} else if tag == "" {
+		return field.Name, true
+	}
+	return tag, true
+}
+
+func isArray(value js.Value) bool {
+	return js.Global().Get("Array").Call("isArray", value).Bool()
+}
+
+func typeError(value js.Value, v reflect.Value, path string) error {
+	return fmt.Errorf("wasmjson: cannot decode %s %s into %s", value.Type(), where(path), v.Type())
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func where(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
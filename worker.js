@@ -0,0 +1,36 @@
+// Runs chip8.wasm inside a Web Worker so emulation and drawing stay off the
+// page's main thread. The page transfers an OffscreenCanvas in the "start"
+// message, along with the wasm_exec script and binary to run (the standard Go
+// or TinyGo build). After that it talks to the Go side with postMessage (see
+// worker_wasm.go for the messages it understands).
+
+// Not every browser gives dedicated workers requestAnimationFrame
+if (typeof self.requestAnimationFrame !== 'function') {
+    self.requestAnimationFrame = callback => setTimeout(() => callback(performance.now()), 1000 / 60);
+}
+
+self.addEventListener('message', async event => {
+    if (event.data.type !== 'start') {
+        return;
+    }
+
+    // main_wasm.go picks the canvas up from here
+    self.chip8Canvas = event.data.canvas;
+    importScripts(event.data.exec);
+
+    const go = new Go();
+    go.argv = event.data.argv;
+
+    try {
+        const wasmResponse = await fetch(event.data.wasm);
+        if (!wasmResponse.ok) {
+            throw new Error('Failed to fetch WASM file');
+        }
+
+        const wasmBytes = await wasmResponse.arrayBuffer();
+        const wasmModule = await WebAssembly.instantiate(wasmBytes, go.importObject);
+        await go.run(wasmModule.instance);
+    } catch (error) {
+        self.postMessage({ type: 'error', message: error.message });
+    }
+});
@@ -0,0 +1,140 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// ------------------------------------------------
+// Worker mode.
+// worker.js runs chip8.wasm inside a Web Worker and hands it an
+// OffscreenCanvas transferred from the page. There is no document in a
+// worker, so key events and control commands arrive from the page with
+// postMessage and replies are posted back the same way.
+// ------------------------------------------------
+
+// isWorker reports whether the emulator runs inside a Web Worker
+func isWorker() bool {
+	return js.Global().Get("document").IsUndefined()
+}
+
+// workerCanvas returns the OffscreenCanvas worker.js received from the page
+func workerCanvas() js.Value {
+	return js.Global().Get("chip8Canvas")
+}
+
+func postMessage(msgType string, fields map[string]interface{}) {
+	msg := map[string]interface{}{"type": msgType}
+	for key, val := range fields {
+		msg[key] = val
+	}
+	js.Global().Call("postMessage", msg)
+}
+
+// ------------------------------------------------
+// Handles messages from the page:
+//
+//	{type: "keydown" | "keyup", key}        keyboard events, key as in KeyboardEvent.key
+//	{type: "pressKey" | "releaseKey", key}  CHIP-8 key 0x0-0xF, from the on-screen keypad
+//	{type: "saveState"}                     replied to with {type: "saveState", state}
+//	{type: "loadState", state}              same as chip8.loadState
+//	{type: "gamepads", pads}                snapshot of navigator.getGamepads(), see gamepad_wasm.go
+//	{type: "gamepadMapping", mapping}       same object as chip8.setGamepadMapping
+//	{type: "load", rom}                     same as chip8.load, rom is a Uint8Array, replied to with
+//	                                        {type: "running", rom} where rom is chip8.romInfo()
+//	{type: "romDatabase", programs}         same as chip8.setROMDatabase
+//	{type: "reset"}                         same as chip8.reset
+//	{type: "displayOptions", options}       same object as setDisplayOptions
+//	{type: "screenshot"}                    replied to with {type: "screenshot", png}
+//	{type: "stop"}
+//
+// Errors are posted back as {type: "error", message}. On start the worker
+// posts {type: "roms", roms}, where roms is chip8.listROMs(), and then
+// {type: "running", rom}.
+// ------------------------------------------------
+func setupWorkerMessages(session *session) {
+	handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := args[0].Get("data")
+		switch data.Get("type").String() {
+		case "keydown":
+			if chip8Key, ok := keyMap[data.Get("key").String()]; ok {
+				keyStates[chip8Key] = true
+			}
+
+		case "keyup":
+			if chip8Key, ok := keyMap[data.Get("key").String()]; ok {
+				keyStates[chip8Key] = false
+			}
+
+		case "pressKey", "releaseKey":
+			key, err := jsKey([]js.Value{data.Get("key")})
+			if err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+				return nil
+			}
+			keyStates[key] = data.Get("type").String() == "pressKey"
+
+		case "saveState":
+			state, err := session.emulator().MarshalBinary()
+			if err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+				return nil
+			}
+			postMessage("saveState", map[string]interface{}{"state": bytesToJS(state)})
+
+		case "loadState":
+			state, err := jsBytes(data.Get("state"))
+			if err == nil {
+				err = session.loadState(state)
+			}
+			if err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+			}
+
+		case "gamepads":
+			gamepadSnapshot = data.Get("pads")
+
+		case "gamepadMapping":
+			mapping, err := parseGamepadMapping(data.Get("mapping"), session.gamepadROM)
+			if err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+				return nil
+			}
+			session.gamepad = mapping
+
+		case "load":
+			rom, err := jsBytes(data.Get("rom"))
+			if err == nil {
+				err = session.load(rom)
+			}
+			if err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+				return nil
+			}
+			postMessage("running", map[string]interface{}{"rom": session.romInfo()})
+
+		case "romDatabase":
+			if err := session.setROMDatabase(data.Get("programs")); err != nil {
+				postMessage("error", map[string]interface{}{"message": err.Error()})
+			}
+
+		case "reset":
+			session.reset()
+
+		case "displayOptions":
+			if errMsg := setDisplayOptions(session, data.Get("options")); errMsg != nil {
+				postMessage("error", map[string]interface{}{"message": errMsg})
+			}
+
+		case "screenshot":
+			postMessage("screenshot", map[string]interface{}{"png": screenshotPNG(session)})
+
+		case "stop":
+			stopEmulator()
+		}
+		return nil
+	})
+
+	js.Global().Call("addEventListener", "message", handler)
+}